@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mywio/git-ops/pkg/config"
+	"github.com/mywio/git-ops/pkg/core"
+	"github.com/mywio/git-ops/plugins/reconciler"
+	"gopkg.in/yaml.v3"
+)
+
+const usage = `Usage: git-ops <command> [flags]
+
+Commands:
+  serve                          Run the daemon (default)
+  plugins list                   List built-in and plugins_dir plugins
+  config validate [path]         Validate a config file
+  config print [--redacted] [--provenance]
+                                 Print the effective config
+  reconcile --once [--dry-run]   Run one full reconciliation and exit
+  stack deploy owner/repo        Deploy one stack and exit
+
+Every command accepts -config (default: $CONFIG_FILE or config.yaml).
+`
+
+// eventDrainTimeout bounds how long one-shot commands wait for event
+// listeners (notifications, audit) after the reconciler is done.
+const eventDrainTimeout = 30 * time.Second
+
+// run dispatches the command line and returns the exit code.
+func run(args []string, out io.Writer) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return runServe(args, out)
+	}
+
+	cmd, rest := args[0], args[1:]
+	sub := ""
+	if len(rest) > 0 {
+		sub = rest[0]
+	}
+	switch {
+	case cmd == "serve":
+		return runServe(rest, out)
+	case cmd == "plugins" && sub == "list":
+		return runPluginsList(rest[1:], out)
+	case cmd == "config" && sub == "validate":
+		return runConfigValidate(rest[1:], out)
+	case cmd == "config" && sub == "print":
+		return runConfigPrint(rest[1:], out)
+	case cmd == "reconcile":
+		return runReconcile(rest, out)
+	case cmd == "stack" && sub == "deploy":
+		return runStackDeploy(rest[1:], out)
+	case cmd == "help" || cmd == "--help" || cmd == "-h":
+		fmt.Fprint(out, usage)
+		return 0
+	default:
+		fmt.Fprintf(out, "unknown command %q\n\n%s", strings.TrimSpace(cmd+" "+sub), usage)
+		return 2
+	}
+}
+
+// runPluginsList implements `git-ops plugins list`. It loads plugins as the
+// daemon does, without initializing them, and prints every built-in plugin,
+// whether it is enabled, and every plugin loaded or rejected from plugins_dir.
+func runPluginsList(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("plugins list", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", defaultConfigPath(), "config file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// Plugins are never initialized, so they are not stopped either; process
+	// plugins exit when their stdin closes with this process.
+	mgr, err := newManager(cliLogger(), *configPath, nil)
+	if err != nil {
+		fmt.Fprintf(out, "error: %v\n", err)
+		return 1
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSOURCE\tSTATE\tVERSION\tAPI\tCAPABILITIES")
+	enabled := map[string]bool{}
+	for _, plug := range mgr.ListPlugins() {
+		enabled[plug.Name()] = true
+		source, _ := mgr.PluginSource(plug.Name())
+		manifest, _ := mgr.Manifest(plug.Name())
+		caps := make([]string, 0, len(plug.Capabilities()))
+		for _, c := range plug.Capabilities() {
+			caps = append(caps, string(c))
+		}
+		fmt.Fprintf(tw, "%s\t%s\tenabled\t%s\t%s\t%s\n", plug.Name(), orDash(string(source)),
+			orDash(manifest.Version), orDash(manifest.APIVersion), orDash(strings.Join(caps, ",")))
+	}
+	for _, name := range core.BuiltinPlugins() {
+		if !enabled[name] {
+			fmt.Fprintf(tw, "%s\t%s\tdisabled\t-\t-\t-\n", name, core.SourceBuiltin)
+		}
+	}
+	for _, rejected := range mgr.RejectedPlugins() {
+		fmt.Fprintf(tw, "%s\t%s\trejected: %s\t-\t-\t-\n", rejected.Path, core.SourcePluginsDir, rejected.Reason)
+	}
+	tw.Flush()
+	return 0
+}
+
+// runConfigPrint implements `git-ops config print [--redacted] [--provenance]`.
+// It prints the effective config (see loadConfig; as at startup, with ${...}
+// references resolved) as YAML, omitting unset keys. With --redacted,
+// resolved references and values of secret fields are replaced. With
+// --provenance, every key is commented with the file or variable it came
+// from, and the defaults of unset core keys are shown.
+func runConfigPrint(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", defaultConfigPath(), "config file")
+	redacted := fs.Bool("redacted", false, "replace secret values with REDACTED")
+	provenance := fs.Bool("provenance", false, "comment every key with its source")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, sources, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(out, "error: %s: %v\n", *configPath, err)
+		return 1
+	}
+	effective := core.UnwrapConfig(cfg)
+	if *redacted {
+		effective = core.RedactConfig(cfg, core.BuiltinConfigSchemas(core.BuiltinPlugins()))
+	}
+
+	printable := map[string]any{}
+	for section, values := range effective {
+		if pruned := pruneUnset(values); pruned != nil {
+			printable[section] = pruned
+		}
+	}
+	if *provenance {
+		// Show the defaults that apply to unset core keys, too.
+		coreCfg, _ := config.LoadCoreConfig(cfg, sources)
+		coreValues, _ := printable["core"].(map[string]any)
+		if coreValues == nil {
+			coreValues = map[string]any{}
+		}
+		for key, value := range config.CoreDefaults() {
+			if source := coreCfg.Source(key); source.Kind == config.SourceDefault {
+				coreValues[key] = value
+				sources["core."+key] = source
+			}
+		}
+		printable["core"] = coreValues
+	}
+	var doc yaml.Node
+	if err := doc.Encode(printable); err != nil {
+		fmt.Fprintf(out, "error: %v\n", err)
+		return 1
+	}
+	if *provenance {
+		annotateSources(&doc, "", sources)
+	}
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		fmt.Fprintf(out, "error: %v\n", err)
+		return 1
+	}
+	enc.Close()
+	return 0
+}
+
+// annotateSources adds the source of every key below node as a line comment.
+func annotateSources(node *yaml.Node, path string, sources config.Provenance) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		keyPath := key.Value
+		if path != "" {
+			keyPath = path + "." + key.Value
+		}
+		if source, ok := sources[keyPath]; ok {
+			key.LineComment = source.String()
+		}
+		annotateSources(value, keyPath, sources)
+	}
+}
+
+// pruneUnset drops nil and empty-string values, which the environment
+// defaults every known key to, and returns nil if nothing is left.
+func pruneUnset(v any) any {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if strings.TrimSpace(t) == "" {
+			return nil
+		}
+	case map[string]any:
+		out := map[string]any{}
+		for k, item := range t {
+			if pruned := pruneUnset(item); pruned != nil {
+				out[k] = pruned
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	}
+	return v
+}
+
+// runReconcile implements `git-ops reconcile --once [--dry-run]`.
+func runReconcile(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", defaultConfigPath(), "config file")
+	once := fs.Bool("once", false, "run a single reconciliation and exit")
+	dryRun := fs.Bool("dry-run", false, "log what would change without deploying or removing stacks")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !*once {
+		fmt.Fprintln(out, "reconcile: only --once is supported; use `git-ops serve` to reconcile continuously")
+		return 2
+	}
+	return runOnce(out, *configPath, *dryRun, "reconcile", nil)
+}
+
+// runStackDeploy implements `git-ops stack deploy owner/repo`.
+func runStackDeploy(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("stack deploy", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", defaultConfigPath(), "config file")
+	force := fs.String("force", "", "force deploy type: bypass_check, clean_local_state, remove_images, restart_only")
+	dryRun := fs.Bool("dry-run", false, "log what would change without deploying")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	owner, repo, ok := strings.Cut(fs.Arg(0), "/")
+	if fs.NArg() != 1 || !ok || owner == "" || repo == "" {
+		fmt.Fprintln(out, "usage: git-ops stack deploy [-config path] [-force type] [-dry-run] owner/repo")
+		return 2
+	}
+	return runOnce(out, *configPath, *dryRun, "reconcile_stack", map[string]any{
+		"owner":      owner,
+		"repo":       repo,
+		"force_type": *force,
+		"wait":       true,
+	})
+}
+
+// runOnce brings the plugins up exactly as the daemon does, except that the
+// reconciler is not started on its interval and the HTTP API is not served.
+// It then runs one reconciler action synchronously, waits for the events it
+// published to be delivered, and stops everything. The reconciliation
+// summary is printed to out as JSON and logs go to stderr. It exits 1 if the
+// run or any stack in it failed.
+func runOnce(out io.Writer, configPath string, dryRun bool, action string, params map[string]any) int {
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+	var overrides map[string]any
+	if dryRun {
+		overrides = map[string]any{"dry_run": true}
+	}
+	mgr, err := newManager(logger, configPath, overrides)
+	if err != nil {
+		logger.Error("Failed to set up modules", "error", err)
+		return 1
+	}
+
+	plug, err := mgr.GetPlugin("reconciler")
+	if err != nil {
+		logger.Error("The reconciler plugin is not enabled; add it to core.builtin_plugins")
+		return 1
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if err := mgr.Init(ctx); err != nil {
+		logger.Error("Failed to initialize modules", "error", err)
+		return 1
+	}
+	defer mgr.Stop(context.Background())
+	if err := mgr.StartModules(ctx, plug.Name()); err != nil {
+		logger.Error("Failed to start modules", "error", err)
+	}
+
+	result, err := plug.Execute(ctx, action, params)
+
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), eventDrainTimeout)
+	defer drainCancel()
+	if werr := mgr.WaitForEvents(drainCtx); werr != nil {
+		logger.Warn("Timed out waiting for event listeners", "error", werr)
+	}
+
+	if err != nil {
+		logger.Error("Reconciliation failed", "action", action, "error", err)
+		return 1
+	}
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		logger.Error("Failed to print summary", "error", err)
+	}
+	if ctx.Err() != nil {
+		logger.Warn("Reconciliation interrupted")
+		return 1
+	}
+	if summary, ok := result.(*reconciler.ReconcileSummary); ok && !summary.OK() {
+		return 1
+	}
+	return 0
+}
+
+// cliLogger logs warnings and errors to stderr so that plugin loading does
+// not interleave with command output.
+func cliLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
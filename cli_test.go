@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_UnknownCommand(t *testing.T) {
+	var out bytes.Buffer
+	assert.Equal(t, 2, run([]string{"deploy"}, &out))
+	assert.Contains(t, out.String(), `unknown command "deploy"`)
+	assert.Contains(t, out.String(), "Usage: git-ops")
+
+	out.Reset()
+	assert.Equal(t, 0, run([]string{"help"}, &out))
+	assert.Contains(t, out.String(), "stack deploy owner/repo")
+}
+
+func TestRun_ConfigPrint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("core:\n  token: ghp_secret\n  users: [me]\npushover:\n  token: abc\n  user: u\n"), 0o644))
+
+	var out bytes.Buffer
+	assert.Equal(t, 0, run([]string{"config", "print", "-config", path}, &out))
+	assert.Contains(t, out.String(), "ghp_secret")
+
+	out.Reset()
+	assert.Equal(t, 0, run([]string{"config", "print", "--redacted", "-config", path}, &out))
+	assert.NotContains(t, out.String(), "ghp_secret")
+	assert.NotContains(t, out.String(), "abc")
+	assert.Contains(t, out.String(), "token: REDACTED")
+	assert.Contains(t, out.String(), "user: u")
+	assert.NotContains(t, out.String(), "target_dir", "unset keys are omitted")
+}
+
+func TestRun_OneShotArguments(t *testing.T) {
+	var out bytes.Buffer
+	assert.Equal(t, 2, run([]string{"reconcile"}, &out))
+	assert.Contains(t, out.String(), "only --once is supported")
+
+	out.Reset()
+	assert.Equal(t, 2, run([]string{"stack", "deploy", "not-a-repo"}, &out))
+	assert.Contains(t, out.String(), "usage: git-ops stack deploy")
+}
+
+func TestRun_ConfigPrintReferences(t *testing.T) {
+	t.Setenv("GITOPS_TEST_WEBHOOK", "https://hooks.example.com/abc")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("webhook:\n  url: ${GITOPS_TEST_WEBHOOK}\n"), 0o644))
+
+	var out bytes.Buffer
+	assert.Equal(t, 0, run([]string{"config", "print", "-config", path}, &out))
+	assert.Contains(t, out.String(), "url: https://hooks.example.com/abc")
+
+	out.Reset()
+	assert.Equal(t, 0, run([]string{"config", "print", "--redacted", "-config", path}, &out))
+	assert.Contains(t, out.String(), "url: REDACTED")
+}
+
+func TestRun_ConfigPrintEnvOverrides(t *testing.T) {
+	t.Setenv("GITOPS__PUSHOVER__USER", "from-env")
+	t.Setenv("GITOPS__ENV_FORWARDER__KEYS", "[A, B]")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("pushover:\n  token: abc\n  user: from-file\n"), 0o644))
+
+	var out bytes.Buffer
+	assert.Equal(t, 0, run([]string{"config", "print", "-config", path}, &out))
+	assert.Contains(t, out.String(), "user: from-env")
+	assert.Contains(t, out.String(), "token: abc")
+	assert.Contains(t, out.String(), "- A\n")
+}
+
+func TestRun_ConfigPrintProvenance(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	fragment := filepath.Join(dir, "conf.d", "10-host.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("pushover:\n  token: abc\n  user: u\n"), 0o644))
+	assert.NoError(t, os.Mkdir(filepath.Dir(fragment), 0o755))
+	assert.NoError(t, os.WriteFile(fragment, []byte("pushover:\n  user: host\n"), 0o644))
+
+	var out bytes.Buffer
+	assert.Equal(t, 0, run([]string{"config", "print", "--provenance", "-config", path}, &out))
+	assert.Contains(t, out.String(), "token: abc # "+path)
+	assert.Contains(t, out.String(), "user: host # "+fragment)
+	assert.Contains(t, out.String(), "target_dir: ./stacks # default")
+}
+
+func TestRun_ConfigLoadErrorIsFatal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("core:\n  builtin_plugins: [reconciler]\n  token: ${GITOPS_TEST_UNSET_TOKEN}\n"), 0o644))
+
+	var out bytes.Buffer
+	assert.Equal(t, 1, run([]string{"serve", "-config", path}, &out), "serve does not start on env-only config")
+	assert.Equal(t, 1, run([]string{"reconcile", "--once", "-config", path}, &out))
+	out.Reset()
+	assert.Equal(t, 1, run([]string{"plugins", "list", "-config", path}, &out))
+	assert.Contains(t, out.String(), "GITOPS_TEST_UNSET_TOKEN")
+}
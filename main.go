@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
@@ -11,65 +14,138 @@ import (
 
 	"github.com/mywio/git-ops/pkg/config"
 	"github.com/mywio/git-ops/pkg/core"
+	_ "github.com/mywio/git-ops/plugins/builtin"
 )
 
 func main() {
-	// Setup Logger
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	os.Exit(run(os.Args[1:], os.Stdout))
+}
 
-	// Load Config
-	cfgMapEnv := config.LoadConfigMapFromEnv()
-	configPath := os.Getenv("CONFIG_FILE")
-	if configPath == "" {
-		configPath = "config.yaml"
+// runServe implements `git-ops serve`, the long-running daemon. It is also
+// what runs when no command is given.
+func runServe(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(out)
+	configPath := fs.String("config", defaultConfigPath(), "config file")
+	if err := fs.Parse(args); err != nil {
+		return 2
 	}
-	cfgMapFile, err := config.LoadConfigFile(configPath)
-	if err != nil {
-		logger.Error("Failed to load config file", "path", configPath, "error", err)
-	}
-	cfgMap := config.MergeConfigMap(cfgMapFile, cfgMapEnv)
 
-	// Setup Module Manager
-	mgr := core.NewModuleManager(logger)
-	mgr.SetConfig(cfgMap)
-	mgr.SetHTTPClient(&http.Client{Timeout: 15 * time.Second})
+	// Setup Logger
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 
-	// Load Plugins
-	pluginsDir := ""
-	if coreSection, ok := cfgMap["core"]; ok {
-		if v, ok := coreSection["plugins_dir"].(string); ok {
-			pluginsDir = v
-		}
-	}
-	if pluginsDir == "" {
-		pluginsDir = "plugins"
-	}
-	if err := mgr.LoadPlugins(pluginsDir); err != nil {
-		logger.Error("Failed to load plugins", "error", err)
+	// Setup Module Manager, built-in and plugins_dir plugins
+	mgr, err := newManager(logger, *configPath, nil)
+	if err != nil {
+		logger.Error("Failed to set up modules", "error", err)
+		return 1
 	}
 
-	// Register Modules (if any core modules remain)
-
 	// Init Modules
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	if err := mgr.Init(ctx); err != nil {
 		logger.Error("Failed to initialize modules", "error", err)
-		os.Exit(1)
+		return 1
 	}
 
 	// Start Modules
 	mgr.Start(ctx)
 
-	// Wait for Signal
+	// Wait for Signal; SIGHUP reloads the config
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	sig := <-sigChan
+	for sig == syscall.SIGHUP {
+		logger.Info("Received SIGHUP, reloading configuration", "path", *configPath)
+		if result, err := mgr.Reload(ctx); err != nil {
+			logger.Error("Config reload failed", "error", err)
+		} else if len(result.Errors) > 0 {
+			logger.Error("Config reload completed with errors", "errors", result.Errors)
+		}
+		sig = <-sigChan
+	}
 	logger.Info("Received signal, shutting down...", "signal", sig)
 
 	// Graceful Shutdown
 	mgr.Stop(ctx)
 	logger.Info("Shutdown complete")
+	return 0
+}
+
+// newManager sets up a ModuleManager the way the daemon does: it loads the
+// config, enables the built-in plugins and loads plugins_dir. overrides are
+// applied to the core section on every (re)load, as if set by a flag.
+// It fails if the config cannot be loaded, e.g. because of an unset ${VAR}:
+// starting without the file would drop the enabled plugins and API tokens.
+// Other failures are logged and leave the manager with whatever could be
+// loaded.
+func newManager(logger *slog.Logger, configPath string, overrides map[string]any) (*core.ModuleManager, error) {
+	var flags []config.Layer
+	if len(overrides) > 0 {
+		flags = append(flags, config.Layer{Kind: config.SourceFlag, Config: config.ConfigMap{"core": overrides}})
+	}
+	load := func() (config.ConfigMap, config.Provenance, error) {
+		return loadConfig(configPath, flags...)
+	}
+
+	cfgMap, provenance, err := load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config %s: %w", configPath, err)
+	}
+	coreCfg, err := config.LoadCoreConfig(cfgMap, provenance)
+	if err != nil {
+		logger.Error("Invalid core config", "error", err)
+	}
+	logger.Info("Loaded core config", "plugins_dir", coreCfg.PluginsDir, "http_addr", coreCfg.HTTPAddr,
+		"dry_run", coreCfg.DryRun, "sources", coreCfg.Sources)
+
+	mgr := core.NewModuleManager(logger)
+	// Plugins still calling the deprecated core.Publish reach this manager.
+	core.SetDefaultEventBus(mgr.EventBus())
+	mgr.SetConfig(cfgMap)
+	mgr.SetConfigLoader(func() (map[string]map[string]any, error) {
+		cfg, _, err := load()
+		return cfg, err
+	})
+	mgr.SetHTTPClient(&http.Client{Timeout: 15 * time.Second})
+
+	// Enable Built-in Plugins
+	if err := mgr.LoadBuiltins(); err != nil {
+		logger.Error("Failed to enable built-in plugins", "error", err)
+	}
+
+	// Load Plugins
+	if err := mgr.LoadPlugins(coreCfg.PluginsDir); err != nil {
+		logger.Error("Failed to load plugins", "error", err)
+	}
+	return mgr, nil
+}
+
+// loadConfig merges, later layers winning: the environment, the config file,
+// the fragments in its conf.d directory, GITOPS__ overrides and extra. It
+// also returns the source of every key. A missing file is not an error; on
+// any other error the file and fragments are ignored.
+func loadConfig(path string, extra ...config.Layer) (config.ConfigMap, config.Provenance, error) {
+	fileLayers, err := config.LoadConfigLayers(path)
+	cfg, provenance := mergeConfig(fileLayers, extra...)
+	return cfg, provenance, err
+}
+
+// mergeConfig merges the environment, the file layers, the environment
+// overrides and extra, in that order.
+func mergeConfig(fileLayers []config.Layer, extra ...config.Layer) (config.ConfigMap, config.Provenance) {
+	layers := append([]config.Layer{config.EnvLayer()}, fileLayers...)
+	layers = append(layers, config.EnvOverridesLayer())
+	return config.MergeLayers(append(layers, extra...)...)
+}
+
+// defaultConfigPath returns CONFIG_FILE, or config.yaml if it is unset.
+func defaultConfigPath() string {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		return path
+	}
+	return "config.yaml"
 }
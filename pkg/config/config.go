@@ -1,25 +1,161 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/mywio/git-ops/pkg/core"
 )
 
+// Config is the typed `core` section. Build it with LoadCoreConfig.
 type Config struct {
-	Token          string
+	Token          string `secret:"true"`
 	Users          []string
 	Topic          string
 	TargetDir      string
 	Interval       time.Duration
 	GlobalHooksDir string
 	DryRun         bool
-	SecretsDir     string // Directory to look for secret files
+	SecretsDir     string `secret:"false"` // Directory to look for secret files
+	// SecretPrecedence lists secret plugins by name, highest precedence
+	// first. Unlisted plugins follow in load order.
+	SecretPrecedence []string
+	PluginsDir       string
+	BuiltinPlugins   []string
+	HTTPAddr         string
+
+	// Sources records where each key came from, by its canonical name
+	// (e.g. "token", not "github_token"). See Source.
+	Sources map[string]Source
+}
+
+// Defaults of the core keys that have one.
+const (
+	DefaultInterval   = 5 * time.Minute
+	DefaultTargetDir  = "./stacks"
+	DefaultPluginsDir = "plugins"
+)
+
+// CoreDefaults returns the defaults of core keys by name, as they would be
+// written in a config file.
+func CoreDefaults() map[string]any {
+	return map[string]any{
+		"target_dir":  DefaultTargetDir,
+		"interval":    DefaultInterval.String(),
+		"dry_run":     false,
+		"plugins_dir": DefaultPluginsDir,
+	}
+}
+
+// Source returns where key came from; keys that were not set come from
+// SourceDefault.
+func (c Config) Source(key string) Source {
+	if s, ok := c.Sources[key]; ok {
+		return s
+	}
+	return Source{Kind: SourceDefault}
+}
+
+// LoadCoreConfig builds the typed core config from the `core` section of
+// the merged config, such as the one returned by MergeLayers, and applies
+// defaults. prov, if non-nil, supplies the source of each key. Invalid
+// values are reported together and leave the default in place. Empty
+// strings count as unset, since the environment layer defaults every key
+// to "".
+func LoadCoreConfig(cfgMap ConfigMap, prov Provenance) (Config, error) {
+	m, _ := core.UnwrapSecrets(cfgMap["core"]).(map[string]any)
+	cfg := Config{
+		TargetDir:  DefaultTargetDir,
+		Interval:   DefaultInterval,
+		PluginsDir: DefaultPluginsDir,
+		Sources:    map[string]Source{},
+	}
+
+	var errs []error
+	// lookup returns the first set key, recording its source under name.
+	lookup := func(name string, aliases ...string) (any, bool) {
+		for _, key := range append([]string{name}, aliases...) {
+			v, ok := m[key]
+			if !ok || v == nil || (isString(v) && strings.TrimSpace(v.(string)) == "") {
+				continue
+			}
+			source, ok := prov["core."+key]
+			if !ok {
+				source = Source{Kind: SourceUnknown}
+			}
+			cfg.Sources[name] = source
+			return v, true
+		}
+		return nil, false
+	}
+	invalid := func(name string, v any, want string) {
+		errs = append(errs, fmt.Errorf("core.%s: %q is not a valid %s", name, toString(v), want))
+		delete(cfg.Sources, name)
+	}
+	list := func(name string, v any) []string {
+		items, ok := stringSliceValue(v)
+		if !ok {
+			invalid(name, v, "list")
+		}
+		return nonEmpty(items)
+	}
+
+	if v, ok := lookup("token", "github_token"); ok {
+		cfg.Token = strings.TrimSpace(toString(v))
+	}
+	if v, ok := lookup("users", "github_users"); ok {
+		cfg.Users = list("users", v)
+	}
+	if v, ok := lookup("topic", "topic_filter"); ok {
+		cfg.Topic = strings.TrimSpace(toString(v))
+	}
+	if v, ok := lookup("target_dir"); ok {
+		cfg.TargetDir = toString(v)
+	}
+	if v, ok := lookup("interval", "sync_interval"); ok {
+		if d, ok := durationValue(v); ok && d > 0 {
+			cfg.Interval = d
+		} else {
+			invalid("interval", v, "positive duration")
+		}
+	}
+	if v, ok := lookup("dry_run"); ok {
+		if b, ok := parseBool(v); ok {
+			cfg.DryRun = b
+		} else {
+			invalid("dry_run", v, "bool")
+		}
+	}
+	if v, ok := lookup("global_hooks_dir"); ok {
+		cfg.GlobalHooksDir = toString(v)
+	}
+	if v, ok := lookup("secrets_dir"); ok {
+		cfg.SecretsDir = toString(v)
+	}
+	if v, ok := lookup("secret_precedence"); ok {
+		cfg.SecretPrecedence = list("secret_precedence", v)
+	}
+	if v, ok := lookup("plugins_dir"); ok {
+		cfg.PluginsDir = toString(v)
+	}
+	if v, ok := lookup("builtin_plugins"); ok {
+		cfg.BuiltinPlugins = list("builtin_plugins", v)
+	}
+	if v, ok := lookup("http_addr"); ok {
+		cfg.HTTPAddr = strings.TrimSpace(toString(v))
+	}
+	return cfg, errors.Join(errs...)
 }
 
+// LoadConfig reads the core config from the legacy environment variables
+// only.
+//
+// Deprecated: use LoadCoreConfig on the merged config, which also honours
+// the config file and GITOPS__ overrides.
 func LoadConfig() Config {
 	interval, _ := time.ParseDuration(os.Getenv("SYNC_INTERVAL"))
 	if interval == 0 {
@@ -33,14 +169,15 @@ func LoadConfig() Config {
 	}
 
 	return Config{
-		Token:          os.Getenv("GITHUB_TOKEN"),
-		Users:          users,
-		Topic:          os.Getenv("TOPIC_FILTER"),
-		TargetDir:      os.Getenv("TARGET_DIR"),
-		Interval:       interval,
-		DryRun:         os.Getenv("DRY_RUN") == "true",
-		GlobalHooksDir: os.Getenv("GLOBAL_HOOKS_DIR"),
-		SecretsDir:     os.Getenv("SECRETS_DIR"),
+		Token:            os.Getenv("GITHUB_TOKEN"),
+		Users:            users,
+		Topic:            os.Getenv("TOPIC_FILTER"),
+		TargetDir:        os.Getenv("TARGET_DIR"),
+		Interval:         interval,
+		DryRun:           os.Getenv("DRY_RUN") == "true",
+		GlobalHooksDir:   os.Getenv("GLOBAL_HOOKS_DIR"),
+		SecretsDir:       os.Getenv("SECRETS_DIR"),
+		SecretPrecedence: splitNonEmpty(os.Getenv("SECRET_PRECEDENCE")),
 	}
 }
 
@@ -48,30 +185,23 @@ func LoadConfig() Config {
 // Values are YAML-friendly scalars or nested maps/lists.
 type ConfigMap map[string]map[string]any
 
-// LoadConfigFile loads a YAML config file from disk.
+// LoadConfigFile loads a YAML config file from disk and resolves ${...}
+// references in its values (see resolveReferences). Merge tags are accepted
+// but have nothing to merge with; use LoadConfigLayers for conf.d fragments.
 // Returns an empty map if the file does not exist or is empty.
 func LoadConfigFile(path string) (ConfigMap, error) {
 	if path == "" {
 		return ConfigMap{}, nil
 	}
 
-	data, err := os.ReadFile(path)
+	layer, err := loadLayer(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return ConfigMap{}, nil
 		}
 		return nil, err
 	}
-	if len(strings.TrimSpace(string(data))) == 0 {
-		return ConfigMap{}, nil
-	}
-
-	var raw map[string]any
-	if err := yaml.Unmarshal(data, &raw); err != nil {
-		return nil, err
-	}
-
-	return normalizeConfigMap(raw), nil
+	return layer.Config, nil
 }
 
 // LoadConfigMapFromEnv builds a sectioned config map from environment variables.
@@ -79,16 +209,18 @@ func LoadConfigFile(path string) (ConfigMap, error) {
 func LoadConfigMapFromEnv() ConfigMap {
 	cfg := ConfigMap{
 		"core": {
-			"token":            os.Getenv("GITHUB_TOKEN"),
-			"users":            os.Getenv("GITHUB_USERS"),
-			"topic":            os.Getenv("TOPIC_FILTER"),
-			"target_dir":       os.Getenv("TARGET_DIR"),
-			"interval":         os.Getenv("SYNC_INTERVAL"),
-			"dry_run":          os.Getenv("DRY_RUN"),
-			"global_hooks_dir": os.Getenv("GLOBAL_HOOKS_DIR"),
-			"secrets_dir":      os.Getenv("SECRETS_DIR"),
-			"plugins_dir":      os.Getenv("PLUGINS_DIR"),
-			"http_addr":        os.Getenv("CORE_HTTP_ADDR"),
+			"token":             os.Getenv("GITHUB_TOKEN"),
+			"users":             os.Getenv("GITHUB_USERS"),
+			"topic":             os.Getenv("TOPIC_FILTER"),
+			"target_dir":        os.Getenv("TARGET_DIR"),
+			"interval":          os.Getenv("SYNC_INTERVAL"),
+			"dry_run":           os.Getenv("DRY_RUN"),
+			"global_hooks_dir":  os.Getenv("GLOBAL_HOOKS_DIR"),
+			"secrets_dir":       os.Getenv("SECRETS_DIR"),
+			"secret_precedence": os.Getenv("SECRET_PRECEDENCE"),
+			"plugins_dir":       os.Getenv("PLUGINS_DIR"),
+			"builtin_plugins":   os.Getenv("BUILTIN_PLUGINS"),
+			"http_addr":         os.Getenv("CORE_HTTP_ADDR"),
 		},
 		"pushover": {
 			"token": os.Getenv("NOTIFY_PUSHOVER_TOKEN"),
@@ -115,13 +247,24 @@ func LoadConfigMapFromEnv() ConfigMap {
 	if v := os.Getenv("NOTIFY_WEBHOOK_EVENTS"); v != "" {
 		cfg["webhook"]["subscribe"] = v
 	}
+	if digests, key := os.Getenv("PLUGIN_SHA256"), os.Getenv("PLUGIN_PUBLIC_KEY_FILE"); digests != "" || key != "" {
+		cfg["core"]["plugin_verification"] = map[string]any{
+			"sha256":          digests,
+			"public_key_file": key,
+		}
+	}
 	return cfg
 }
 
 // LoadConfigFromMap builds a core Config from a map.
-// Supported keys (yaml): token, users, topic, target_dir, interval, dry_run, global_hooks_dir, secrets_dir.
+//
+// Deprecated: use LoadCoreConfig, which validates values and records their
+// source.
+// Supported keys (yaml): token, users, topic, target_dir, interval, dry_run, global_hooks_dir, secrets_dir,
+// secret_precedence.
 func LoadConfigFromMap(m map[string]any) Config {
 	cfg := Config{}
+	m, _ = core.UnwrapSecrets(m).(map[string]any)
 
 	if v, ok := getString(m, "token", "github_token"); ok {
 		cfg.Token = v
@@ -147,6 +290,9 @@ func LoadConfigFromMap(m map[string]any) Config {
 	if v, ok := getString(m, "secrets_dir"); ok {
 		cfg.SecretsDir = v
 	}
+	if v, ok := getStringSlice(m, "secret_precedence"); ok {
+		cfg.SecretPrecedence = nonEmpty(v)
+	}
 
 	if cfg.Interval == 0 {
 		cfg.Interval = 5 * time.Minute
@@ -155,7 +301,11 @@ func LoadConfigFromMap(m map[string]any) Config {
 	return cfg
 }
 
-// MergeConfig uses primary values when set, otherwise falls back.
+// MergeConfig uses primary values when set, otherwise falls back. A
+// fallback dry_run of true cannot be turned off by primary.
+//
+// Deprecated: merge config maps (MergeLayers) and build the typed config
+// once with LoadCoreConfig.
 func MergeConfig(primary, fallback Config) Config {
 	out := primary
 	if out.Token == "" {
@@ -179,33 +329,45 @@ func MergeConfig(primary, fallback Config) Config {
 	if out.SecretsDir == "" {
 		out.SecretsDir = fallback.SecretsDir
 	}
+	if len(out.SecretPrecedence) == 0 {
+		out.SecretPrecedence = fallback.SecretPrecedence
+	}
 	if !out.DryRun && fallback.DryRun {
 		out.DryRun = true
 	}
 	return out
 }
 
-// MergeConfigMap merges primary over fallback (primary wins).
+// MergeConfigMap merges primary over fallback (primary wins). Nested maps
+// are merged key by key; lists and scalars from primary replace fallback's.
 func MergeConfigMap(primary, fallback ConfigMap) ConfigMap {
 	out := cloneConfigMap(fallback)
 	for section, vals := range primary {
 		if len(vals) == 0 {
 			continue
 		}
-		merged := map[string]any{}
-		if existing, ok := out[section]; ok {
-			for k, v := range existing {
-				merged[k] = v
-			}
-		}
-		for k, v := range vals {
-			merged[k] = v
-		}
-		out[section] = merged
+		out[section] = mergeMaps(vals, out[section])
 	}
 	return out
 }
 
+func mergeMaps(primary, fallback map[string]any) map[string]any {
+	merged := make(map[string]any, len(primary)+len(fallback))
+	for k, v := range fallback {
+		merged[k] = v
+	}
+	for k, v := range primary {
+		pm, pok := v.(map[string]any)
+		fm, fok := merged[k].(map[string]any)
+		if pok && fok {
+			merged[k] = mergeMaps(pm, fm)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
 func cloneConfigMap(src ConfigMap) ConfigMap {
 	dst := ConfigMap{}
 	for section, vals := range src {
@@ -290,6 +452,22 @@ func toString(v any) string {
 	return fmt.Sprint(v)
 }
 
+func isString(v any) bool {
+	_, ok := v.(string)
+	return ok
+}
+
+func parseBool(v any) (bool, bool) {
+	switch t := v.(type) {
+	case bool:
+		return t, true
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(t))
+		return b, err == nil
+	}
+	return false, false
+}
+
 func getBool(m map[string]any, keys ...string) (bool, bool) {
 	for _, key := range keys {
 		if v, ok := m[key]; ok {
@@ -313,46 +491,76 @@ func getBool(m map[string]any, keys ...string) (bool, bool) {
 func getDuration(m map[string]any, keys ...string) (time.Duration, bool) {
 	for _, key := range keys {
 		if v, ok := m[key]; ok {
-			switch t := v.(type) {
-			case time.Duration:
-				return t, true
-			case string:
-				d, err := time.ParseDuration(strings.TrimSpace(t))
-				if err == nil {
-					return d, true
-				}
-			case int:
-				return time.Duration(t) * time.Second, true
-			case int64:
-				return time.Duration(t) * time.Second, true
-			case float64:
-				return time.Duration(t) * time.Second, true
+			if d, ok := durationValue(v); ok {
+				return d, true
 			}
 		}
 	}
 	return 0, false
 }
 
+// durationValue parses a duration string; numbers are seconds.
+func durationValue(v any) (time.Duration, bool) {
+	switch t := v.(type) {
+	case time.Duration:
+		return t, true
+	case string:
+		d, err := time.ParseDuration(strings.TrimSpace(t))
+		if err == nil {
+			return d, true
+		}
+	case int:
+		return time.Duration(t) * time.Second, true
+	case int64:
+		return time.Duration(t) * time.Second, true
+	case float64:
+		return time.Duration(t) * time.Second, true
+	}
+	return 0, false
+}
+
 func getStringSlice(m map[string]any, keys ...string) ([]string, bool) {
 	for _, key := range keys {
 		if v, ok := m[key]; ok {
-			switch t := v.(type) {
-			case []any:
-				out := make([]string, 0, len(t))
-				for _, item := range t {
-					out = append(out, strings.TrimSpace(toString(item)))
-				}
-				return out, true
-			case []string:
-				return t, true
-			case string:
-				parts := strings.Split(t, ",")
-				for i := range parts {
-					parts[i] = strings.TrimSpace(parts[i])
-				}
-				return parts, true
+			if items, ok := stringSliceValue(v); ok {
+				return items, true
 			}
 		}
 	}
 	return nil, false
 }
+
+// stringSliceValue accepts a list or a comma-separated string.
+func stringSliceValue(v any) ([]string, bool) {
+	switch t := v.(type) {
+	case []any:
+		out := make([]string, 0, len(t))
+		for _, item := range t {
+			out = append(out, strings.TrimSpace(toString(item)))
+		}
+		return out, true
+	case []string:
+		return t, true
+	case string:
+		parts := strings.Split(t, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		return parts, true
+	}
+	return nil, false
+}
+
+func splitNonEmpty(s string) []string {
+	return nonEmpty(strings.Split(s, ","))
+}
+
+func nonEmpty(items []string) []string {
+	var out []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
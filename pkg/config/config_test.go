@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mywio/git-ops/pkg/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCoreConfig_Defaults(t *testing.T) {
+	cfg, err := LoadCoreConfig(ConfigMap{"core": {"token": "", "dry_run": ""}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultInterval, cfg.Interval)
+	assert.Equal(t, DefaultTargetDir, cfg.TargetDir)
+	assert.Equal(t, DefaultPluginsDir, cfg.PluginsDir)
+	assert.False(t, cfg.DryRun)
+	assert.Equal(t, Source{Kind: SourceDefault}, cfg.Source("interval"))
+	assert.Equal(t, Source{Kind: SourceDefault}, cfg.Source("token"))
+}
+
+func TestLoadCoreConfig_Layers(t *testing.T) {
+	env := Layer{Kind: SourceEnv, Source: "environment", Config: ConfigMap{"core": {
+		"github_token": "from-env",
+		"dry_run":      "true",
+		"interval":     "1m",
+	}}}
+	file := Layer{Kind: SourceFile, Source: "config.yaml", Config: ConfigMap{"core": {
+		"dry_run":         false,
+		"users":           "a, b,",
+		"builtin_plugins": []any{"reconciler", "ui"},
+		"http_addr":       core.NewSecret("127.0.0.1:8080"),
+		"plugins_dir":     "/opt/plugins",
+	}}}
+
+	cfg, err := LoadCoreConfig(MergeLayers(env, file))
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", cfg.Token)
+	assert.False(t, cfg.DryRun, "the file turns dry_run off")
+	assert.Equal(t, time.Minute, cfg.Interval)
+	assert.Equal(t, []string{"a", "b"}, cfg.Users)
+	assert.Equal(t, []string{"reconciler", "ui"}, cfg.BuiltinPlugins)
+	assert.Equal(t, "127.0.0.1:8080", cfg.HTTPAddr)
+	assert.Equal(t, "/opt/plugins", cfg.PluginsDir)
+
+	assert.Equal(t, Source{Kind: SourceEnv, Name: "environment"}, cfg.Source("token"))
+	assert.Equal(t, Source{Kind: SourceFile, Name: "config.yaml"}, cfg.Source("dry_run"))
+	assert.Equal(t, Source{Kind: SourceDefault}, cfg.Source("target_dir"))
+}
+
+func TestLoadCoreConfig_Invalid(t *testing.T) {
+	cfg, err := LoadCoreConfig(ConfigMap{"core": {
+		"interval": "soon",
+		"dry_run":  "maybe",
+		"users":    42,
+		"topic":    "t",
+	}}, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `core.interval: "soon" is not a valid positive duration`)
+	assert.Contains(t, err.Error(), `core.dry_run: "maybe" is not a valid bool`)
+	assert.Contains(t, err.Error(), `core.users: "42" is not a valid list`)
+	assert.Equal(t, DefaultInterval, cfg.Interval)
+	assert.Equal(t, "t", cfg.Topic)
+	assert.Equal(t, Source{Kind: SourceDefault}, cfg.Source("interval"))
+	assert.Equal(t, Source{Kind: SourceUnknown}, cfg.Source("topic"))
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvOverridePrefix starts the name of an environment variable that sets a
+// single config key: GITOPS__<SECTION>__<KEY>[__<KEY>...]. Names are
+// lowercased, so GITOPS__ENV_FORWARDER__PREFIXES sets env_forwarder.prefixes.
+// Further segments address nested maps, and numeric segments address list
+// items:
+//
+//	GITOPS__CORE__RESTART_POLICY__MODE=never
+//	GITOPS__FILE_FORWARDER__FILES__0__PATH=/etc/tls/tls.crt
+//	GITOPS__ENV_FORWARDER__KEYS=[DB_PASSWORD, API_KEY]
+const EnvOverridePrefix = "GITOPS__"
+
+var plainInt = regexp.MustCompile(`^-?(0|[1-9][0-9]*)$`)
+
+// LoadConfigOverridesFromEnv builds a config map from GITOPS__ environment
+// variables. Empty variables are ignored.
+func LoadConfigOverridesFromEnv() ConfigMap {
+	return configOverrides(os.Environ())
+}
+
+func configOverrides(environ []string) ConfigMap {
+	sort.Strings(environ)
+	root := map[string]any{}
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvOverridePrefix) || value == "" {
+			continue
+		}
+		path := strings.Split(strings.ToLower(strings.TrimPrefix(name, EnvOverridePrefix)), "__")
+		if len(path) < 2 || containsEmpty(path) {
+			continue
+		}
+		setPath(root, path, envValue(value))
+	}
+
+	out := ConfigMap{}
+	for section, value := range root {
+		if m, ok := listify(value).(map[string]any); ok {
+			out[section] = m
+		}
+	}
+	return out
+}
+
+// envValue types an override value: flow collections ([a, b] or {k: v}) are
+// parsed as YAML, true/false become bools and plain decimal integers become
+// ints. Anything else, including numbers with a leading zero such as file
+// modes, stays a string.
+func envValue(value string) any {
+	trimmed := strings.TrimSpace(value)
+	switch {
+	case strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{"):
+		var v any
+		if err := yaml.Unmarshal([]byte(trimmed), &v); err == nil {
+			return normalizeValue(v)
+		}
+	case strings.EqualFold(trimmed, "true"), strings.EqualFold(trimmed, "false"):
+		return strings.EqualFold(trimmed, "true")
+	case plainInt.MatchString(trimmed):
+		if n, err := strconv.Atoi(trimmed); err == nil {
+			return n
+		}
+	}
+	return value
+}
+
+func setPath(m map[string]any, path []string, value any) {
+	for _, key := range path[:len(path)-1] {
+		next, ok := m[key].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			m[key] = next
+		}
+		m = next
+	}
+	m[path[len(path)-1]] = value
+}
+
+// listify turns maps whose keys are all list indexes into lists, ordered by
+// index.
+func listify(v any) any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+	indexes := make([]int, 0, len(m))
+	for key, item := range m {
+		m[key] = listify(item)
+		if n, err := strconv.Atoi(key); err == nil && n >= 0 {
+			indexes = append(indexes, n)
+		}
+	}
+	if len(indexes) == 0 || len(indexes) != len(m) {
+		return m
+	}
+	sort.Ints(indexes)
+	list := make([]any, 0, len(indexes))
+	for _, n := range indexes {
+		list = append(list, m[strconv.Itoa(n)])
+	}
+	return list
+}
+
+func containsEmpty(items []string) bool {
+	for _, item := range items {
+		if item == "" {
+			return true
+		}
+	}
+	return false
+}
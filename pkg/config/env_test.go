@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigOverrides(t *testing.T) {
+	cfg := configOverrides([]string{
+		"GITOPS__AUDIT__STORAGE=sqlite",
+		"GITOPS__AUDIT__RETENTION_COUNT=500",
+		"GITOPS__CORE__DRY_RUN=true",
+		"GITOPS__CORE__RESTART_POLICY__MODE=never",
+		"GITOPS__ENV_FORWARDER__KEYS=[DB_PASSWORD, API_KEY]",
+		"GITOPS__FILE_FORWARDER__FILES__1__PATH=/etc/b",
+		"GITOPS__FILE_FORWARDER__FILES__0__PATH=/etc/a",
+		"GITOPS__FILE_FORWARDER__FILES__0__MODE=0600",
+		"GITOPS__PUSHOVER__TOKEN=",
+		"GITOPS__NOSECTION=ignored",
+		"OTHER__CORE__TOKEN=ignored",
+	})
+
+	assert.Equal(t, ConfigMap{
+		"audit": {"storage": "sqlite", "retention_count": 500},
+		"core": {
+			"dry_run":        true,
+			"restart_policy": map[string]any{"mode": "never"},
+		},
+		"env_forwarder": {"keys": []any{"DB_PASSWORD", "API_KEY"}},
+		"file_forwarder": {"files": []any{
+			map[string]any{"path": "/etc/a", "mode": "0600"},
+			map[string]any{"path": "/etc/b"},
+		}},
+	}, cfg)
+}
+
+func TestMergeConfigMap_Nested(t *testing.T) {
+	file := ConfigMap{"core": {
+		"token":          "abc",
+		"users":          []any{"a", "b"},
+		"restart_policy": map[string]any{"mode": "on-failure", "backoff": "1s"},
+	}}
+	overrides := ConfigMap{"core": {
+		"users":          []any{"c"},
+		"restart_policy": map[string]any{"mode": "never"},
+	}}
+
+	merged := MergeConfigMap(overrides, file)
+	assert.Equal(t, "abc", merged["core"]["token"])
+	assert.Equal(t, []any{"c"}, merged["core"]["users"])
+	assert.Equal(t, map[string]any{"mode": "never", "backoff": "1s"}, merged["core"]["restart_policy"])
+	assert.Equal(t, map[string]any{"mode": "on-failure", "backoff": "1s"}, file["core"]["restart_policy"], "fallback must not be modified")
+}
@@ -0,0 +1,398 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigDirName is the directory of config fragments read after a config
+// file, next to it: /etc/git-ops/config.yaml is followed by
+// /etc/git-ops/conf.d/*.yaml.
+const ConfigDirName = "conf.d"
+
+// Merge tags change how a value in a fragment is merged over the layers
+// before it. Without a tag, maps are merged key by key and lists and scalars
+// replace.
+const (
+	// TagAppend appends a list to the list of the layers before it.
+	TagAppend = "!append"
+	// TagReplace replaces a map wholesale instead of merging it.
+	TagReplace = "!replace"
+)
+
+type mergeMode int
+
+const (
+	mergeDefault mergeMode = iota
+	mergeAppend
+	mergeReplace
+)
+
+// SourceKind classifies where a config value came from.
+type SourceKind string
+
+const (
+	SourceDefault SourceKind = "default" // not set; the built-in default applies
+	SourceEnv     SourceKind = "env"     // an environment variable
+	SourceFile    SourceKind = "file"    // a config file or conf.d fragment
+	SourceFlag    SourceKind = "flag"    // a command-line flag, e.g. --dry-run
+	// SourceUnknown is a value that is set, but whose provenance was not
+	// recorded, e.g. a config map handed to a plugin.
+	SourceUnknown SourceKind = "unknown"
+)
+
+// Source is where a config value came from.
+type Source struct {
+	Kind SourceKind `json:"kind"`
+	// Name is the file path or variable, if known. An appended list names
+	// every file it came from, separated by ", ".
+	Name string `json:"name,omitempty"`
+}
+
+func (s Source) String() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return string(s.Kind)
+}
+
+// Layer is one source of configuration. Layers are merged in order by
+// MergeLayers, later layers winning.
+type Layer struct {
+	// Kind and Source name the layer in provenance; Source is e.g. a file
+	// path.
+	Kind   SourceKind
+	Source string
+	Config ConfigMap
+
+	// modes holds the !append and !replace tags, keyed by dotted path.
+	modes map[string]mergeMode
+	// keySource, if set, names the source of one key more precisely.
+	keySource func(path string) string
+}
+
+func (l Layer) sourceOf(path string) Source {
+	if l.keySource != nil {
+		return Source{Kind: l.Kind, Name: l.keySource(path)}
+	}
+	return Source{Kind: l.Kind, Name: l.Source}
+}
+
+// Provenance maps the dotted path of every set key (section.key, or
+// section.key.nested for keys of nested maps) to the source of its value.
+// Lists are a single key; an appended list names every layer it came from.
+type Provenance map[string]Source
+
+// Keys returns the paths in p, sorted.
+func (p Provenance) Keys() []string {
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// drop forgets path and every key below it.
+func (p Provenance) drop(path string) {
+	for k := range p {
+		if k == path || strings.HasPrefix(k, path+".") {
+			delete(p, k)
+		}
+	}
+}
+
+// EnvLayer is the layer of the legacy environment variables such as
+// GITHUB_TOKEN (see LoadConfigMapFromEnv).
+func EnvLayer() Layer {
+	return Layer{Kind: SourceEnv, Source: "environment", Config: LoadConfigMapFromEnv()}
+}
+
+// EnvOverridesLayer is the layer of GITOPS__ environment variables (see
+// LoadConfigOverridesFromEnv). Keys are attributed to their variable.
+func EnvOverridesLayer() Layer {
+	return Layer{
+		Kind:   SourceEnv,
+		Source: "environment",
+		Config: LoadConfigOverridesFromEnv(),
+		keySource: func(path string) string {
+			return "env " + EnvOverridePrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "__"))
+		},
+	}
+}
+
+// ConfigFiles returns the files that make up the config at path, in the
+// order they are merged. path may be a file, which is followed by the
+// *.yaml and *.yml fragments in the conf.d directory next to it, or a
+// directory of fragments. Fragments are read in lexical order, so number
+// them: 00-base.yaml, 50-host.yaml. Missing files and directories are
+// skipped.
+func ConfigFiles(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		return fragmentFiles(filepath.Join(filepath.Dir(path), ConfigDirName))
+	case err != nil:
+		return nil, err
+	case info.IsDir():
+		return fragmentFiles(path)
+	}
+	fragments, err := fragmentFiles(filepath.Join(filepath.Dir(path), ConfigDirName))
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{path}, fragments...), nil
+}
+
+func fragmentFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// LoadConfigLayers loads every file of the config at path (see ConfigFiles)
+// as a layer, in merge order. Errors name the file they occurred in.
+func LoadConfigLayers(path string) ([]Layer, error) {
+	files, err := ConfigFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	layers := make([]Layer, 0, len(files))
+	for _, file := range files {
+		layer, err := loadLayer(file)
+		if err != nil {
+			if len(files) > 1 {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+// CheckConfigLayers is LoadConfigLayers for validation: a file with
+// unresolvable ${...} references still yields its layer, with those values
+// left as written, and every such reference is returned as its own problem.
+// The error is for files that cannot be read or parsed at all.
+func CheckConfigLayers(path string) ([]Layer, []error, error) {
+	files, err := ConfigFiles(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	layers := make([]Layer, 0, len(files))
+	var problems []error
+	for _, file := range files {
+		layer, err := parseLayer(file)
+		if err != nil {
+			if len(files) > 1 {
+				return nil, nil, fmt.Errorf("%s: %w", file, err)
+			}
+			return nil, nil, err
+		}
+		for _, err := range splitErrors(resolveReferences(layer.Config)) {
+			if len(files) > 1 {
+				err = fmt.Errorf("%s: %w", file, err)
+			}
+			problems = append(problems, err)
+		}
+		layers = append(layers, layer)
+	}
+	return layers, problems, nil
+}
+
+// splitErrors flattens errors joined with errors.Join.
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, splitErrors(e)...)
+	}
+	return errs
+}
+
+// loadLayer reads one YAML file, records its merge tags and resolves
+// ${...} references in its values. An empty file is an empty layer.
+func loadLayer(path string) (Layer, error) {
+	layer, err := parseLayer(path)
+	if err != nil {
+		return layer, err
+	}
+	if err := resolveReferences(layer.Config); err != nil {
+		return layer, err
+	}
+	return layer, nil
+}
+
+// parseLayer reads one YAML file and records its merge tags, leaving ${...}
+// references unresolved.
+func parseLayer(path string) (Layer, error) {
+	layer := Layer{Kind: SourceFile, Source: path, Config: ConfigMap{}, modes: map[string]mergeMode{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return layer, err
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return layer, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return layer, err
+	}
+	if len(doc.Content) > 0 {
+		if err := collectMergeTags(doc.Content[0], "", layer.modes); err != nil {
+			return layer, err
+		}
+	}
+	var raw map[string]any
+	if err := doc.Decode(&raw); err != nil {
+		return layer, err
+	}
+
+	layer.Config = normalizeConfigMap(raw)
+	return layer, nil
+}
+
+// collectMergeTags records the merge tags on map values below node, keyed
+// by dotted path, and removes them so the node decodes as plain YAML.
+func collectMergeTags(node *yaml.Node, path string, modes map[string]mergeMode) error {
+	var errs []error
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPath := key.Value
+			if path != "" {
+				childPath = path + "." + key.Value
+			}
+			switch value.Tag {
+			case TagAppend:
+				if value.Kind != yaml.SequenceNode {
+					errs = append(errs, fmt.Errorf("%s: %s needs a list (line %d)", childPath, TagAppend, value.Line))
+				}
+				modes[childPath] = mergeAppend
+				value.Tag = ""
+			case TagReplace:
+				if value.Kind != yaml.MappingNode && value.Kind != yaml.SequenceNode {
+					errs = append(errs, fmt.Errorf("%s: %s needs a map or a list (line %d)", childPath, TagReplace, value.Line))
+				}
+				modes[childPath] = mergeReplace
+				value.Tag = ""
+			}
+			if err := collectMergeTags(value, childPath, modes); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if item.Tag == TagAppend || item.Tag == TagReplace {
+				errs = append(errs, fmt.Errorf("%s: %s is only supported on map values (line %d)", path, item.Tag, item.Line))
+				item.Tag = ""
+			}
+			if err := collectMergeTags(item, path, modes); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MergeLayers deep-merges layers in order, later layers winning, and
+// reports the source of every set key. Maps are merged key by key unless
+// tagged !replace; lists replace unless tagged !append; scalars replace.
+// The layers are not modified.
+func MergeLayers(layers ...Layer) (ConfigMap, Provenance) {
+	out := ConfigMap{}
+	prov := Provenance{}
+	for _, layer := range layers {
+		sections := make([]string, 0, len(layer.Config))
+		for section := range layer.Config {
+			sections = append(sections, section)
+		}
+		sort.Strings(sections)
+		for _, section := range sections {
+			values := layer.Config[section]
+			dst := map[string]any{}
+			if layer.modes[section] == mergeReplace {
+				prov.drop(section)
+			} else {
+				for k, v := range out[section] {
+					dst[k] = v
+				}
+			}
+			layer.mergeInto(dst, values, section, prov)
+			out[section] = dst
+		}
+	}
+	return out, prov
+}
+
+func (l Layer) mergeInto(dst, src map[string]any, path string, prov Provenance) {
+	for key, value := range src {
+		keyPath := path + "." + key
+		mode := l.modes[keyPath]
+		switch v := value.(type) {
+		case map[string]any:
+			merged := map[string]any{}
+			if existing, ok := dst[key].(map[string]any); ok && mode != mergeReplace {
+				for k, item := range existing {
+					merged[k] = item
+				}
+			} else {
+				prov.drop(keyPath)
+			}
+			l.mergeInto(merged, v, keyPath, prov)
+			dst[key] = merged
+		case []any:
+			if existing, ok := dst[key].([]any); ok && mode == mergeAppend {
+				list := make([]any, 0, len(existing)+len(v))
+				dst[key] = append(append(list, existing...), v...)
+				source := l.sourceOf(keyPath)
+				if previous, ok := prov[keyPath]; ok {
+					source.Name = previous.String() + ", " + source.String()
+				}
+				prov[keyPath] = source
+				continue
+			}
+			prov.drop(keyPath)
+			prov[keyPath] = l.sourceOf(keyPath)
+			dst[key] = v
+		default:
+			prov.drop(keyPath)
+			if v != nil && v != "" {
+				prov[keyPath] = l.sourceOf(keyPath)
+			}
+			dst[key] = v
+		}
+	}
+}
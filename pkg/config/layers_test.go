@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+}
+
+func TestConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeFile(t, path, "core: {}\n")
+	writeFile(t, filepath.Join(dir, ConfigDirName, "50-host.yml"), "")
+	writeFile(t, filepath.Join(dir, ConfigDirName, "00-base.yaml"), "")
+	writeFile(t, filepath.Join(dir, ConfigDirName, "README.md"), "")
+	writeFile(t, filepath.Join(dir, ConfigDirName, ".10-hidden.yaml"), "")
+
+	files, err := ConfigFiles(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		path,
+		filepath.Join(dir, ConfigDirName, "00-base.yaml"),
+		filepath.Join(dir, ConfigDirName, "50-host.yml"),
+	}, files)
+
+	files, err = ConfigFiles(filepath.Join(dir, ConfigDirName))
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	files, err = ConfigFiles(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestMergeLayers(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.yaml")
+	host := filepath.Join(dir, ConfigDirName, "10-host.yaml")
+	writeFile(t, base, `
+core:
+  users: [a, b]
+  restart_policy:
+    mode: on-failure
+    backoff: 1s
+env_forwarder:
+  keys: [A]
+pushover:
+  token: abc
+  user: u
+`)
+	writeFile(t, host, `
+core:
+  users: !append [c]
+  restart_policy:
+    mode: never
+env_forwarder:
+  keys: [B]
+pushover: !replace
+  token: def
+`)
+
+	layers, err := LoadConfigLayers(base)
+	require.NoError(t, err)
+	env := Layer{Kind: SourceEnv, Source: "environment", Config: ConfigMap{"core": {"topic": "t", "target_dir": ""}}}
+	cfg, prov := MergeLayers(append([]Layer{env}, layers...)...)
+
+	assert.Equal(t, []any{"a", "b", "c"}, cfg["core"]["users"])
+	assert.Equal(t, map[string]any{"mode": "never", "backoff": "1s"}, cfg["core"]["restart_policy"])
+	assert.Equal(t, []any{"B"}, cfg["env_forwarder"]["keys"])
+	assert.Equal(t, map[string]any{"token": "def"}, cfg["pushover"])
+	assert.Equal(t, "t", cfg["core"]["topic"])
+
+	assert.Equal(t, Provenance{
+		"core.topic":                  {Kind: SourceEnv, Name: "environment"},
+		"core.users":                  {Kind: SourceFile, Name: base + ", " + host},
+		"core.restart_policy.backoff": {Kind: SourceFile, Name: base},
+		"core.restart_policy.mode":    {Kind: SourceFile, Name: host},
+		"env_forwarder.keys":          {Kind: SourceFile, Name: host},
+		"pushover.token":              {Kind: SourceFile, Name: host},
+	}, prov)
+
+	// Layers are not modified by merging.
+	assert.Equal(t, []any{"a", "b"}, layers[0].Config["core"]["users"])
+	assert.Equal(t, "u", layers[0].Config["pushover"]["user"])
+}
+
+func TestLoadConfigLayers_TagErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ConfigDirName, "10-bad.yaml")
+	writeFile(t, path, "core:\n  topic: !append x\n")
+	writeFile(t, filepath.Join(dir, ConfigDirName, "20-ok.yaml"), "core: {}\n")
+
+	_, err := LoadConfigLayers(filepath.Join(dir, ConfigDirName))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), path+": core.topic: !append needs a list")
+}
+
+func TestEnvOverridesLayer_Provenance(t *testing.T) {
+	t.Setenv("GITOPS__CORE__RESTART_POLICY__MODE", "never")
+
+	_, prov := MergeLayers(EnvOverridesLayer())
+	assert.Equal(t, Source{Kind: SourceEnv, Name: "env GITOPS__CORE__RESTART_POLICY__MODE"}, prov["core.restart_policy.mode"])
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mywio/git-ops/pkg/core"
+)
+
+// resolveReferences expands references in every string value of the config:
+//
+//	${VAR}              environment variable VAR; an error if it is unset
+//	${VAR:-default}     VAR, or default if VAR is unset or empty
+//	${env:VAR}          same as ${VAR}
+//	${file:/path}       contents of the file, without the trailing newline
+//	$${                 a literal "${"
+//
+// A value that contained a reference becomes a core.Secret, so it is redacted
+// wherever the config is shown. Map keys are not expanded. All unresolvable
+// references are reported together, prefixed with their section.key path.
+func resolveReferences(cfg ConfigMap) error {
+	var errs []error
+	sections := make([]string, 0, len(cfg))
+	for section := range cfg {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+	for _, section := range sections {
+		values := cfg[section]
+		for key, value := range values {
+			resolved, err := resolveValue(value, section+"."+key)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			values[key] = resolved
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func resolveValue(v any, path string) (any, error) {
+	switch t := v.(type) {
+	case string:
+		if !strings.Contains(t, "${") {
+			return t, nil
+		}
+		expanded, substituted, err := expandReferences(t)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if !substituted {
+			return expanded, nil
+		}
+		return core.NewSecret(expanded), nil
+	case map[string]any:
+		var errs []error
+		for key, item := range t {
+			resolved, err := resolveValue(item, path+"."+key)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			t[key] = resolved
+		}
+		return t, errors.Join(errs...)
+	case []any:
+		var errs []error
+		for i, item := range t {
+			resolved, err := resolveValue(item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			t[i] = resolved
+		}
+		return t, errors.Join(errs...)
+	default:
+		return v, nil
+	}
+}
+
+// expandReferences replaces every ${...} reference in s and reports whether
+// there was any. A string that only contains escaped "$${" is returned with
+// the escapes removed and is not reported as substituted.
+func expandReferences(s string) (string, bool, error) {
+	var b strings.Builder
+	substituted := false
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), substituted, nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1])
+			b.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", false, fmt.Errorf("unterminated reference %q", s[i:])
+		}
+		value, err := lookupReference(s[i+2 : i+end])
+		if err != nil {
+			return "", false, err
+		}
+		substituted = true
+		b.WriteString(s[:i])
+		b.WriteString(value)
+		s = s[i+end+1:]
+	}
+}
+
+func lookupReference(ref string) (string, error) {
+	kind, arg, found := strings.Cut(ref, ":")
+	if !found || strings.HasPrefix(arg, "-") {
+		// ${VAR} or ${VAR:-default}
+		name, def, hasDefault := strings.Cut(ref, ":-")
+		if name == "" {
+			return "", fmt.Errorf("empty reference ${%s}", ref)
+		}
+		if v := os.Getenv(name); v != "" || (!hasDefault && envSet(name)) {
+			return v, nil
+		}
+		if hasDefault {
+			return def, nil
+		}
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	switch kind {
+	case "env":
+		if !envSet(arg) {
+			return "", fmt.Errorf("environment variable %s is not set", arg)
+		}
+		return os.Getenv(arg), nil
+	case "file":
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", arg, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	default:
+		return "", fmt.Errorf("unknown reference type %q in ${%s} (want env or file)", kind, ref)
+	}
+}
+
+func envSet(name string) bool {
+	_, ok := os.LookupEnv(name)
+	return ok
+}
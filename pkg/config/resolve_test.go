@@ -0,0 +1,65 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mywio/git-ops/pkg/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigFile_ResolvesReferences(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("from-file\n"), 0o600))
+	t.Setenv("GITOPS_TEST_TOKEN", "ghp_env")
+	t.Setenv("GITOPS_TEST_EMPTY", "")
+
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+core:
+  token: ${GITOPS_TEST_TOKEN}
+  topic: plain
+  target_dir: ${GITOPS_TEST_UNSET:-/srv/stacks}
+  users: ["${env:GITOPS_TEST_TOKEN}", me]
+pushover:
+  token: ${file:`+tokenFile+`}
+  user: ${GITOPS_TEST_EMPTY:-fallback}
+webhook:
+  url: https://example.com/$${literal}
+`), 0o644))
+
+	cfg, err := LoadConfigFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, core.NewSecret("ghp_env"), cfg["core"]["token"])
+	assert.Equal(t, "plain", cfg["core"]["topic"])
+	assert.Equal(t, core.NewSecret("/srv/stacks"), cfg["core"]["target_dir"])
+	assert.Equal(t, []any{core.NewSecret("ghp_env"), "me"}, cfg["core"]["users"])
+	assert.Equal(t, core.NewSecret("from-file"), cfg["pushover"]["token"])
+	assert.Equal(t, core.NewSecret("fallback"), cfg["pushover"]["user"])
+	assert.Equal(t, "https://example.com/${literal}", cfg["webhook"]["url"], "an escaped value is not a secret")
+
+	coreCfg := LoadConfigFromMap(cfg["core"])
+	assert.Equal(t, "ghp_env", coreCfg.Token)
+	assert.Equal(t, []string{"ghp_env", "me"}, coreCfg.Users)
+	assert.Equal(t, "/srv/stacks", coreCfg.TargetDir)
+}
+
+func TestLoadConfigFile_UnresolvedReferences(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+core:
+  token: ${GITOPS_TEST_UNSET}
+pushover:
+  token: ${file:/does/not/exist}
+  user: ${vault:secret/path}
+`), 0o644))
+
+	_, err := LoadConfigFile(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "core.token: environment variable GITOPS_TEST_UNSET is not set")
+	assert.Contains(t, err.Error(), "pushover.token: read /does/not/exist")
+	assert.Contains(t, err.Error(), `pushover.user: unknown reference type "vault"`)
+}
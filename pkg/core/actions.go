@@ -0,0 +1,161 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxActionBody bounds the JSON params of an action request.
+const maxActionBody = 1 << 20
+
+// ActionDesc describes an action a plugin runs through Execute.
+type ActionDesc struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Params describes the parameters, with the types of config fields.
+	// Params sent over HTTP are validated against it, and ints and bools
+	// are converted to int and bool, before Execute is called.
+	Params []ConfigField `json:"params,omitempty"`
+	// Scope is the API token scope needed to invoke the action over HTTP;
+	// admin if empty.
+	Scope Scope `json:"scope,omitempty"`
+	// Internal actions can only be called by other plugins, e.g. because
+	// they return secrets.
+	Internal bool `json:"internal,omitempty"`
+}
+
+// ActionProvider is implemented by plugins that describe their Execute
+// actions. Only described, non-internal actions can be invoked over HTTP.
+type ActionProvider interface {
+	Actions() []ActionDesc
+}
+
+func actionsOf(plug Plugin) []ActionDesc {
+	if ap, ok := plug.(ActionProvider); ok {
+		return ap.Actions()
+	}
+	return nil
+}
+
+// handlePluginAction serves POST /api/plugins/{name}/actions/{action}: it
+// calls the plugin's Execute with the JSON object in the body as params and
+// returns {"result": ...}. The result is redacted like a config view.
+// Actions run in the manager's run context, so work they start in the
+// background outlives the request.
+func (m *ModuleManager) handlePluginAction(w http.ResponseWriter, r *http.Request) {
+	name, action := r.PathValue("name"), r.PathValue("action")
+	plug, err := m.GetPlugin(name)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var desc ActionDesc
+	found := false
+	for _, a := range actionsOf(plug) {
+		if a.Name == action {
+			desc, found = a, true
+			break
+		}
+	}
+	switch {
+	case !found:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "plugin " + name + " has no action " + action})
+		return
+	case desc.Internal:
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "action " + action + " cannot be invoked over HTTP"})
+		return
+	}
+	if desc.Scope == "" {
+		desc.Scope = ScopeAdmin
+	}
+	if !m.authorize(w, r, desc.Scope) {
+		return
+	}
+
+	params, err := decodeActionParams(http.MaxBytesReader(w, r.Body, maxActionBody))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if desc.Params != nil {
+		if problems := validateFields(params, desc.Params, "", true); len(problems) > 0 {
+			sort.Slice(problems, func(i, j int) bool { return problems[i].Key < problems[j].Key })
+			for i := range problems {
+				problems[i].Section = "params"
+			}
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid params", "problems": problems})
+			return
+		}
+		coerceParams(params, desc.Params)
+	}
+
+	token, _ := AuthenticatedToken(r.Context())
+	m.logger.Info("Invoking plugin action", "plugin", name, "action", action, "token", token.Name, "request_id", RequestID(r.Context()))
+	var result any
+	err = m.safeCall(plug, "action "+action, func() error {
+		var err error
+		result, err = plug.Execute(m.runContext(), action, params)
+		return err
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"result": m.redactConfigView(plug, RedactConfigView(result))})
+}
+
+// decodeActionParams reads a JSON object; an empty body is no params.
+func decodeActionParams(body io.Reader) (map[string]any, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return map[string]any{}, nil
+	}
+	var params map[string]any
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, errors.New("params must be a JSON object: " + err.Error())
+	}
+	if params == nil {
+		params = map[string]any{}
+	}
+	return params, nil
+}
+
+// coerceParams converts validated int and bool params to int and bool, as
+// Go callers of Execute would pass them.
+func coerceParams(params map[string]any, fields []ConfigField) {
+	for _, field := range fields {
+		value, ok := params[field.Name]
+		if !ok {
+			continue
+		}
+		switch field.Type {
+		case ConfigInt:
+			switch t := value.(type) {
+			case float64:
+				if t == math.Trunc(t) {
+					params[field.Name] = int(t)
+				}
+			case string:
+				if n, err := strconv.Atoi(strings.TrimSpace(t)); err == nil {
+					params[field.Name] = n
+				}
+			}
+		case ConfigBool:
+			if s, ok := value.(string); ok {
+				if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+					params[field.Name] = b
+				}
+			}
+		}
+	}
+}
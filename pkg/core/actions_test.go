@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// actionPlugin describes its actions and records the params it is called with.
+type actionPlugin struct {
+	testPlugin
+	params map[string]any
+}
+
+func (p *actionPlugin) Actions() []ActionDesc {
+	return []ActionDesc{
+		{Name: "echo", Scope: ScopeRead, Params: []ConfigField{
+			{Name: "name", Type: ConfigString, Required: true},
+			{Name: "count", Type: ConfigInt},
+			{Name: "wait", Type: ConfigBool},
+		}},
+		{Name: "deploy", Scope: ScopeTrigger},
+		{Name: "fail"},
+		{Name: "explode", Scope: ScopeRead},
+		{Name: "dump_secrets", Internal: true},
+	}
+}
+
+func (p *actionPlugin) Execute(ctx context.Context, action string, params map[string]interface{}) (interface{}, error) {
+	p.params = params
+	switch action {
+	case "echo":
+		return map[string]any{"name": params["name"], "api_token": "leaked"}, nil
+	case "fail":
+		return nil, errors.New("it failed")
+	case "explode":
+		panic("boom")
+	}
+	return true, nil
+}
+
+func invokeAction(h http.Handler, path, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestPluginAction_Invoke(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	plug := &actionPlugin{testPlugin: testPlugin{name: "act"}}
+	mgr.Register(plug)
+	h := mgr.httpHandler()
+
+	rr := invokeAction(h, "/api/plugins/act/actions/echo", `{"name":"web","count":3,"wait":"true"}`, "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"result":{"name":"web","api_token":"REDACTED"}}`, rr.Body.String())
+	assert.Equal(t, map[string]any{"name": "web", "count": 3, "wait": true}, plug.params, "params are converted to Go types")
+
+	rr = invokeAction(h, "/api/plugins/act/actions/echo", `{"count":1.5,"nmae":"web"}`, "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var invalid struct {
+		Problems []ConfigProblem `json:"problems"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &invalid))
+	require.Len(t, invalid.Problems, 3)
+	assert.Equal(t, "params.count: expected an integer, got 1.5", invalid.Problems[0].String())
+	assert.Equal(t, "params.name: required key is not set", invalid.Problems[1].String())
+	assert.Equal(t, `params.nmae: unknown key (did you mean "name"?)`, invalid.Problems[2].String())
+
+	for _, tc := range []struct {
+		path, body string
+		code       int
+		err        string
+	}{
+		{"/api/plugins/act/actions/deploy", "", http.StatusOK, ""},
+		{"/api/plugins/act/actions/echo", `[1]`, http.StatusBadRequest, "params must be a JSON object"},
+		{"/api/plugins/act/actions/missing", "", http.StatusNotFound, "has no action missing"},
+		{"/api/plugins/act/actions/dump_secrets", "", http.StatusForbidden, "cannot be invoked over HTTP"},
+		{"/api/plugins/nope/actions/echo", "", http.StatusNotFound, "not found"},
+		{"/api/plugins/act/actions/fail", "", http.StatusInternalServerError, "it failed"},
+		{"/api/plugins/act/actions/explode", "", http.StatusInternalServerError, "panic during action explode: boom"},
+	} {
+		rr := invokeAction(h, tc.path, tc.body, "")
+		assert.Equal(t, tc.code, rr.Code, tc.path)
+		assert.Contains(t, rr.Body.String(), tc.err, tc.path)
+	}
+}
+
+func TestPluginAction_Scopes(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mgr.SetConfig(map[string]map[string]any{"core": {"auth": map[string]any{"tokens": []any{
+		map[string]any{"name": "viewer", "token": "r-token", "scopes": "read"},
+		map[string]any{"name": "ci", "token": "t-token", "scopes": "trigger"},
+		map[string]any{"name": "ops", "token": "a-token", "scopes": "admin"},
+	}}}})
+	mgr.Register(&actionPlugin{testPlugin: testPlugin{name: "act"}})
+	mgr.Register(&testPlugin{name: "plain"})
+	h := mgr.httpHandler()
+
+	cases := []struct {
+		path  string
+		token string
+		code  int
+	}{
+		{"/api/plugins/act/actions/deploy", "", http.StatusUnauthorized},
+		{"/api/plugins/act/actions/echo", "r-token", http.StatusBadRequest}, // authorized, missing name
+		{"/api/plugins/act/actions/deploy", "r-token", http.StatusForbidden},
+		{"/api/plugins/act/actions/deploy", "t-token", http.StatusOK},
+		{"/api/plugins/act/actions/fail", "t-token", http.StatusForbidden}, // no scope: admin
+		{"/api/plugins/act/actions/dump_secrets", "a-token", http.StatusForbidden},
+		// Undescribed actions cannot be invoked, not even by admin tokens.
+		{"/api/plugins/plain/actions/anything", "a-token", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.code, invokeAction(h, tc.path, "", tc.token).Code, "%s with %q", tc.path, tc.token)
+	}
+}
+
+func TestPluginsAPI_ListsActions(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mgr.Register(&actionPlugin{testPlugin: testPlugin{name: "act"}})
+
+	rr := serve(mgr.httpHandler(), http.MethodGet, "/api/plugins/act", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var info pluginInfo
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &info))
+	require.Len(t, info.Actions, 5)
+	assert.Equal(t, "echo", info.Actions[0].Name)
+	assert.Equal(t, ScopeRead, info.Actions[0].Scope)
+	assert.Equal(t, "name", info.Actions[0].Params[0].Name)
+	assert.True(t, info.Actions[4].Internal)
+}
@@ -2,22 +2,35 @@ package core
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"strings"
 )
 
 type pluginInfo struct {
 	Name         string       `json:"name"`
+	Version      string       `json:"version,omitempty"`
+	APIVersion   string       `json:"api_version,omitempty"`
 	Description  string       `json:"description,omitempty"`
 	Capabilities []Capability `json:"capabilities,omitempty"`
 	Status       ServiceStatus `json:"status,omitempty"`
+	StatusReason string        `json:"status_reason,omitempty"`
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+	Lifecycle    *ModuleLifecycle `json:"lifecycle,omitempty"`
+	Actions      []ActionDesc `json:"actions,omitempty"`
 	Config       any          `json:"config,omitempty"`
 }
 
 func (m *ModuleManager) registerCoreRoutes() {
 	m.mux.HandleFunc("/api/plugins", m.handlePlugins)
 	m.mux.HandleFunc("/api/plugins/", m.handlePlugin)
+	m.mux.HandleFunc("POST /api/plugins/{name}/actions/{action}", m.handlePluginAction)
+	m.mux.HandleFunc("/api/config/reload", m.handleConfigReload)
+	m.mux.HandleFunc("/api/rejected_plugins", m.handleRejectedPlugins)
+	m.mux.HandleFunc("GET /healthz", m.handleHealthz)
+	m.mux.HandleFunc("GET /readyz", m.handleReadyz)
+	m.mux.HandleFunc("GET /metrics", m.handleMetrics)
+	m.mux.HandleFunc("GET /api/events/subscriptions", m.handleEventSubscriptions)
+	m.mux.HandleFunc("GET /api/events/types", m.handleEventTypes)
 }
 
 func (m *ModuleManager) handlePlugins(w http.ResponseWriter, r *http.Request) {
@@ -29,7 +42,7 @@ func (m *ModuleManager) handlePlugins(w http.ResponseWriter, r *http.Request) {
 	plugins := m.ListPlugins()
 	out := make([]pluginInfo, 0, len(plugins))
 	for _, p := range plugins {
-		out = append(out, buildPluginInfo(p, includeConfig))
+		out = append(out, m.pluginInfo(p, includeConfig))
 	}
 	writeJSON(w, http.StatusOK, out)
 }
@@ -50,7 +63,43 @@ func (m *ModuleManager) handlePlugin(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
 		return
 	}
-	writeJSON(w, http.StatusOK, buildPluginInfo(plug, true))
+	writeJSON(w, http.StatusOK, m.pluginInfo(plug, true))
+}
+
+// pluginInfo adds the manifest version and supervised lifecycle to
+// buildPluginInfo. A module the supervisor marked failed is reported
+// unhealthy regardless of its Status.
+func (m *ModuleManager) pluginInfo(plug Plugin, includeConfig bool) pluginInfo {
+	info := buildPluginInfo(plug, includeConfig)
+	if info.Config != nil {
+		info.Config = m.redactConfigView(plug, info.Config)
+	}
+	if manifest, ok := m.pluginManifest(plug); ok {
+		info.Version = manifest.Version
+		info.APIVersion = manifest.APIVersion
+	}
+	if lc, ok := m.Lifecycle(plug.Name()); ok {
+		info.Lifecycle = &lc
+	}
+	status := m.PluginStatus(plug)
+	info.Status, info.StatusReason = status.Status, status.Reason
+	return info
+}
+
+// redactConfigView redacts every string in a plugin's config view (already
+// passed through RedactConfigView) that equals a Secret value (such as a
+// resolved ${...} reference) in the sections the plugin reads, so references
+// stay redacted even when the plugin copied them into plain fields.
+func (m *ModuleManager) redactConfigView(plug Plugin, view any) any {
+	cfg := m.GetConfig()
+	secrets := map[string]bool{}
+	for _, section := range configSectionsOf(plug) {
+		collectSecretValues(cfg[section], secrets)
+	}
+	if len(secrets) == 0 {
+		return view
+	}
+	return redactStrings(view, secrets)
 }
 
 func buildPluginInfo(plug Plugin, includeConfig bool) pluginInfo {
@@ -60,9 +109,13 @@ func buildPluginInfo(plug Plugin, includeConfig bool) pluginInfo {
 		Capabilities: plug.Capabilities(),
 		Status:       plug.Status(),
 	}
+	if dp, ok := plug.(DependencyProvider); ok {
+		info.Dependencies = dp.Dependencies()
+	}
+	info.Actions = actionsOf(plug)
 	if includeConfig {
 		if cfg, ok := plug.(ConfigProvider); ok {
-			info.Config = cfg.Config()
+			info.Config = RedactConfigView(cfg.Config())
 		}
 	}
 	return info
@@ -74,13 +127,37 @@ func (m *ModuleManager) startHTTPServer() {
 		if addr == "" {
 			return
 		}
-		m.server = &http.Server{
-			Addr:    addr,
-			Handler: m.mux,
+		server := &http.Server{Handler: m.httpHandler()}
+		tlsCfg, useTLS := m.httpTLS()
+		var reloader *certReloader
+		if useTLS {
+			var err error
+			if reloader, err = newCertReloader(tlsCfg, m.logger); err != nil {
+				m.logger.Error("HTTP server not started: invalid core.http_tls", "error", err)
+				return
+			}
+			server.TLSConfig = reloader.tlsConfig()
+		}
+		ln, err := listenHTTP(addr)
+		if err != nil {
+			m.logger.Error("HTTP server failed", "addr", addr, "error", err)
+			return
+		}
+		m.server = server
+		m.logger.Info("HTTP server starting", "addr", addr, "tls", useTLS, "mtls", useTLS && tlsCfg.ClientCAFile != "")
+		if tokens, err := m.APITokens(); err != nil {
+			m.logger.Error("Invalid core.auth; the HTTP API denies every authenticated route", "addr", addr, "error", err)
+		} else if len(tokens) == 0 {
+			m.logger.Warn("No core.auth.tokens configured; the HTTP API is not authenticated", "addr", addr)
 		}
-		m.logger.Info("HTTP server starting", "addr", addr)
 		go func() {
-			if err := m.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			var err error
+			if reloader != nil {
+				err = server.ServeTLS(ln, "", "")
+			} else {
+				err = server.Serve(ln)
+			}
+			if err != nil && err != http.ErrServerClosed {
 				m.logger.Error("HTTP server failed", "error", err)
 			}
 		}()
@@ -94,7 +171,7 @@ func (m *ModuleManager) httpAddr() string {
 		return ""
 	}
 	if v, ok := coreSection["http_addr"]; ok {
-		return strings.TrimSpace(fmt.Sprint(v))
+		return configString(v)
 	}
 	return ""
 }
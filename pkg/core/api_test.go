@@ -90,3 +90,33 @@ func TestPluginsAPIDetail(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, "REDACTED", cfg["token"])
 }
+
+// plainConfigPlugin copies its config into a plain string field.
+type plainConfigPlugin struct {
+	testPlugin
+	url string
+}
+
+func (p *plainConfigPlugin) Config() any {
+	return map[string]any{"url": p.url, "mode": "fast"}
+}
+
+func TestPluginsAPI_RedactsResolvedReferences(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mgr := NewModuleManager(logger)
+	mgr.SetConfig(map[string]map[string]any{
+		"notifier": {"url": NewSecret("https://hooks.example.com/abc"), "mode": "fast"},
+	})
+	mgr.Register(&plainConfigPlugin{testPlugin: testPlugin{name: "notifier"}, url: "https://hooks.example.com/abc"})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/plugins/notifier", nil)
+	rr := httptest.NewRecorder()
+	mgr.handlePlugin(rr, req)
+
+	var out pluginInfo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&out))
+	cfg, ok := out.Config.(map[string]any)
+	assert.True(t, ok)
+	assert.Equal(t, "REDACTED", cfg["url"])
+	assert.Equal(t, "fast", cfg["mode"])
+}
@@ -0,0 +1,328 @@
+package core
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Scope is a permission level of the core HTTP API. Scopes are ordered:
+// admin includes trigger, and trigger includes read.
+type Scope string
+
+const (
+	// ScopePublic marks routes that need no token, such as health checks.
+	ScopePublic  Scope = "public"
+	ScopeRead    Scope = "read"    // view plugins, stacks and logs
+	ScopeTrigger Scope = "trigger" // request reconciliations and deployments
+	ScopeAdmin   Scope = "admin"   // reload config and everything else
+)
+
+// Scopes lists the scopes a token can be granted, lowest first.
+var Scopes = []Scope{ScopeRead, ScopeTrigger, ScopeAdmin}
+
+func (s Scope) level() int {
+	switch s {
+	case ScopePublic:
+		return 0
+	case ScopeRead:
+		return 1
+	case ScopeTrigger:
+		return 2
+	case ScopeAdmin:
+		return 3
+	default:
+		return -1
+	}
+}
+
+// Allows reports whether a token granted s may use a route that requires
+// required.
+func (s Scope) Allows(required Scope) bool {
+	return s.level() > 0 && s.level() >= required.level() && required.level() >= 0
+}
+
+// RouteScopeProvider is implemented by plugins that register HTTP routes,
+// to declare the scope each route requires. Keys are the patterns passed to
+// the plugin's Router, relative to its prefix (or to GetMuxServer, as
+// absolute paths); a pattern ending in "/" covers every path below it, and
+// an optional method prefix ("POST /reconcile") limits it to one method.
+// Undeclared routes require read for GET, HEAD and OPTIONS and admin for any
+// other method.
+type RouteScopeProvider interface {
+	RouteScopes() map[string]Scope
+}
+
+// coreRouteScopes are the scopes of the routes registered by core itself.
+var coreRouteScopes = map[string]Scope{
+	"/api/plugins":              ScopeRead,
+	"/api/plugins/":             ScopeRead,
+	"/api/rejected_plugins":     ScopeRead,
+	"/api/config/reload":        ScopeAdmin,
+	"/api/events/subscriptions": ScopeRead,
+	"/api/events/types":         ScopeRead,
+	"/healthz":                  ScopePublic,
+	"/readyz":                   ScopePublic,
+	"/metrics":                  ScopeRead,
+	// Each action declares its own scope, checked by handlePluginAction.
+	"POST /api/plugins/": ScopeRead,
+}
+
+// APIToken is a named token of the core HTTP API, configured in
+// `core.auth.tokens`.
+type APIToken struct {
+	Name   string
+	Token  Secret
+	Scopes []Scope
+}
+
+// Allows reports whether any of the token's scopes allows required.
+func (t APIToken) Allows(required Scope) bool {
+	for _, s := range t.Scopes {
+		if s.Allows(required) {
+			return true
+		}
+	}
+	return false
+}
+
+type apiTokenEntry struct {
+	Name   string `yaml:"name"`
+	Token  Secret `yaml:"token"`
+	Scopes any    `yaml:"scopes"`
+}
+
+// APITokens returns the tokens configured in `core.auth.tokens`. Entries
+// without a token and unknown scopes are skipped. If there are none, the HTTP
+// API is not authenticated. A `core.auth` that is set but cannot be read, or
+// whose entries all lack a token, is an error rather than no tokens, and the
+// HTTP API denies every request that needs one until it is fixed.
+func (m *ModuleManager) APITokens() ([]APIToken, error) {
+	tokens, err := apiTokens(m.GetConfig())
+	if err != nil {
+		return nil, fmt.Errorf("core.auth: %w", err)
+	}
+	return tokens, nil
+}
+
+// apiTokens reads the tokens of cfg. Errors are relative to `core.auth`.
+func apiTokens(cfg map[string]map[string]any) ([]APIToken, error) {
+	var auth map[string]any
+	switch v := cfg["core"]["auth"].(type) {
+	case nil:
+	case string:
+		if v != "" {
+			return nil, errors.New("must be a map")
+		}
+	case map[string]any:
+		auth = v
+	default:
+		return nil, errors.New("must be a map")
+	}
+	var section struct {
+		Tokens []apiTokenEntry `yaml:"tokens"`
+	}
+	if err := DecodeConfigSection(auth, &section); err != nil {
+		return nil, err
+	}
+	var tokens []APIToken
+	for _, entry := range section.Tokens {
+		if entry.Token.Value == "" {
+			continue
+		}
+		token := APIToken{Name: entry.Name, Token: entry.Token}
+		for _, s := range configStringList(entry.Scopes) {
+			if scope := Scope(strings.ToLower(s)); scope.level() > 0 {
+				token.Scopes = append(token.Scopes, scope)
+			}
+		}
+		tokens = append(tokens, token)
+	}
+	if len(tokens) == 0 && len(section.Tokens) > 0 {
+		return nil, errors.New("tokens: no entry has a token")
+	}
+	return tokens, nil
+}
+
+type principalKey struct{}
+
+// AuthenticatedToken returns the API token a request was authenticated
+// with. Plugins use it to skip their own checks for requests core already
+// authorized.
+func AuthenticatedToken(ctx context.Context) (APIToken, bool) {
+	token, ok := ctx.Value(principalKey{}).(APIToken)
+	return token, ok
+}
+
+// requestToken returns the token sent as "Authorization: Bearer <token>" or
+// in the X-API-Key header.
+func requestToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// requiredScope returns the scope of the most specific route pattern that
+// matches r, from core's routes and every plugin's RouteScopes.
+func (m *ModuleManager) requiredScope(r *http.Request) Scope {
+	best, bestLen := Scope(""), -1
+	match := func(pattern string, scope Scope) {
+		method, path, ok := strings.Cut(pattern, " ")
+		if !ok {
+			method, path = "", pattern
+		}
+		path = strings.TrimSpace(path)
+		if method != "" && method != r.Method {
+			return
+		}
+		if path != r.URL.Path && !(strings.HasSuffix(path, "/") && strings.HasPrefix(r.URL.Path, path)) {
+			return
+		}
+		// Exact and method-specific patterns beat prefixes of equal length.
+		length := 2 * len(path)
+		if method != "" {
+			length++
+		}
+		if length > bestLen {
+			best, bestLen = scope, length
+		}
+	}
+	for pattern, scope := range coreRouteScopes {
+		match(pattern, scope)
+	}
+	for _, plug := range m.ListPlugins() {
+		rp, ok := plug.(RouteScopeProvider)
+		if !ok {
+			continue
+		}
+		prefixes := m.routePrefixes(plug.Name())
+		if prefixes == nil {
+			prefixes = []string{""}
+		}
+		for pattern, scope := range rp.RouteScopes() {
+			method, path, err := splitRoutePattern(pattern)
+			if err != nil {
+				continue
+			}
+			for _, prefix := range prefixes {
+				match(joinRoutePattern(method, prefix+path), scope)
+			}
+		}
+	}
+	if best != "" {
+		return best
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ScopeRead
+	default:
+		return ScopeAdmin
+	}
+}
+
+// authMiddleware authenticates every request to the core HTTP server
+// against `core.auth.tokens` and checks the scope its route requires. It
+// responds 401 without a valid token and 403 if the token lacks the scope.
+// Without configured tokens every request is allowed; with an invalid
+// `core.auth` only public routes are.
+func (m *ModuleManager) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		required := m.requiredScope(r)
+		tokens, err := m.APITokens()
+		if err != nil {
+			if required == ScopePublic {
+				next.ServeHTTP(w, r)
+				return
+			}
+			m.denyInvalidAuth(w, r, err)
+			return
+		}
+		if len(tokens) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		presented := requestToken(r)
+		token, ok := matchToken(tokens, presented)
+		switch {
+		case ok:
+		case required == ScopePublic:
+			next.ServeHTTP(w, r)
+			return
+		default:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="git-ops"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid API token"})
+			return
+		}
+		if !token.Allows(required) {
+			m.logger.Warn("API request denied", "token", token.Name, "path", r.URL.Path, "method", r.Method, "required_scope", required)
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "token " + token.Name + " lacks the " + string(required) + " scope"})
+			return
+		}
+		if info := requestInfoFrom(r.Context()); info != nil {
+			info.token = token.Name
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, token)))
+	})
+}
+
+// authorize checks in a handler that the request's token allows scope, for
+// routes whose scope depends on what is requested. It responds 403 and
+// returns false if not. Without configured tokens every request is allowed;
+// with an invalid `core.auth` none is.
+func (m *ModuleManager) authorize(w http.ResponseWriter, r *http.Request, scope Scope) bool {
+	tokens, err := m.APITokens()
+	if err != nil {
+		m.denyInvalidAuth(w, r, err)
+		return false
+	}
+	if len(tokens) == 0 {
+		return true
+	}
+	token, ok := AuthenticatedToken(r.Context())
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="git-ops"`)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid API token"})
+		return false
+	}
+	if !token.Allows(scope) {
+		m.logger.Warn("API request denied", "token", token.Name, "path", r.URL.Path, "method", r.Method, "required_scope", scope)
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "token " + token.Name + " lacks the " + string(scope) + " scope"})
+		return false
+	}
+	return true
+}
+
+// denyInvalidAuth responds 503 to a request that cannot be authenticated
+// because `core.auth` is invalid.
+func (m *ModuleManager) denyInvalidAuth(w http.ResponseWriter, r *http.Request, err error) {
+	m.logger.Error("API request denied, core.auth is invalid", "path", r.URL.Path, "method", r.Method, "error", err)
+	writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "core.auth is invalid; fix the config and reload"})
+}
+
+// matchToken compares presented against every token in constant time.
+func matchToken(tokens []APIToken, presented string) (APIToken, bool) {
+	var found APIToken
+	ok := false
+	if presented == "" {
+		return found, false
+	}
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token.Value), []byte(presented)) == 1 && !ok {
+			found, ok = t, true
+		}
+	}
+	return found, ok
+}
+
+// scopeNames returns the names of Scopes, for the config schema.
+func scopeNames() []string {
+	names := make([]string, 0, len(Scopes))
+	for _, s := range Scopes {
+		names = append(names, string(s))
+	}
+	return names
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// routePlugin declares scopes for the routes it registers.
+type routePlugin struct {
+	testPlugin
+}
+
+func (p *routePlugin) RouteScopes() map[string]Scope {
+	return map[string]Scope{
+		"/hooks/":          ScopeTrigger,
+		"GET /hooks/ping":  ScopePublic,
+		"/hooks/ping/deep": ScopeAdmin,
+	}
+}
+
+func newAuthManager(t *testing.T, tokens []any) (*ModuleManager, http.Handler) {
+	t.Helper()
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if tokens != nil {
+		mgr.SetConfig(map[string]map[string]any{"core": {"auth": map[string]any{"tokens": tokens}}})
+	}
+	mgr.Register(&routePlugin{testPlugin{name: "hooks"}})
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		token, _ := AuthenticatedToken(r.Context())
+		w.Header().Set("X-Token-Name", token.Name)
+	}
+	mgr.GetMuxServer().HandleFunc("/hooks/", ok)
+	mgr.GetMuxServer().HandleFunc("/custom", ok)
+	return mgr, mgr.authMiddleware(mgr.GetMuxServer())
+}
+
+func serve(h http.Handler, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAuthMiddleware_NoTokens(t *testing.T) {
+	_, h := newAuthManager(t, nil)
+	assert.Equal(t, http.StatusOK, serve(h, http.MethodGet, "/api/plugins", "").Code)
+	assert.Equal(t, http.StatusOK, serve(h, http.MethodPost, "/custom", "").Code)
+}
+
+func TestAuthMiddleware_Scopes(t *testing.T) {
+	_, h := newAuthManager(t, []any{
+		map[string]any{"name": "viewer", "token": "r-token", "scopes": []any{"read"}},
+		map[string]any{"name": "ci", "token": NewSecret("t-token"), "scopes": "trigger"},
+		map[string]any{"name": "ops", "token": "a-token", "scopes": []any{"admin"}},
+		map[string]any{"name": "empty", "token": "", "scopes": []any{"admin"}},
+	})
+
+	cases := []struct {
+		method, path, token string
+		want                int
+	}{
+		{http.MethodGet, "/api/plugins", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/plugins", "wrong", http.StatusUnauthorized},
+		{http.MethodGet, "/api/plugins", "r-token", http.StatusOK},
+		{http.MethodPost, "/api/config/reload", "r-token", http.StatusForbidden},
+		{http.MethodPost, "/api/config/reload", "t-token", http.StatusForbidden},
+		{http.MethodPost, "/hooks/deploy", "r-token", http.StatusForbidden},
+		{http.MethodPost, "/hooks/deploy", "t-token", http.StatusOK},
+		{http.MethodPost, "/hooks/deploy", "a-token", http.StatusOK},
+		{http.MethodGet, "/hooks/ping", "", http.StatusOK},
+		{http.MethodPost, "/hooks/ping", "", http.StatusUnauthorized},
+		{http.MethodGet, "/hooks/ping/deep", "t-token", http.StatusForbidden},
+		{http.MethodGet, "/custom", "r-token", http.StatusOK},
+		{http.MethodPost, "/custom", "t-token", http.StatusForbidden},
+		{http.MethodPost, "/custom", "a-token", http.StatusOK},
+	}
+	for _, tc := range cases {
+		rr := serve(h, tc.method, tc.path, tc.token)
+		assert.Equal(t, tc.want, rr.Code, "%s %s with %q", tc.method, tc.path, tc.token)
+	}
+
+	rr := serve(h, http.MethodPost, "/hooks/deploy", "t-token")
+	assert.Equal(t, "ci", rr.Header().Get("X-Token-Name"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/plugins", nil)
+	req.Header.Set("X-API-Key", "r-token")
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = serve(h, http.MethodGet, "/api/plugins", "")
+	assert.Equal(t, `Bearer realm="git-ops"`, rr.Header().Get("WWW-Authenticate"))
+}
+
+func TestAPITokens_Validation(t *testing.T) {
+	mgr, _ := newAuthManager(t, []any{
+		map[string]any{"name": "ci", "token": "t", "scopes": []any{"trigger", "deploy"}},
+	})
+	tokens, err := mgr.APITokens()
+	require.NoError(t, err)
+	assert.Len(t, tokens, 1)
+	assert.Equal(t, []Scope{ScopeTrigger}, tokens[0].Scopes)
+
+	problems := mgr.ValidateConfig()
+	if assert.Len(t, problems, 1) {
+		assert.Equal(t, "auth.tokens[0].scopes", problems[0].Key)
+		assert.Contains(t, problems[0].Message, `got "deploy"`)
+	}
+}
+
+func TestAPITokens_InvalidAuthFailsClosed(t *testing.T) {
+	for name, auth := range map[string]any{
+		"not_a_map":     "r-token",
+		"tokens_string": map[string]any{"tokens": "r-token"},
+		"missing_token": map[string]any{"tokens": []any{map[string]any{"name": "ci", "token": "", "scopes": "read"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mgr, h := newAuthManager(t, nil)
+			mgr.SetConfig(map[string]map[string]any{"core": {"auth": auth}})
+
+			_, err := mgr.APITokens()
+			assert.ErrorContains(t, err, "core.auth")
+			assert.Equal(t, http.StatusServiceUnavailable, serve(h, http.MethodGet, "/api/plugins", "").Code)
+			assert.Equal(t, http.StatusServiceUnavailable, serve(h, http.MethodPost, "/custom", "r-token").Code)
+			assert.Equal(t, http.StatusOK, serve(h, http.MethodGet, "/healthz", "").Code)
+
+			problems := mgr.ValidateConfig()
+			if assert.NotEmpty(t, problems) {
+				assert.Equal(t, "core", problems[0].Section)
+				assert.Contains(t, problems[0].Key, "auth")
+			}
+		})
+	}
+}
@@ -3,83 +3,343 @@ package core
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/mywio/git-ops/pkg/metrics"
 )
 
-var (
-	// RegisteredEventTypes maps name -> desc (for discoverability/validation)
-	RegisteredEventTypes = make(map[EventTypeName]EventTypeDesc)
-	eventTypesMu         sync.RWMutex
+// EventBus registers event types and dispatches published events to the
+// listeners subscribed to them. Every ModuleManager owns one, so managers in
+// the same process (or parallel tests) do not see each other's events.
+//
+// Each subscription gets its own queue and workers (see DeliveryPolicy):
+// listeners see events in order, a slow listener only holds up its own
+// queue, and a panicking listener is logged instead of crashing the process.
+type EventBus struct {
+	logger  *slog.Logger
+	metrics busMetrics
+	// policy returns the DeliveryPolicy of new subscriptions.
+	policy func() DeliveryPolicy
+
+	typesMu sync.RWMutex
+	types   map[EventTypeName]registeredEventType // for discoverability/validation
+	// unregistered holds the UnregisteredEventPolicy; warned the warnings
+	// about unregistered types already logged.
+	unregistered atomic.Value
+	warned       sync.Map
 
-	// subscribers maps eventType (or pattern like "deploy_*") -> []Listener
-	subscribers   = make(map[string][]Listener)
+	// subscribers maps eventType (or pattern like "deploy_*") -> subscribers
 	subscribersMu sync.RWMutex
-)
+	subscribers   map[string][]*subscriber
+	nextID        uint64
 
-// registerEventType lets plugins/core define a new event type
-func registerEventType(desc EventTypeDesc) error {
-	eventTypesMu.Lock()
-	defer eventTypesMu.Unlock()
+	// deliveries tracks queued and running deliveries so that one-shot
+	// commands can wait for notifications before exiting.
+	deliveries pendingCounter
+}
+
+// pendingCounter counts queued and running deliveries. Unlike a WaitGroup,
+// it may go up again while someone waits for zero, and a wait can be
+// abandoned. The zero value is ready to use.
+type pendingCounter struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{} // closed when n drops to zero; nil while n is zero
+}
+
+func (c *pendingCounter) Add(delta int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.n == 0 && delta > 0 {
+		c.idle = make(chan struct{})
+	}
+	c.n += delta
+	if c.n < 0 {
+		panic("core: negative pending delivery count")
+	}
+	if c.n == 0 && c.idle != nil {
+		close(c.idle)
+		c.idle = nil
+	}
+}
 
-	//TODO: we need to add validation on the names
-	// Something like {type}_{description} to help and force standards and easily human read
+func (c *pendingCounter) Done() { c.Add(-1) }
+
+// Wait blocks until the count is zero or ctx is done.
+func (c *pendingCounter) Wait(ctx context.Context) error {
+	c.mu.Lock()
+	idle := c.idle
+	c.mu.Unlock()
+	if idle == nil {
+		return nil
+	}
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// NewEventBus returns an empty bus that records its metrics on reg; with a
+// nil logger it logs to slog.Default, with a nil registry its metrics are not
+// exposed.
+func NewEventBus(logger *slog.Logger, reg *metrics.Registry) *EventBus {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &EventBus{
+		logger:      logger,
+		metrics:     newBusMetrics(reg),
+		policy:      func() DeliveryPolicy { return DefaultDeliveryPolicy },
+		types:       map[EventTypeName]registeredEventType{},
+		subscribers: map[string][]*subscriber{},
+	}
+}
+
+// RegisterEventType lets plugins/core define a new event type. The name
+// must follow the naming rule checked by ValidateEventTypeName.
+func (b *EventBus) RegisterEventType(desc EventTypeDesc) error {
+	return b.registerEventType("", desc)
+}
 
-	if _, exists := RegisteredEventTypes[desc.Name]; exists {
+// registerEventType registers desc on behalf of a plugin. A plugin may
+// register its own types again, e.g. when it is restarted; the new
+// description replaces the old one.
+func (b *EventBus) registerEventType(owner string, desc EventTypeDesc) error {
+	if err := ValidateEventTypeName(desc.Name); err != nil {
+		return err
+	}
+
+	b.typesMu.Lock()
+	defer b.typesMu.Unlock()
+	if existing, exists := b.types[desc.Name]; exists && (owner == "" || existing.owner != owner) {
+		if existing.owner != "" {
+			return fmt.Errorf("event type %s already registered by plugin %s", desc.Name, existing.owner)
+		}
 		return fmt.Errorf("event type %s already registered", desc.Name)
 	}
-	RegisteredEventTypes[desc.Name] = desc
-	log.Printf("Registered event type: %s (%s)", desc.Name, desc.Description)
+	b.types[desc.Name] = registeredEventType{desc: desc, owner: owner}
+	b.logger.Debug("Registered event type", "event", desc.Name, "description", desc.Description, "plugin", owner)
 	return nil
 }
 
-// RegisterEventType lets plugins/core define a new event type
-func RegisterEventType(desc EventTypeDesc) error {
-	return registerEventType(desc)
+// EventType returns the registered description of an event type.
+func (b *EventBus) EventType(name EventTypeName) (EventTypeDesc, bool) {
+	b.typesMu.RLock()
+	defer b.typesMu.RUnlock()
+	t, ok := b.types[name]
+	return t.desc, ok
 }
 
-// Subscribe lets plugins register a handler for an event type or pattern
+// EventTypes returns the registered event types, sorted by name.
+func (b *EventBus) EventTypes() []EventTypeDesc {
+	b.typesMu.RLock()
+	defer b.typesMu.RUnlock()
+	out := make([]EventTypeDesc, 0, len(b.types))
+	for _, t := range b.types {
+		out = append(out, t.desc)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Subscribe registers a handler for an event type or pattern
 // Pattern support: exact "deploy_success" or wildcard "deploy_*"
-func Subscribe(pattern string, handler Listener) {
-	subscribersMu.Lock()
-	defer subscribersMu.Unlock()
+func (b *EventBus) Subscribe(pattern string, handler Listener) Subscription {
+	return b.SubscribeWith(pattern, handler, b.policy())
+}
 
-	subscribers[pattern] = append(subscribers[pattern], handler)
-	log.Printf("Subscribed to pattern: %s", pattern)
+// SubscribeWith is Subscribe with an explicit delivery policy.
+func (b *EventBus) SubscribeWith(pattern string, handler Listener, policy DeliveryPolicy) Subscription {
+	return b.subscribe("", pattern, handler, policy)
 }
 
-// Publish sends an event to all matching subscribers (async)
-func Publish(ctx context.Context, event InternalEvent) {
+// subscribe registers a subscriber owned by a plugin, so that it can be
+// dropped when the plugin stops.
+func (b *EventBus) subscribe(owner, pattern string, handler Listener, policy DeliveryPolicy) *subscriber {
+	b.subscribersMu.Lock()
+	defer b.subscribersMu.Unlock()
+
+	b.nextID++
+	sub := newSubscriber(b, b.nextID, owner, pattern, handler, policy)
+	b.subscribers[pattern] = append(b.subscribers[pattern], sub)
+	b.logger.Debug("Subscribed to pattern", "pattern", pattern, "plugin", owner, "buffer", sub.policy.Buffer,
+		"workers", sub.policy.Workers)
+	return sub
+}
+
+// remove takes sub out of the subscribers; see subscriber.Unsubscribe.
+func (b *EventBus) remove(sub *subscriber) {
+	b.subscribersMu.Lock()
+	defer b.subscribersMu.Unlock()
+
+	subs := slices.DeleteFunc(b.subscribers[sub.pattern], func(s *subscriber) bool { return s == sub })
+	if len(subs) == 0 {
+		delete(b.subscribers, sub.pattern)
+		return
+	}
+	b.subscribers[sub.pattern] = subs
+}
+
+// unsubscribeOwner removes every subscription of a plugin and returns how
+// many there were.
+func (b *EventBus) unsubscribeOwner(owner string) int {
+	var owned []*subscriber
+	b.subscribersMu.RLock()
+	for _, subs := range b.subscribers {
+		for _, sub := range subs {
+			if sub.owner == owner {
+				owned = append(owned, sub)
+			}
+		}
+	}
+	b.subscribersMu.RUnlock()
+
+	for _, sub := range owned {
+		sub.Unsubscribe()
+	}
+	return len(owned)
+}
+
+// Subscriptions describes the current subscriptions, in the order they were
+// made.
+func (b *EventBus) Subscriptions() []SubscriptionInfo {
+	b.subscribersMu.RLock()
+	defer b.subscribersMu.RUnlock()
+	out := []SubscriptionInfo{}
+	for _, subs := range b.subscribers {
+		for _, sub := range subs {
+			out = append(out, sub.info())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// handleEventSubscriptions lists the subscriptions of the manager's bus
+// and the plugins that made them.
+func (m *ModuleManager) handleEventSubscriptions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, m.events.Subscriptions())
+}
+
+// Publish sends an event to all matching subscribers (async). An event
+// whose type is not registered is handled as UnregisteredPolicy says.
+func (b *EventBus) Publish(ctx context.Context, event InternalEvent) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 	event.Timestamp = time.Now()
 
-	// Optional: Validate against registered type (if exists)
-	if desc, ok := RegisteredEventTypes[event.Type]; ok {
-		for field, spec := range desc.PayloadSpec {
-			if spec.Required {
-				if _, has := event.Details[field]; !has {
-					log.Printf("Warning: Published event %s missing required field %s", event.Type, field)
-				}
+	desc, ok := b.EventType(event.Type)
+	if !ok && !b.allowUnregistered(event) {
+		return
+	}
+	for field, spec := range desc.PayloadSpec {
+		if spec.Required {
+			if _, has := event.Details[field]; !has {
+				b.logger.Warn("Published event is missing a required field", "event", event.Type, "field", field)
 			}
 		}
 	}
 
-	subscribersMu.RLock()
-	defer subscribersMu.RUnlock()
+	eventType := string(event.Type)
+	label := typeLabel(event.Type, ok)
+	b.metrics.published.With(label).Inc()
+
+	// Queue outside the lock: with the block policy, enqueue may wait.
+	var matched []*subscriber
+	b.subscribersMu.RLock()
+	for pattern, subs := range b.subscribers {
+		if matchesPattern(eventType, pattern) {
+			matched = append(matched, subs...)
+		}
+	}
+	b.subscribersMu.RUnlock()
 
-	for pattern, listeners := range subscribers {
-		if matchesPattern(string(event.Type), pattern) {
-			for _, listener := range listeners {
-				go listener(ctx, event) // Async dispatch
+	for _, sub := range matched {
+		b.deliveries.Add(1)
+		if !sub.enqueue(ctx, event, label) {
+			b.deliveries.Done()
+			if sub.unsubscribed() {
+				continue
 			}
+			b.metrics.dropped.With(label).Inc()
+			b.logger.Warn("Subscriber queue is full, dropping event", "event", eventType, "pattern", sub.pattern,
+				"overflow", sub.policy.Overflow)
+			continue
 		}
+		b.metrics.deliveries.With(label).Inc()
+	}
+	b.metrics.dispatchDuration.With(label).Observe(time.Since(event.Timestamp).Seconds())
+}
+
+// Wait blocks until every event queued by Publish has been delivered and
+// its listener returned, including events published in the meantime, or
+// until ctx is done.
+func (b *EventBus) Wait(ctx context.Context) error {
+	return b.deliveries.Wait(ctx)
+}
+
+// defaultBus backs the package-level functions below.
+var defaultBus atomic.Pointer[EventBus]
+
+func init() {
+	defaultBus.Store(NewEventBus(nil, nil))
+}
+
+// DefaultEventBus returns the bus used by the package-level Publish,
+// Subscribe, RegisterEventType and WaitForEvents.
+func DefaultEventBus() *EventBus {
+	return defaultBus.Load()
+}
+
+// SetDefaultEventBus makes the package-level functions use bus, typically
+// the bus of the process's only ModuleManager, so plugins still calling
+// core.Publish reach its subscribers.
+func SetDefaultEventBus(bus *EventBus) {
+	if bus != nil {
+		defaultBus.Store(bus)
 	}
 }
 
+// RegisterEventType lets plugins/core define a new event type
+//
+// Deprecated: use PluginRegistry.RegisterEventType.
+func RegisterEventType(desc EventTypeDesc) error {
+	return DefaultEventBus().RegisterEventType(desc)
+}
+
+// Subscribe lets plugins register a handler for an event type or pattern
+// Pattern support: exact "deploy_success" or wildcard "deploy_*"
+//
+// Deprecated: use PluginRegistry.Subscribe.
+func Subscribe(pattern string, handler Listener) Subscription {
+	return DefaultEventBus().Subscribe(pattern, handler)
+}
+
+// Publish sends an event to all matching subscribers (async)
+//
+// Deprecated: use PluginRegistry.Publish.
+func Publish(ctx context.Context, event InternalEvent) {
+	DefaultEventBus().Publish(ctx, event)
+}
+
+// WaitForEvents waits for the listeners of the default bus; see
+// EventBus.Wait.
+//
+// Deprecated: use ModuleManager.WaitForEvents.
+func WaitForEvents(ctx context.Context) error {
+	return DefaultEventBus().Wait(ctx)
+}
+
 // matchesPattern: Simple wildcard support (e.g., "deploy_*" matches "deploy_success")
 func matchesPattern(eventType, pattern string) bool {
 	if pattern == eventType {
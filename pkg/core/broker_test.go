@@ -0,0 +1,241 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBus_Wait(t *testing.T) {
+	bus := NewEventBus(slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+	var delivered atomic.Int32
+	release := make(chan struct{})
+	bus.Subscribe("wait_test_event", func(ctx context.Context, event InternalEvent) {
+		<-release
+		delivered.Add(1)
+	})
+
+	bus.Publish(context.Background(), InternalEvent{Type: "wait_test_event"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, bus.Wait(ctx), context.DeadlineExceeded)
+
+	close(release)
+	require.NoError(t, bus.Wait(context.Background()))
+	assert.Equal(t, int32(1), delivered.Load())
+}
+
+func TestEventBus_WaitWhilePublishing(t *testing.T) {
+	bus := NewEventBus(slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+	var delivered atomic.Int32
+	bus.Subscribe("wait_test_event", func(ctx context.Context, event InternalEvent) {
+		delivered.Add(1)
+	})
+
+	// Waiting at zero while Publish adds deliveries must not race.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			bus.Publish(context.Background(), InternalEvent{Type: "wait_test_event"})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		_ = bus.Wait(ctx)
+		cancel()
+	}
+	<-done
+	require.NoError(t, bus.Wait(context.Background()))
+	assert.Equal(t, int32(100), delivered.Load())
+}
+
+func TestEventBus_ManagersAreIsolated(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	a, b := NewModuleManager(logger), NewModuleManager(logger)
+
+	var gotA, gotB atomic.Int32
+	a.Subscribe("deploy_*", func(context.Context, InternalEvent) { gotA.Add(1) })
+	b.Subscribe("deploy_*", func(context.Context, InternalEvent) { gotB.Add(1) })
+	require.NoError(t, a.RegisterEventType(EventTypeDesc{Name: "deploy_success"}))
+	require.NoError(t, b.RegisterEventType(EventTypeDesc{Name: "deploy_success"}), "event types are per bus")
+
+	a.Publish(context.Background(), InternalEvent{Type: "deploy_success"})
+	require.NoError(t, a.WaitForEvents(context.Background()))
+	require.NoError(t, b.WaitForEvents(context.Background()))
+	assert.Equal(t, int32(1), gotA.Load())
+	assert.Equal(t, int32(0), gotB.Load())
+}
+
+func TestDefaultEventBus(t *testing.T) {
+	previous := DefaultEventBus()
+	t.Cleanup(func() { SetDefaultEventBus(previous) })
+
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	SetDefaultEventBus(mgr.EventBus())
+
+	var got atomic.Int32
+	mgr.Subscribe("legacy_event", func(context.Context, InternalEvent) { got.Add(1) })
+	Publish(context.Background(), InternalEvent{Type: "legacy_event"})
+	require.NoError(t, WaitForEvents(context.Background()))
+	assert.Equal(t, int32(1), got.Load())
+}
+
+func TestEventBus_EventTypes(t *testing.T) {
+	bus := NewEventBus(slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+	require.NoError(t, bus.RegisterEventType(EventTypeDesc{Name: "deploy_start"}))
+	require.NoError(t, bus.RegisterEventType(EventTypeDesc{Name: "audit_purged"}))
+	assert.Error(t, bus.RegisterEventType(EventTypeDesc{Name: "deploy_start"}))
+
+	types := bus.EventTypes()
+	require.Len(t, types, 2)
+	assert.Equal(t, EventTypeName("audit_purged"), types[0].Name)
+	_, ok := bus.EventType("deploy_start")
+	assert.True(t, ok)
+}
+
+func TestSubscription_Unsubscribe(t *testing.T) {
+	bus, reg := newTestBus(t)
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	var delivered atomic.Int32
+	sub := bus.Subscribe("deploy_*", func(ctx context.Context, event InternalEvent) {
+		started <- struct{}{}
+		<-release
+		delivered.Add(1)
+	})
+	assert.Equal(t, "deploy_*", sub.Pattern())
+
+	bus.Publish(context.Background(), InternalEvent{Type: "deploy_start"})
+	<-started                                                                // the worker holds the first event
+	bus.Publish(context.Background(), InternalEvent{Type: "deploy_success"}) // queued
+	require.Len(t, bus.Subscriptions(), 1)
+	assert.Equal(t, 1, bus.Subscriptions()[0].Queued)
+
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	close(release)
+	bus.Publish(context.Background(), InternalEvent{Type: "deploy_failure"})
+	require.NoError(t, bus.Wait(context.Background()))
+	assert.Equal(t, int32(1), delivered.Load(), "queued and later events are not delivered")
+	assert.Empty(t, bus.Subscriptions())
+	out := metricsText(t, reg)
+	assert.NotContains(t, out, "gitops_event_queue_depth{")
+	assert.NotContains(t, out, "gitops_events_dropped_total")
+}
+
+func TestEventSubscriptionsAPI(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mgr.SetConfig(map[string]map[string]any{"core": {"auth": map[string]any{"tokens": []any{
+		map[string]any{"name": "dashboard", "token": "read-token", "scopes": "read"},
+	}}}})
+	pluginRegistry{mgr, "notifier"}.Subscribe("notify_*", func(context.Context, InternalEvent) {})
+	mgr.Subscribe("deploy_*", func(context.Context, InternalEvent) {})
+
+	assert.Equal(t, http.StatusUnauthorized, serve(mgr.httpHandler(), http.MethodGet, "/api/events/subscriptions", "").Code)
+	rr := serve(mgr.httpHandler(), http.MethodGet, "/api/events/subscriptions", "read-token")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var subs []SubscriptionInfo
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &subs))
+	assert.Equal(t, []SubscriptionInfo{
+		{ID: 1, Pattern: "notify_*", Plugin: "notifier", Buffer: 256, Workers: 1, Overflow: OverflowBlock},
+		{ID: 2, Pattern: "deploy_*", Buffer: 256, Workers: 1, Overflow: OverflowBlock},
+	}, subs)
+
+	mgr.dropSubscriptions("notifier")
+	rr = serve(mgr.httpHandler(), http.MethodGet, "/api/events/subscriptions", "read-token")
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &subs))
+	require.Len(t, subs, 1)
+	assert.Equal(t, "deploy_*", subs[0].Pattern)
+}
+
+func TestValidateEventTypeName(t *testing.T) {
+	for _, name := range []EventTypeName{"deploy_success", "notify_env_forwarder_missing", "reconcile_now", "hook2_failed"} {
+		assert.NoError(t, ValidateEventTypeName(name), name)
+	}
+	for _, name := range []EventTypeName{"", "deploy", "Deploy_success", "deploy-success", "deploy__success", "_deploy", "deploy_", "2fa_failed", "deploy_*"} {
+		assert.Error(t, ValidateEventTypeName(name), name)
+	}
+}
+
+func TestEventBus_RegisterEventTypeOwners(t *testing.T) {
+	bus := NewEventBus(slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+	assert.Error(t, bus.RegisterEventType(EventTypeDesc{Name: "DeploySuccess"}))
+
+	require.NoError(t, bus.registerEventType("reconciler", EventTypeDesc{Name: "deploy_success"}))
+	require.NoError(t, bus.registerEventType("reconciler", EventTypeDesc{Name: "deploy_success", Description: "again"}),
+		"a restarted plugin registers its types again")
+	desc, _ := bus.EventType("deploy_success")
+	assert.Equal(t, "again", desc.Description)
+
+	assert.ErrorContains(t, bus.registerEventType("other", EventTypeDesc{Name: "deploy_success"}), "by plugin reconciler")
+	assert.Error(t, bus.RegisterEventType(EventTypeDesc{Name: "deploy_success"}))
+}
+
+func TestEventBus_UnregisteredPolicy(t *testing.T) {
+	bus, reg := newTestBus(t)
+	require.NoError(t, bus.RegisterEventType(EventTypeDesc{Name: "deploy_success"}))
+	var got atomic.Int32
+	bus.Subscribe("*", func(context.Context, InternalEvent) { got.Add(1) })
+	publish := func() {
+		bus.Publish(context.Background(), InternalEvent{Type: "deploy_success"})
+		bus.Publish(context.Background(), InternalEvent{Type: "notify_unknown"})
+		require.NoError(t, bus.Wait(context.Background()))
+	}
+
+	assert.Equal(t, UnregisteredWarn, bus.UnregisteredPolicy())
+	publish()
+	assert.Equal(t, int32(2), got.Load(), "warn delivers the event")
+
+	bus.SetUnregisteredPolicy(UnregisteredReject)
+	publish()
+	assert.Equal(t, int32(3), got.Load(), "reject drops the event")
+
+	bus.SetUnregisteredPolicy(UnregisteredAllow)
+	publish()
+	assert.Equal(t, int32(5), got.Load())
+
+	out := metricsText(t, reg)
+	assert.Contains(t, out, `gitops_events_unregistered_total{policy="warn"} 1`)
+	assert.Contains(t, out, `gitops_events_unregistered_total{policy="reject"} 1`)
+	assert.Contains(t, out, `gitops_events_unregistered_total{policy="allow"} 1`)
+	assert.Contains(t, out, `gitops_events_published_total{type="unregistered"} 2`)
+	assert.NotContains(t, out, `type="notify_unknown"`, "unregistered names are not labels")
+}
+
+func TestUnregisteredPolicy_Config(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mgr.SetConfig(map[string]map[string]any{"core": {"events": map[string]any{"unregistered": "REJECT"}}})
+	assert.Equal(t, UnregisteredReject, mgr.EventBus().UnregisteredPolicy())
+
+	mgr.SetConfig(map[string]map[string]any{"core": {"events": map[string]any{"unregistered": "ignore"}}})
+	assert.Equal(t, DefaultUnregisteredEventPolicy, mgr.EventBus().UnregisteredPolicy(), "unknown values keep the default")
+}
+
+func TestEventTypesAPI(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, pluginRegistry{mgr, "reconciler"}.RegisterEventType(EventTypeDesc{
+		Name:        "deploy_failed",
+		Description: "Stack deployment failed",
+		PayloadSpec: map[string]PayloadField{"error": {Type: "string", Description: "Error message", Required: true}},
+	}))
+	require.NoError(t, mgr.RegisterEventType(EventTypeDesc{Name: "config_reloaded"}))
+
+	rr := serve(mgr.httpHandler(), http.MethodGet, "/api/events/types", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[
+		{"name": "config_reloaded"},
+		{"name": "deploy_failed", "description": "Stack deployment failed", "plugin": "reconciler",
+		 "payload_spec": {"error": {"type": "string", "description": "Error message", "required": true}}}
+	]`, rr.Body.String())
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PluginFactory constructs a fresh instance of a built-in plugin.
+type PluginFactory func() Plugin
+
+var (
+	builtinMu sync.RWMutex
+	builtins  = make(map[string]PluginFactory)
+)
+
+// RegisterBuiltin makes a plugin available for linking into the core binary.
+// Plugin packages call it from init so that importing the package is enough
+// to make the plugin available. It panics if the name is empty or already
+// registered, mirroring database/sql.Register.
+func RegisterBuiltin(name string, factory PluginFactory) {
+	builtinMu.Lock()
+	defer builtinMu.Unlock()
+
+	if name == "" || factory == nil {
+		panic("core: RegisterBuiltin requires a name and a factory")
+	}
+	if _, exists := builtins[name]; exists {
+		panic(fmt.Sprintf("core: built-in plugin %s registered twice", name))
+	}
+	builtins[name] = factory
+}
+
+// BuiltinPlugins returns the names of all plugins linked into the binary, sorted.
+func BuiltinPlugins() []string {
+	builtinMu.RLock()
+	defer builtinMu.RUnlock()
+
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewBuiltin returns a new instance of the named built-in plugin.
+func NewBuiltin(name string) (Plugin, bool) {
+	builtinMu.RLock()
+	factory, ok := builtins[name]
+	builtinMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return factory(), true
+}
+
+// LoadBuiltins registers the built-in plugins enabled by `core.builtin_plugins`.
+// The key accepts a list or comma-separated string of plugin names; "*"
+// enables every built-in plugin. Nothing is enabled when the key is unset.
+// Built-ins are registered in the configured order, before any plugin loaded
+// from plugins_dir, so a `.so` with the same name is skipped. A built-in
+// whose manifest does not match the plugin is not registered.
+func (m *ModuleManager) LoadBuiltins() error {
+	names := m.enabledBuiltins()
+	var unknown []string
+	var errs []error
+	for _, name := range names {
+		if _, err := m.GetPlugin(name); err == nil {
+			m.logger.Debug("Built-in plugin already registered, skipping", "name", name)
+			continue
+		}
+		plug, ok := NewBuiltin(name)
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		var manifest PluginManifest
+		if mp, ok := plug.(ManifestProvider); ok {
+			manifest = mp.Manifest()
+			if err := m.checkManifest(plug, manifest); err != nil {
+				errs = append(errs, fmt.Errorf("built-in plugin %s: %w", name, err))
+				continue
+			}
+		}
+		m.registerWithManifest(plug, manifest, SourceBuiltin)
+		m.logger.Info("Built-in plugin enabled", "name", name, "version", manifest.Version)
+	}
+	if len(unknown) > 0 {
+		errs = append(errs, fmt.Errorf("unknown built-in plugins %s (available: %s)",
+			strings.Join(unknown, ", "), strings.Join(BuiltinPlugins(), ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+func (m *ModuleManager) enabledBuiltins() []string {
+	cfg := m.GetConfig()
+	raw, ok := cfg["core"]["builtin_plugins"]
+	if !ok {
+		return nil
+	}
+	names := configStringList(raw)
+	for _, name := range names {
+		if name == "*" {
+			return BuiltinPlugins()
+		}
+	}
+	return names
+}
+
+// configStringList accepts a YAML list or a comma-separated string and
+// returns the trimmed, non-empty items.
+func configStringList(v any) []string {
+	var items []string
+	switch t := UnwrapSecrets(v).(type) {
+	case nil:
+		return nil
+	case []string:
+		items = t
+	case []any:
+		for _, item := range t {
+			items = append(items, fmt.Sprint(item))
+		}
+	case string:
+		items = strings.Split(t, ",")
+	default:
+		items = []string{fmt.Sprint(t)}
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	RegisterBuiltin("builtin_a", func() Plugin { return &testPlugin{name: "builtin_a"} })
+	RegisterBuiltin("builtin_b", func() Plugin { return &testPlugin{name: "builtin_b"} })
+}
+
+func TestLoadBuiltins_FromConfig(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mgr := NewModuleManager(logger)
+	mgr.SetConfig(map[string]map[string]any{
+		"core": {"builtin_plugins": "builtin_b, builtin_a"},
+	})
+
+	require.NoError(t, mgr.LoadBuiltins())
+	plugins := mgr.ListPlugins()
+	require.Len(t, plugins, 2)
+	assert.Equal(t, "builtin_b", plugins[0].Name())
+	assert.Equal(t, "builtin_a", plugins[1].Name())
+
+	source, ok := mgr.PluginSource("builtin_a")
+	assert.True(t, ok)
+	assert.Equal(t, SourceBuiltin, source)
+
+	mgr.Register(&testPlugin{name: "direct"})
+	_, ok = mgr.PluginSource("direct")
+	assert.False(t, ok, "plugins registered directly have no source")
+}
+
+func TestLoadBuiltins_DisabledByDefault(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mgr := NewModuleManager(logger)
+
+	require.NoError(t, mgr.LoadBuiltins())
+	assert.Empty(t, mgr.ListPlugins())
+}
+
+func TestLoadBuiltins_Wildcard(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mgr := NewModuleManager(logger)
+	mgr.SetConfig(map[string]map[string]any{
+		"core": {"builtin_plugins": []any{"*"}},
+	})
+
+	require.NoError(t, mgr.LoadBuiltins())
+	assert.Len(t, mgr.ListPlugins(), len(BuiltinPlugins()))
+}
+
+func TestLoadBuiltins_Unknown(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mgr := NewModuleManager(logger)
+	mgr.SetConfig(map[string]map[string]any{
+		"core": {"builtin_plugins": []any{"builtin_a", "missing"}},
+	})
+
+	err := mgr.LoadBuiltins()
+	assert.ErrorContains(t, err, "missing")
+	assert.Len(t, mgr.ListPlugins(), 1)
+}
+
+func TestRegisterBuiltin_DuplicatePanics(t *testing.T) {
+	assert.Panics(t, func() {
+		RegisterBuiltin("builtin_a", func() Plugin { return &testPlugin{name: "builtin_a"} })
+	})
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// eventTypeNamePattern is the {category}_{description} form of event type
+// names: lowercase words separated by underscores, at least two of them.
+var eventTypeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)+$`)
+
+// ValidateEventTypeName reports whether name follows the naming rule of
+// event types: a category, such as "deploy" or "notify", then a description,
+// in lowercase words separated by underscores ("deploy_success",
+// "notify_secret_conflict"). Subscribers rely on the category to match
+// "deploy_*".
+func ValidateEventTypeName(name EventTypeName) error {
+	if !eventTypeNamePattern.MatchString(string(name)) {
+		return fmt.Errorf("invalid event type name %q: want {category}_{description} in lowercase words separated by underscores, e.g. deploy_success", name)
+	}
+	return nil
+}
+
+// UnregisteredEventPolicy decides what Publish does with an event whose type
+// is not registered.
+type UnregisteredEventPolicy string
+
+const (
+	// UnregisteredAllow delivers the event.
+	UnregisteredAllow UnregisteredEventPolicy = "allow"
+	// UnregisteredWarn delivers the event and logs a warning, once per type.
+	UnregisteredWarn UnregisteredEventPolicy = "warn"
+	// UnregisteredReject drops the event and logs a warning, once per type.
+	UnregisteredReject UnregisteredEventPolicy = "reject"
+)
+
+// DefaultUnregisteredEventPolicy is used when core.events.unregistered is
+// not configured.
+const DefaultUnregisteredEventPolicy = UnregisteredWarn
+
+// unregisteredEventPolicy reads `core.events.unregistered`; unknown values
+// keep the default.
+func unregisteredEventPolicy(cfg map[string]map[string]any) UnregisteredEventPolicy {
+	values, ok := cfg["core"]["events"].(map[string]any)
+	if !ok {
+		return DefaultUnregisteredEventPolicy
+	}
+	switch policy := UnregisteredEventPolicy(strings.ToLower(configString(values["unregistered"]))); policy {
+	case UnregisteredAllow, UnregisteredWarn, UnregisteredReject:
+		return policy
+	}
+	return DefaultUnregisteredEventPolicy
+}
+
+// registeredEventType is an entry of the event type catalog.
+type registeredEventType struct {
+	desc  EventTypeDesc
+	owner string // plugin that registered it, if any
+}
+
+// unregisteredWarning is the key of a warning already logged by
+// allowUnregistered.
+type unregisteredWarning struct {
+	policy    UnregisteredEventPolicy
+	eventType EventTypeName
+}
+
+// EventTypeInfo describes a registered event type, as listed by
+// GET /api/events/types.
+type EventTypeInfo struct {
+	Name        EventTypeName               `json:"name"`
+	Description string                      `json:"description,omitempty"`
+	PayloadSpec map[string]PayloadFieldInfo `json:"payload_spec,omitempty"`
+	Plugin      string                      `json:"plugin,omitempty"` // empty if registered by core
+}
+
+// PayloadFieldInfo is the JSON form of a PayloadField.
+type PayloadFieldInfo struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+}
+
+// Catalog describes the registered event types and the plugins that
+// registered them, sorted by name.
+func (b *EventBus) Catalog() []EventTypeInfo {
+	b.typesMu.RLock()
+	defer b.typesMu.RUnlock()
+	out := make([]EventTypeInfo, 0, len(b.types))
+	for _, t := range b.types {
+		info := EventTypeInfo{Name: t.desc.Name, Description: t.desc.Description, Plugin: t.owner}
+		if len(t.desc.PayloadSpec) > 0 {
+			info.PayloadSpec = make(map[string]PayloadFieldInfo, len(t.desc.PayloadSpec))
+			for name, field := range t.desc.PayloadSpec {
+				info.PayloadSpec[name] = PayloadFieldInfo(field)
+			}
+		}
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// SetUnregisteredPolicy sets what Publish does with events of unregistered
+// types. The manager sets it from core.events.unregistered.
+func (b *EventBus) SetUnregisteredPolicy(policy UnregisteredEventPolicy) {
+	b.unregistered.Store(policy)
+}
+
+// UnregisteredPolicy returns what Publish does with events of unregistered
+// types.
+func (b *EventBus) UnregisteredPolicy() UnregisteredEventPolicy {
+	if policy, ok := b.unregistered.Load().(UnregisteredEventPolicy); ok {
+		return policy
+	}
+	return DefaultUnregisteredEventPolicy
+}
+
+// unregisteredTypeLabel is the "type" label of events whose type is not
+// registered. Their names come from callers and are unbounded, so they are
+// counted together.
+const unregisteredTypeLabel = "unregistered"
+
+// typeLabel returns the "type" metric label of an event type.
+func typeLabel(name EventTypeName, registered bool) string {
+	if !registered {
+		return unregisteredTypeLabel
+	}
+	return string(name)
+}
+
+// allowUnregistered applies the UnregisteredEventPolicy to an event whose
+// type is not registered and reports whether it may be delivered.
+func (b *EventBus) allowUnregistered(event InternalEvent) bool {
+	policy := b.UnregisteredPolicy()
+	b.metrics.unregistered.With(string(policy)).Inc()
+	if policy == UnregisteredAllow {
+		return true
+	}
+	key := unregisteredWarning{policy: policy, eventType: event.Type}
+	if _, warned := b.warned.LoadOrStore(key, true); !warned {
+		msg := "Published event type is not registered"
+		if policy == UnregisteredReject {
+			msg = "Rejected event of unregistered type"
+		}
+		b.logger.Warn(msg, "event", event.Type, "source", event.Source, "policy", policy)
+	}
+	return policy != UnregisteredReject
+}
+
+// handleEventTypes lists the registered event types with their payload and
+// the plugin that registered them.
+func (m *ModuleManager) handleEventTypes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, m.events.Catalog())
+}
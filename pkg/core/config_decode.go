@@ -2,13 +2,14 @@ package core
 
 import "gopkg.in/yaml.v3"
 
-// DecodeConfigSection decodes a config section into a struct.
+// DecodeConfigSection decodes a config section into a struct. Secret values
+// decode as plain strings, or into Secret fields.
 // It is safe to call with a nil or empty section.
 func DecodeConfigSection(section map[string]any, out any) error {
 	if len(section) == 0 {
 		return nil
 	}
-	data, err := yaml.Marshal(section)
+	data, err := yaml.Marshal(UnwrapSecrets(section))
 	if err != nil {
 		return err
 	}
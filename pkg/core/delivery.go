@@ -0,0 +1,278 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mywio/git-ops/pkg/metrics"
+)
+
+// OverflowPolicy decides what Publish does when a subscriber's queue is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock makes Publish wait for room, up to BlockTimeout, then
+	// drop the event.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDrop drops the event for that subscriber right away.
+	OverflowDrop OverflowPolicy = "drop"
+)
+
+// DeliveryPolicy configures how events are queued and delivered to one
+// subscriber. Each subscription has its own queue and workers, so a slow
+// listener only delays its own events.
+type DeliveryPolicy struct {
+	// Buffer is the number of events queued for the subscriber, split
+	// between its workers, before Overflow applies.
+	Buffer int
+	// Workers is the number of goroutines calling the listener. With one,
+	// events are delivered in the order they were published; with more,
+	// events of the same repository (InternalEvent.Repo) still are.
+	Workers      int
+	Overflow     OverflowPolicy
+	BlockTimeout time.Duration
+}
+
+// DefaultDeliveryPolicy is used when core.events is not configured.
+var DefaultDeliveryPolicy = DeliveryPolicy{
+	Buffer:       256,
+	Workers:      1,
+	Overflow:     OverflowBlock,
+	BlockTimeout: 5 * time.Second,
+}
+
+// deliveryPolicy returns the policy of new subscriptions, from
+// `core.events`:
+//
+//	core:
+//	  events:
+//	    buffer: 256
+//	    workers: 1
+//	    overflow: block
+//	    block_timeout: 5s
+func (m *ModuleManager) deliveryPolicy() DeliveryPolicy {
+	policy := DefaultDeliveryPolicy
+	applyDeliveryPolicy(&policy, m.GetConfig()["core"]["events"])
+	return policy
+}
+
+func applyDeliveryPolicy(policy *DeliveryPolicy, raw any) {
+	values, ok := raw.(map[string]any)
+	if !ok {
+		return
+	}
+	if v, ok := values["buffer"]; ok {
+		var n int
+		if _, err := fmt.Sscan(configString(v), &n); err == nil && n >= 0 {
+			policy.Buffer = n
+		}
+	}
+	if v, ok := values["workers"]; ok {
+		var n int
+		if _, err := fmt.Sscan(configString(v), &n); err == nil && n > 0 {
+			policy.Workers = n
+		}
+	}
+	if v, ok := values["overflow"]; ok {
+		policy.Overflow = OverflowPolicy(strings.ToLower(configString(v)))
+	}
+	if d, ok := configDuration(values["block_timeout"]); ok {
+		policy.BlockTimeout = d
+	}
+}
+
+// delivery is an event queued for a subscriber.
+type delivery struct {
+	ctx   context.Context
+	event InternalEvent
+	label string // metric label of the event type, see typeLabel
+}
+
+// subscriber is one subscription: a listener with its queues and workers.
+// It implements Subscription.
+type subscriber struct {
+	bus     *EventBus
+	id      uint64
+	owner   string // plugin that subscribed, if any
+	pattern string
+	handler Listener
+	policy  DeliveryPolicy
+	queues  []chan delivery // one per worker
+	depth   *metrics.Gauge
+
+	// done is closed by Unsubscribe; workers then discard what is left.
+	done chan struct{}
+	once sync.Once
+	// mu guards closed: enqueue holds it for reading, so the queues are
+	// only closed once no send is in progress.
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newSubscriber(bus *EventBus, id uint64, owner, pattern string, handler Listener, policy DeliveryPolicy) *subscriber {
+	if policy.Workers < 1 {
+		policy.Workers = 1
+	}
+	if policy.Buffer < 0 {
+		policy.Buffer = 0
+	}
+	s := &subscriber{
+		bus:     bus,
+		id:      id,
+		owner:   owner,
+		pattern: pattern,
+		handler: handler,
+		policy:  policy,
+		queues:  make([]chan delivery, policy.Workers),
+		depth:   bus.metrics.queueDepth.With(subscriberLabel(pattern, id)),
+		done:    make(chan struct{}),
+	}
+	size := (policy.Buffer + policy.Workers - 1) / policy.Workers
+	for i := range s.queues {
+		s.queues[i] = make(chan delivery, size)
+		go s.work(s.queues[i])
+	}
+	return s
+}
+
+// subscriberLabel is the subscription label of the queue depth gauge.
+func subscriberLabel(pattern string, id uint64) string {
+	return fmt.Sprintf("%s#%d", pattern, id)
+}
+
+// Pattern returns the event type or pattern subscribed to.
+func (s *subscriber) Pattern() string {
+	return s.pattern
+}
+
+// Unsubscribe removes the subscriber from its bus and stops its workers,
+// discarding the events still queued.
+func (s *subscriber) Unsubscribe() {
+	s.once.Do(func() {
+		s.bus.remove(s)
+		close(s.done)
+		s.mu.Lock()
+		s.closed = true
+		for _, queue := range s.queues {
+			close(queue)
+		}
+		s.mu.Unlock()
+		s.bus.metrics.queueDepth.Delete(subscriberLabel(s.pattern, s.id))
+		s.bus.logger.Debug("Unsubscribed from pattern", "pattern", s.pattern, "plugin", s.owner)
+	})
+}
+
+// unsubscribed reports whether Unsubscribe was called.
+func (s *subscriber) unsubscribed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// info describes the subscriber for the subscriptions API.
+func (s *subscriber) info() SubscriptionInfo {
+	queued := 0
+	for _, queue := range s.queues {
+		queued += len(queue)
+	}
+	return SubscriptionInfo{
+		ID:       s.id,
+		Pattern:  s.pattern,
+		Plugin:   s.owner,
+		Buffer:   s.policy.Buffer,
+		Workers:  s.policy.Workers,
+		Overflow: s.policy.Overflow,
+		Queued:   queued,
+	}
+}
+
+// enqueue queues the event, applying the overflow policy if the queue is
+// full. It reports whether the event was queued; it is not once the
+// subscriber is unsubscribed.
+func (s *subscriber) enqueue(ctx context.Context, event InternalEvent, label string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return false
+	}
+	queue := s.queues[s.shard(event)]
+	// Listeners run after Publish returned; they keep the context's values,
+	// such as the request ID, but not its cancellation.
+	d := delivery{ctx: context.WithoutCancel(ctx), event: event, label: label}
+	s.depth.Inc()
+	select {
+	case queue <- d:
+		return true
+	default:
+	}
+	if s.policy.Overflow == OverflowDrop {
+		s.depth.Dec()
+		return false
+	}
+
+	var timeout <-chan time.Time
+	if s.policy.BlockTimeout > 0 {
+		timer := time.NewTimer(s.policy.BlockTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case queue <- d:
+		return true
+	case <-timeout:
+	case <-ctx.Done():
+	case <-s.done:
+	}
+	s.depth.Dec()
+	return false
+}
+
+// shard picks the worker of an event; events of one repository always go to
+// the same worker.
+func (s *subscriber) shard(event InternalEvent) int {
+	if len(s.queues) == 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(event.Repo))
+	return int(h.Sum32() % uint32(len(s.queues)))
+}
+
+func (s *subscriber) work(queue chan delivery) {
+	for d := range queue {
+		s.depth.Dec()
+		if s.unsubscribed() {
+			s.bus.deliveries.Done()
+			continue
+		}
+		s.deliver(d)
+	}
+}
+
+// deliver calls the listener. A panicking listener is logged and counted;
+// it does not take down the worker or the process.
+func (s *subscriber) deliver(d delivery) {
+	b := s.bus
+	inFlight := b.metrics.inFlight.With()
+	inFlight.Inc()
+	start := time.Now()
+	defer func() {
+		if p := recover(); p != nil {
+			b.metrics.panics.With(d.label).Inc()
+			b.logger.Error("Event listener panicked", "event", d.event.Type, "pattern", s.pattern,
+				"panic", p, "stack", string(debug.Stack()))
+		}
+		b.metrics.listenerDuration.With(d.label).Observe(time.Since(start).Seconds())
+		inFlight.Dec()
+		b.deliveries.Done()
+	}()
+	s.handler(d.ctx, d.event)
+}
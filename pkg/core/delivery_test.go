@@ -0,0 +1,152 @@
+package core
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mywio/git-ops/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBus(t *testing.T) (*EventBus, *metrics.Registry) {
+	t.Helper()
+	reg := metrics.NewRegistry(nil)
+	return NewEventBus(slog.New(slog.NewTextHandler(io.Discard, nil)), reg), reg
+}
+
+func metricsText(t *testing.T, reg *metrics.Registry) string {
+	t.Helper()
+	var b strings.Builder
+	require.NoError(t, metrics.WriteText(&b, reg))
+	return b.String()
+}
+
+func TestDelivery_InOrder(t *testing.T) {
+	bus, _ := newTestBus(t)
+	var mu sync.Mutex
+	var got []string
+	bus.Subscribe("deploy_*", func(ctx context.Context, event InternalEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, event.Repo)
+	})
+
+	var want []string
+	for i := 0; i < 100; i++ {
+		repo := string(rune('a' + i%26))
+		want = append(want, repo)
+		bus.Publish(context.Background(), InternalEvent{Type: "deploy_start", Repo: repo})
+	}
+	require.NoError(t, bus.Wait(context.Background()))
+	assert.Equal(t, want, got)
+}
+
+func TestDelivery_WorkersKeepOrderPerRepo(t *testing.T) {
+	bus, _ := newTestBus(t)
+	var mu sync.Mutex
+	got := map[string][]int{}
+	bus.SubscribeWith("*", func(ctx context.Context, event InternalEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		got[event.Repo] = append(got[event.Repo], event.Details["n"].(int))
+	}, DeliveryPolicy{Buffer: 64, Workers: 4, Overflow: OverflowBlock})
+
+	for i := 0; i < 50; i++ {
+		for _, repo := range []string{"web", "api", "db"} {
+			bus.Publish(context.Background(), InternalEvent{Type: "tick", Repo: repo, Details: map[string]any{"n": i}})
+		}
+	}
+	require.NoError(t, bus.Wait(context.Background()))
+	for _, repo := range []string{"web", "api", "db"} {
+		require.Len(t, got[repo], 50)
+		for i, n := range got[repo] {
+			assert.Equal(t, i, n, repo)
+		}
+	}
+}
+
+func TestDelivery_PanicIsolation(t *testing.T) {
+	bus, reg := newTestBus(t)
+	require.NoError(t, bus.RegisterEventType(EventTypeDesc{Name: "test_boom"}))
+	var delivered atomic.Int32
+	bus.Subscribe("test_boom", func(ctx context.Context, event InternalEvent) {
+		if event.Repo == "panic" {
+			panic("listener failed")
+		}
+		delivered.Add(1)
+	})
+
+	bus.Publish(context.Background(), InternalEvent{Type: "test_boom", Repo: "panic"})
+	bus.Publish(context.Background(), InternalEvent{Type: "test_boom", Repo: "ok"})
+	require.NoError(t, bus.Wait(context.Background()))
+	assert.Equal(t, int32(1), delivered.Load(), "the worker survives the panic")
+	assert.Contains(t, metricsText(t, reg), `gitops_event_listener_panics_total{type="test_boom"} 1`)
+}
+
+func TestDelivery_DropWhenFull(t *testing.T) {
+	bus, reg := newTestBus(t)
+	require.NoError(t, bus.RegisterEventType(EventTypeDesc{Name: "test_slow"}))
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	var delivered atomic.Int32
+	bus.SubscribeWith("test_slow", func(ctx context.Context, event InternalEvent) {
+		started <- struct{}{}
+		<-release
+		delivered.Add(1)
+	}, DeliveryPolicy{Buffer: 1, Workers: 1, Overflow: OverflowDrop})
+
+	bus.Publish(context.Background(), InternalEvent{Type: "test_slow"})
+	<-started                                                           // the worker holds the first event
+	bus.Publish(context.Background(), InternalEvent{Type: "test_slow"}) // queued
+	bus.Publish(context.Background(), InternalEvent{Type: "test_slow"}) // dropped
+	assert.Contains(t, metricsText(t, reg), `gitops_event_queue_depth{subscription="test_slow#1"} 1`)
+
+	close(release)
+	require.NoError(t, bus.Wait(context.Background()))
+	assert.Equal(t, int32(2), delivered.Load())
+	out := metricsText(t, reg)
+	assert.Contains(t, out, `gitops_events_dropped_total{type="test_slow"} 1`)
+	assert.Contains(t, out, `gitops_event_queue_depth{subscription="test_slow#1"} 0`)
+}
+
+func TestDelivery_BlockTimeout(t *testing.T) {
+	bus, reg := newTestBus(t)
+	require.NoError(t, bus.RegisterEventType(EventTypeDesc{Name: "test_slow"}))
+	release := make(chan struct{})
+	bus.SubscribeWith("test_slow", func(ctx context.Context, event InternalEvent) {
+		<-release
+	}, DeliveryPolicy{Buffer: 0, Workers: 1, Overflow: OverflowBlock, BlockTimeout: 20 * time.Millisecond})
+
+	bus.Publish(context.Background(), InternalEvent{Type: "test_slow"}) // taken by the worker
+	require.Eventually(t, func() bool {
+		return strings.Contains(metricsText(t, reg), `gitops_event_listeners_in_flight 1`)
+	}, time.Second, time.Millisecond)
+
+	start := time.Now()
+	bus.Publish(context.Background(), InternalEvent{Type: "test_slow"})
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond, "Publish blocks before dropping")
+	assert.Contains(t, metricsText(t, reg), `gitops_events_dropped_total{type="test_slow"} 1`)
+
+	close(release)
+	require.NoError(t, bus.Wait(context.Background()))
+}
+
+func TestDeliveryPolicy_Config(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.Equal(t, DefaultDeliveryPolicy, mgr.deliveryPolicy())
+
+	mgr.SetConfig(map[string]map[string]any{"core": {"events": map[string]any{
+		"buffer": 10, "workers": "2", "overflow": "DROP", "block_timeout": "1s",
+	}}})
+	assert.Equal(t, DeliveryPolicy{Buffer: 10, Workers: 2, Overflow: OverflowDrop, BlockTimeout: time.Second}, mgr.deliveryPolicy())
+
+	mgr.SetConfig(map[string]map[string]any{"core": {"events": map[string]any{"workers": 0, "buffer": -1}}})
+	assert.Equal(t, DefaultDeliveryPolicy, mgr.deliveryPolicy(), "invalid sizes keep the defaults")
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Dependency declares that a plugin needs another plugin to be initialized
+// and started before it. Set either Name (a specific plugin) or Capability
+// (every plugin providing it). Optional dependencies only affect ordering;
+// a missing required dependency fails Init.
+type Dependency struct {
+	Name       string     `json:"name,omitempty"`
+	Capability Capability `json:"capability,omitempty"`
+	Optional   bool       `json:"optional,omitempty"`
+}
+
+func (d Dependency) String() string {
+	if d.Name != "" {
+		return "plugin " + d.Name
+	}
+	return "capability " + string(d.Capability)
+}
+
+// DependencyProvider is implemented by modules that depend on other plugins.
+type DependencyProvider interface {
+	Dependencies() []Dependency
+}
+
+// resolveOrder returns the modules sorted so that every module comes after
+// its dependencies. Modules without ordering constraints keep their
+// registration order.
+func resolveOrder(modules []Module) ([]Module, error) {
+	index := make(map[string]int, len(modules))
+	for i, mod := range modules {
+		index[mod.Name()] = i
+	}
+
+	// edges[i] lists the modules that must come before module i.
+	edges := make([][]int, len(modules))
+	for i, mod := range modules {
+		dp, ok := mod.(DependencyProvider)
+		if !ok {
+			continue
+		}
+		for _, dep := range dp.Dependencies() {
+			var targets []int
+			switch {
+			case dep.Name != "":
+				if j, ok := index[dep.Name]; ok && j != i {
+					targets = append(targets, j)
+				}
+			case dep.Capability != "":
+				for j, other := range modules {
+					if j != i && hasCapability(other, dep.Capability) {
+						targets = append(targets, j)
+					}
+				}
+			default:
+				return nil, fmt.Errorf("module %s declares a dependency without a name or capability", mod.Name())
+			}
+			if len(targets) == 0 && !dep.Optional {
+				return nil, fmt.Errorf("module %s requires %s, which is not loaded", mod.Name(), dep)
+			}
+			edges[i] = append(edges[i], targets...)
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(modules))
+	order := make([]Module, 0, len(modules))
+	var stack []string
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case done:
+			return nil
+		case visiting:
+			cycle := append([]string{}, stack...)
+			for k, name := range cycle {
+				if name == modules[i].Name() {
+					cycle = cycle[k:]
+					break
+				}
+			}
+			cycle = append(cycle, modules[i].Name())
+			return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+		}
+		state[i] = visiting
+		stack = append(stack, modules[i].Name())
+		for _, j := range edges[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = done
+		order = append(order, modules[i])
+		return nil
+	}
+
+	for i := range modules {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+func hasCapability(mod Module, cap Capability) bool {
+	plug, ok := mod.(Plugin)
+	if !ok {
+		return false
+	}
+	for _, c := range plug.Capabilities() {
+		if c == cap {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderByPrecedence sorts plugins so that those named in precedence come
+// first, in that order, followed by the remaining plugins in their original
+// order. It is used to make "first one wins" merges (e.g. secrets)
+// independent of load order.
+func OrderByPrecedence(plugins []Plugin, precedence []string) []Plugin {
+	rank := make(map[string]int, len(precedence))
+	for i, name := range precedence {
+		name = strings.TrimSpace(name)
+		if _, seen := rank[name]; name != "" && !seen {
+			rank[name] = i
+		}
+	}
+	out := make([]Plugin, 0, len(plugins))
+	var rest []Plugin
+	for _, p := range plugins {
+		if _, ok := rank[p.Name()]; ok {
+			out = append(out, p)
+		} else {
+			rest = append(rest, p)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return rank[out[i].Name()] < rank[out[j].Name()]
+	})
+	return append(out, rest...)
+}
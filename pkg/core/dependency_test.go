@@ -0,0 +1,93 @@
+package core
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type depPlugin struct {
+	testPlugin
+	caps []Capability
+	deps []Dependency
+	log  *[]string
+}
+
+func (p *depPlugin) Capabilities() []Capability { return p.caps }
+func (p *depPlugin) Dependencies() []Dependency { return p.deps }
+func (p *depPlugin) Init(ctx context.Context, logger *slog.Logger, registry PluginRegistry) error {
+	*p.log = append(*p.log, "init:"+p.name)
+	return nil
+}
+func (p *depPlugin) Stop(ctx context.Context) error {
+	*p.log = append(*p.log, "stop:"+p.name)
+	return nil
+}
+
+func newDepPlugin(log *[]string, name string, caps []Capability, deps ...Dependency) *depPlugin {
+	return &depPlugin{testPlugin: testPlugin{name: name}, caps: caps, deps: deps, log: log}
+}
+
+func TestModuleManager_DependencyOrder(t *testing.T) {
+	var calls []string
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mgr := NewModuleManager(logger)
+	mgr.Register(newDepPlugin(&calls, "reconciler", nil, Dependency{Capability: CapabilitySecrets}))
+	mgr.Register(newDepPlugin(&calls, "ui", nil, Dependency{Name: "reconciler"}))
+	mgr.Register(newDepPlugin(&calls, "secrets_a", []Capability{CapabilitySecrets}))
+	mgr.Register(newDepPlugin(&calls, "secrets_b", []Capability{CapabilitySecrets}))
+
+	require.NoError(t, mgr.Init(context.Background()))
+	assert.Equal(t, []string{"init:secrets_a", "init:secrets_b", "init:reconciler", "init:ui"}, calls)
+
+	calls = nil
+	mgr.Stop(context.Background())
+	assert.Equal(t, []string{"stop:ui", "stop:reconciler", "stop:secrets_b", "stop:secrets_a"}, calls)
+}
+
+func TestModuleManager_MissingDependency(t *testing.T) {
+	var calls []string
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mgr := NewModuleManager(logger)
+	mgr.Register(newDepPlugin(&calls, "reconciler", nil,
+		Dependency{Capability: CapabilitySecrets, Optional: true},
+		Dependency{Name: "audit"},
+	))
+
+	err := mgr.Init(context.Background())
+	assert.ErrorContains(t, err, "module reconciler requires plugin audit")
+	assert.Empty(t, calls)
+}
+
+func TestModuleManager_DependencyCycle(t *testing.T) {
+	var calls []string
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mgr := NewModuleManager(logger)
+	mgr.Register(newDepPlugin(&calls, "standalone", nil))
+	mgr.Register(newDepPlugin(&calls, "a", nil, Dependency{Name: "b"}))
+	mgr.Register(newDepPlugin(&calls, "b", nil, Dependency{Name: "c"}))
+	mgr.Register(newDepPlugin(&calls, "c", nil, Dependency{Name: "a"}))
+
+	err := mgr.Init(context.Background())
+	assert.ErrorContains(t, err, "dependency cycle: a -> b -> c -> a")
+	assert.Empty(t, calls)
+}
+
+func TestOrderByPrecedence(t *testing.T) {
+	plugins := []Plugin{
+		&testPlugin{name: "google_secret_manager"},
+		&testPlugin{name: "env_forwarder"},
+		&testPlugin{name: "vault"},
+	}
+
+	ordered := OrderByPrecedence(plugins, []string{"vault", "missing", "env_forwarder"})
+	names := make([]string, 0, len(ordered))
+	for _, p := range ordered {
+		names = append(names, p.Name())
+	}
+	assert.Equal(t, []string{"vault", "env_forwarder", "google_secret_manager"}, names)
+}
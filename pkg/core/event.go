@@ -34,3 +34,24 @@ type InternalEvent struct {
 
 // Listener is a handler func for subscribers
 type Listener func(ctx context.Context, event InternalEvent)
+
+// Subscription is a listener registered with Subscribe.
+type Subscription interface {
+	// Pattern returns the event type or pattern subscribed to.
+	Pattern() string
+	// Unsubscribe removes the listener. Events queued for it but not yet
+	// delivered are discarded. Calling it again has no effect.
+	Unsubscribe()
+}
+
+// SubscriptionInfo describes a subscription, as listed by
+// GET /api/events/subscriptions.
+type SubscriptionInfo struct {
+	ID       uint64         `json:"id"`
+	Pattern  string         `json:"pattern"`
+	Plugin   string         `json:"plugin,omitempty"` // empty if not subscribed by a plugin
+	Buffer   int            `json:"buffer"`
+	Workers  int            `json:"workers"`
+	Overflow OverflowPolicy `json:"overflow"`
+	Queued   int            `json:"queued"` // events waiting for the listener
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// readinessTimeout bounds the ReadinessChecks of one /readyz request.
+const readinessTimeout = 5 * time.Second
+
+// StatusReport is a plugin status with the reason for it.
+type StatusReport struct {
+	Status ServiceStatus `json:"status"`
+	Reason string        `json:"reason,omitempty"`
+}
+
+// StatusReporter is implemented by plugins that explain their Status, e.g.
+// why they are degraded. It should agree with Status.
+type StatusReporter interface {
+	StatusReport() StatusReport
+}
+
+// ReadinessCheck is one condition a plugin needs to be ready.
+type ReadinessCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ReadinessChecker is implemented by plugins that need more than a HEALTHY
+// status to be ready, such as a completed first run or a reachable remote.
+// Checks are run on every /readyz request of a required plugin, so cache
+// anything expensive.
+type ReadinessChecker interface {
+	ReadinessChecks(ctx context.Context) []ReadinessCheck
+}
+
+// PluginStatus returns the status of plug and its reason. A module the
+// supervisor marked failed is unhealthy, with its last error as reason.
+func (m *ModuleManager) PluginStatus(plug Plugin) StatusReport {
+	report := StatusReport{Status: plug.Status()}
+	if sr, ok := plug.(StatusReporter); ok {
+		report = sr.StatusReport()
+	}
+	if lc, ok := m.Lifecycle(plug.Name()); ok && lc.State == StateFailed {
+		report = StatusReport{Status: StatusUnhealthy, Reason: lc.LastError}
+	}
+	return report
+}
+
+// requiredPlugins returns `core.health.required_plugins`; unset, it is the
+// reconciler if it is loaded. "*" requires every plugin.
+func (m *ModuleManager) requiredPlugins() []string {
+	health, _ := m.GetConfig()["core"]["health"].(map[string]any)
+	names, set := health["required_plugins"]
+	if !set || names == nil {
+		if _, err := m.GetPlugin("reconciler"); err == nil {
+			return []string{"reconciler"}
+		}
+		return nil
+	}
+	required := configStringList(names)
+	for _, name := range required {
+		if name == "*" {
+			required = nil
+			for _, plug := range m.ListPlugins() {
+				required = append(required, plug.Name())
+			}
+			break
+		}
+	}
+	sort.Strings(required)
+	return required
+}
+
+type pluginReadiness struct {
+	Name     string           `json:"name"`
+	Status   ServiceStatus    `json:"status"`
+	Reason   string           `json:"reason,omitempty"`
+	Required bool             `json:"required"`
+	Checks   []ReadinessCheck `json:"checks,omitempty"`
+}
+
+type readinessReport struct {
+	Status  string            `json:"status"` // "ready" or "not_ready"
+	Plugins []pluginReadiness `json:"plugins,omitempty"`
+}
+
+// readiness reports whether every required plugin is HEALTHY and passes its
+// ReadinessChecks, with the detail of every plugin.
+func (m *ModuleManager) readiness(ctx context.Context) (bool, []pluginReadiness) {
+	required := map[string]bool{}
+	for _, name := range m.requiredPlugins() {
+		required[name] = true
+	}
+	ready := true
+	var out []pluginReadiness
+	for _, plug := range m.ListPlugins() {
+		name := plug.Name()
+		report := m.PluginStatus(plug)
+		entry := pluginReadiness{Name: name, Status: report.Status, Reason: report.Reason, Required: required[name]}
+		delete(required, name)
+		if entry.Required {
+			if rc, ok := plug.(ReadinessChecker); ok {
+				entry.Checks = rc.ReadinessChecks(ctx)
+			}
+			ready = ready && entry.Status == StatusHealthy
+			for _, check := range entry.Checks {
+				ready = ready && check.OK
+			}
+		}
+		out = append(out, entry)
+	}
+	for name := range required {
+		ready = false
+		out = append(out, pluginReadiness{Name: name, Status: StatusUnknown, Reason: "plugin is not loaded", Required: true})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return ready, out
+}
+
+// handleHealthz serves /healthz: the process is up and serving.
+func (m *ModuleManager) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReadyz serves /readyz: 200 when ready, 503 otherwise. Plugin detail
+// is only included for authorized requests, since the route is public.
+func (m *ModuleManager) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+	ready, plugins := m.readiness(ctx)
+
+	report := readinessReport{Status: "ready"}
+	status := http.StatusOK
+	if !ready {
+		report.Status, status = "not_ready", http.StatusServiceUnavailable
+	}
+	tokens, err := m.APITokens()
+	if _, ok := AuthenticatedToken(r.Context()); ok || (err == nil && len(tokens) == 0) {
+		report.Plugins = plugins
+	}
+	writeJSON(w, status, report)
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readyPlugin reports a configurable status and readiness checks.
+type readyPlugin struct {
+	testPlugin
+	report StatusReport
+	checks []ReadinessCheck
+}
+
+func (p *readyPlugin) Status() ServiceStatus                            { return p.report.Status }
+func (p *readyPlugin) StatusReport() StatusReport                       { return p.report }
+func (p *readyPlugin) ReadinessChecks(context.Context) []ReadinessCheck { return p.checks }
+
+func readyz(t *testing.T, mgr *ModuleManager, token string) (int, readinessReport) {
+	t.Helper()
+	rr := serve(mgr.httpHandler(), http.MethodGet, "/readyz", token)
+	var report readinessReport
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	return rr.Code, report
+}
+
+func TestHealthz(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mgr.SetConfig(map[string]map[string]any{"core": {"auth": map[string]any{"tokens": []any{
+		map[string]any{"name": "ops", "token": "a-token", "scopes": "admin"},
+	}}}})
+	rr := serve(mgr.httpHandler(), http.MethodGet, "/healthz", "")
+	assert.Equal(t, http.StatusOK, rr.Code, "health checks need no token")
+	assert.JSONEq(t, `{"status":"ok"}`, rr.Body.String())
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(mgr.httpHandler(), http.MethodPost, "/healthz", "a-token").Code)
+}
+
+func TestReadyz_RequiredPlugins(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	code, report := readyz(t, mgr, "")
+	assert.Equal(t, http.StatusOK, code, "nothing is required without a reconciler")
+	assert.Equal(t, "ready", report.Status)
+
+	rec := &readyPlugin{testPlugin: testPlugin{name: "reconciler"}, report: StatusReport{Status: StatusHealthy}}
+	opt := &readyPlugin{testPlugin: testPlugin{name: "optional"}, report: StatusReport{Status: StatusDegraded, Reason: "no config"}}
+	mgr.Register(rec)
+	mgr.Register(opt)
+
+	rec.checks = []ReadinessCheck{{Name: "first_reconciliation", OK: false, Reason: "not yet"}}
+	code, report = readyz(t, mgr, "")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not_ready", report.Status)
+	require.Len(t, report.Plugins, 2)
+	assert.Equal(t, pluginReadiness{Name: "optional", Status: StatusDegraded, Reason: "no config"}, report.Plugins[0])
+	assert.Equal(t, "reconciler", report.Plugins[1].Name)
+	assert.True(t, report.Plugins[1].Required)
+	assert.Equal(t, rec.checks, report.Plugins[1].Checks)
+
+	rec.checks[0].OK = true
+	code, _ = readyz(t, mgr, "")
+	assert.Equal(t, http.StatusOK, code, "a degraded optional plugin does not matter")
+
+	mgr.SetConfig(map[string]map[string]any{"core": {"health": map[string]any{"required_plugins": "*"}}})
+	code, _ = readyz(t, mgr, "")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	mgr.SetConfig(map[string]map[string]any{"core": {"health": map[string]any{"required_plugins": []any{"reconciler", "audit"}}}})
+	code, report = readyz(t, mgr, "")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, pluginReadiness{Name: "audit", Status: StatusUnknown, Reason: "plugin is not loaded", Required: true}, report.Plugins[0])
+
+	mgr.SetConfig(map[string]map[string]any{"core": {"health": map[string]any{"required_plugins": []any{}}}})
+	code, _ = readyz(t, mgr, "")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestReadyz_DetailNeedsToken(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mgr.SetConfig(map[string]map[string]any{"core": {"auth": map[string]any{"tokens": []any{
+		map[string]any{"name": "viewer", "token": "r-token", "scopes": "read"},
+	}}}})
+	mgr.Register(&readyPlugin{testPlugin: testPlugin{name: "reconciler"}, report: StatusReport{Status: StatusHealthy}})
+
+	code, report := readyz(t, mgr, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, report.Plugins)
+
+	_, report = readyz(t, mgr, "r-token")
+	assert.Len(t, report.Plugins, 1)
+}
+
+func TestPluginStatus_Reason(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	plug := &readyPlugin{testPlugin: testPlugin{name: "p"}, report: StatusReport{Status: StatusDegraded, Reason: "no config"}}
+	mgr.Register(plug)
+
+	rr := serve(mgr.httpHandler(), http.MethodGet, "/api/plugins/p", "")
+	var info pluginInfo
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &info))
+	assert.Equal(t, StatusDegraded, info.Status)
+	assert.Equal(t, "no config", info.StatusReason)
+
+	mgr.setState("p", StateFailed, assert.AnError)
+	assert.Equal(t, StatusReport{Status: StatusUnhealthy, Reason: assert.AnError.Error()}, mgr.PluginStatus(plug))
+}
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unixAddrPrefix makes `core.http_addr` a Unix domain socket path, e.g.
+// "unix:/run/git-ops/api.sock".
+const unixAddrPrefix = "unix:"
+
+// unixSocketMode is the mode of the API socket: owner and group only.
+const unixSocketMode = 0o660
+
+// httpTLSConfig is `core.http_tls`.
+type httpTLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ClientCAFile enables mutual TLS: clients must present a certificate
+	// signed by one of these CAs.
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// httpTLS returns `core.http_tls` and whether TLS is enabled (a certificate
+// is configured).
+func (m *ModuleManager) httpTLS() (httpTLSConfig, bool) {
+	var cfg httpTLSConfig
+	section, _ := m.GetConfig()["core"]["http_tls"].(map[string]any)
+	if err := DecodeConfigSection(section, &cfg); err != nil {
+		m.logger.Error("Invalid core.http_tls", "error", err)
+		return cfg, false
+	}
+	return cfg, cfg.CertFile != "" || cfg.KeyFile != ""
+}
+
+// listenHTTP listens on a TCP address or, with the "unix:" prefix, on a Unix
+// domain socket. A stale socket file is replaced; the socket is removed
+// again when the listener is closed.
+func listenHTTP(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixAddrPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, unixSocketMode); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// certReloader serves the certificate and client CAs of `core.http_tls`
+// and reloads them when their files change, checked on every handshake. If
+// a reload fails, the previous files stay in use.
+type certReloader struct {
+	cfg    httpTLSConfig
+	logger *slog.Logger
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	versions  map[string]fileVersion
+}
+
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+func newCertReloader(cfg httpTLSConfig, logger *slog.Logger) (*certReloader, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("core.http_tls needs both cert_file and key_file")
+	}
+	r := &certReloader{cfg: cfg, logger: logger, versions: map[string]fileVersion{}}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// files returns the files the reloader watches.
+func (r *certReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+// stat returns the current versions of the files, and whether any of them
+// differs from the loaded one.
+func (r *certReloader) stat() (map[string]fileVersion, bool, error) {
+	versions := map[string]fileVersion{}
+	changed := false
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, false, err
+		}
+		versions[file] = fileVersion{modTime: info.ModTime(), size: info.Size()}
+		changed = changed || versions[file] != r.versions[file]
+	}
+	return versions, changed, nil
+}
+
+// load reads every file and replaces the served certificate and CAs.
+func (r *certReloader) load() error {
+	versions, _, err := r.stat()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.cfg.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%s: no PEM certificates found", r.cfg.ClientCAFile)
+		}
+	}
+	r.cert, r.clientCAs, r.versions = &cert, pool, versions
+	return nil
+}
+
+// current reloads the files if any of them changed and returns what to
+// serve.
+func (r *certReloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	versions, changed, err := r.stat()
+	if err != nil || !changed {
+		return r.cert, r.clientCAs
+	}
+	if err := r.load(); err != nil {
+		r.logger.Error("Failed to reload TLS files, keeping the previous ones", "error", err)
+		// Do not retry on every handshake until a file changes again.
+		r.versions = versions
+	} else {
+		r.logger.Info("Reloaded TLS files", "cert_file", r.cfg.CertFile, "client_ca_file", r.cfg.ClientCAFile)
+	}
+	return r.cert, r.clientCAs
+}
+
+// tlsConfig returns the server TLS config. With a client CA, clients must
+// present a certificate it signed.
+func (r *certReloader) tlsConfig() *tls.Config {
+	nextProtos := []string{"h2", "http/1.1"}
+	getCertificate := func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert, _ := r.current()
+		return cert, nil
+	}
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     nextProtos,
+		GetCertificate: getCertificate,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			_, clientCAs := r.current()
+			if clientCAs == nil {
+				return nil, nil
+			}
+			return &tls.Config{
+				MinVersion:     tls.VersionTLS12,
+				NextProtos:     nextProtos,
+				GetCertificate: getCertificate,
+				ClientAuth:     tls.RequireAndVerifyClientCert,
+				ClientCAs:      clientCAs,
+			}, nil
+		},
+	}
+}
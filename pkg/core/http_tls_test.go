@@ -0,0 +1,215 @@
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for name, signed by the CA.
+func (ca testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeTLSFile writes data and moves the modification time forward, so the
+// change is seen even on filesystems with coarse timestamps.
+func writeTLSFile(t *testing.T, path string, data []byte, age time.Duration) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	mtime := time.Now().Add(age)
+	require.NoError(t, os.Chtimes(path, mtime, mtime))
+}
+
+func serveTLS(t *testing.T, cfg httpTLSConfig) string {
+	t.Helper()
+	reloader, err := newCertReloader(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	ln, err := listenHTTP("127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{
+		Handler:   http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "ok") }),
+		TLSConfig: reloader.tlsConfig(),
+	}
+	go server.ServeTLS(ln, "", "")
+	t.Cleanup(func() { server.Close() })
+	return "https://" + ln.Addr().String()
+}
+
+func tlsClient(ca testCA, certs ...tls.Certificate) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool, Certificates: certs},
+		DisableKeepAlives: true,
+	}}
+}
+
+func servedName(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	return resp.TLS.PeerCertificates[0].Subject.CommonName
+}
+
+func TestCertReloader_ReloadsChangedFiles(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	cfg := httpTLSConfig{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}
+	certPEM, keyPEM := ca.issue(t, "first", x509.ExtKeyUsageServerAuth)
+	writeTLSFile(t, cfg.CertFile, certPEM, -time.Minute)
+	writeTLSFile(t, cfg.KeyFile, keyPEM, -time.Minute)
+
+	url := serveTLS(t, cfg)
+	client := tlsClient(ca)
+	assert.Equal(t, "first", servedName(t, client, url))
+
+	certPEM, keyPEM = ca.issue(t, "second", x509.ExtKeyUsageServerAuth)
+	writeTLSFile(t, cfg.CertFile, certPEM, 0)
+	writeTLSFile(t, cfg.KeyFile, keyPEM, 0)
+	assert.Equal(t, "second", servedName(t, client, url))
+
+	// A broken rotation keeps the last good certificate.
+	writeTLSFile(t, cfg.KeyFile, []byte("not a key"), time.Minute)
+	assert.Equal(t, "second", servedName(t, client, url))
+}
+
+func TestCertReloader_MutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	cfg := httpTLSConfig{
+		CertFile:     filepath.Join(dir, "tls.crt"),
+		KeyFile:      filepath.Join(dir, "tls.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+	certPEM, keyPEM := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	writeTLSFile(t, cfg.CertFile, certPEM, 0)
+	writeTLSFile(t, cfg.KeyFile, keyPEM, 0)
+	writeTLSFile(t, cfg.ClientCAFile, ca.pem, 0)
+	url := serveTLS(t, cfg)
+
+	_, err := tlsClient(ca).Get(url)
+	assert.Error(t, err, "a client without a certificate is rejected")
+
+	other := newTestCA(t)
+	otherPEM, otherKey := other.issue(t, "stranger", x509.ExtKeyUsageClientAuth)
+	stranger, err := tls.X509KeyPair(otherPEM, otherKey)
+	require.NoError(t, err)
+	_, err = tlsClient(ca, stranger).Get(url)
+	assert.Error(t, err, "a certificate from another CA is rejected")
+
+	clientPEM, clientKey := ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(clientPEM, clientKey)
+	require.NoError(t, err)
+	assert.Equal(t, "server", servedName(t, tlsClient(ca, clientCert), url))
+}
+
+func TestNewCertReloader_Errors(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	_, err := newCertReloader(httpTLSConfig{CertFile: "tls.crt"}, logger)
+	assert.ErrorContains(t, err, "needs both cert_file and key_file")
+
+	dir := t.TempDir()
+	_, err = newCertReloader(httpTLSConfig{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: filepath.Join(dir, "missing.key")}, logger)
+	assert.Error(t, err)
+}
+
+func TestListenHTTP_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.sock")
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := listenHTTP(unixAddrPrefix + path)
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "ok") })}
+	go server.Serve(ln)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(unixSocketMode), info.Mode().Perm())
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://git-ops/api/plugins")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "ok", string(body))
+
+	require.NoError(t, server.Close())
+	assert.NoFileExists(t, path, "the socket is removed on close")
+
+	regular := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(regular, nil, 0o600))
+	_, err = listenHTTP(unixAddrPrefix + regular)
+	assert.ErrorContains(t, err, "is not a socket")
+}
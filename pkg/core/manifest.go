@@ -0,0 +1,161 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// APIVersion is the version of the plugin API implemented by this core, as
+// MAJOR.MINOR. The minor version is bumped for backwards-compatible additions
+// and the major version for breaking changes to Plugin, PluginRegistry or the
+// process plugin protocol.
+const APIVersion = "1.0"
+
+// Version is the git-ops release. It is set at build time with
+// -ldflags "-X github.com/mywio/git-ops/pkg/core.Version=...".
+var Version = "dev"
+
+// PluginManifest describes a plugin and the core API it was built against.
+// `.so` plugins export it as the `Manifest` symbol next to `Plugin`; process
+// plugins and built-ins provide it by implementing ManifestProvider.
+//
+// Each bundled plugin package declares its manifest as a package-level
+// `Manifest` variable, which its ManifestProvider returns and its cmd package
+// re-exports as the `.so` symbol, so the built-in, `.so` and process builds
+// of a plugin report the same manifest.
+type PluginManifest struct {
+	Name         string       `json:"name"`
+	Version      string       `json:"version,omitempty"`
+	APIVersion   string       `json:"api_version"`
+	Capabilities []Capability `json:"capabilities,omitempty"`
+}
+
+// ManifestProvider is implemented by plugins that carry a manifest.
+type ManifestProvider interface {
+	Manifest() PluginManifest
+}
+
+// CheckCompatible reports whether a plugin built against this manifest can
+// be loaded by this core: the name must be set and the required API version
+// must have the same major and at most the same minor version as APIVersion.
+func (pm PluginManifest) CheckCompatible() error {
+	if pm.Name == "" {
+		return fmt.Errorf("manifest has no plugin name")
+	}
+	if pm.APIVersion == "" {
+		return fmt.Errorf("manifest of %s has no api_version", pm.Name)
+	}
+	major, minor, err := parseAPIVersion(pm.APIVersion)
+	if err != nil {
+		return fmt.Errorf("manifest of %s: %w", pm.Name, err)
+	}
+	coreMajor, coreMinor, _ := parseAPIVersion(APIVersion)
+	if major != coreMajor || minor > coreMinor {
+		return fmt.Errorf("plugin %s requires core API %s, but this core provides %s; rebuild the plugin against this release",
+			pm.Name, pm.APIVersion, APIVersion)
+	}
+	return nil
+}
+
+func parseAPIVersion(v string) (major, minor int, err error) {
+	majorStr, minorStr, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(v), "v"), ".")
+	if minorStr == "" {
+		minorStr = "0"
+	}
+	major, err1 := strconv.Atoi(majorStr)
+	minor, err2 := strconv.Atoi(minorStr)
+	if err1 != nil || err2 != nil || major < 0 || minor < 0 {
+		return 0, 0, fmt.Errorf("invalid api_version %q (want MAJOR.MINOR)", v)
+	}
+	return major, minor, nil
+}
+
+// checkManifest validates a plugin against its manifest before it is
+// registered. Capabilities that differ from the manifest are only logged.
+func (m *ModuleManager) checkManifest(plug Plugin, manifest PluginManifest) error {
+	if err := manifest.CheckCompatible(); err != nil {
+		return err
+	}
+	if plug.Name() != manifest.Name {
+		return fmt.Errorf("plugin reports name %s but its manifest declares %s", plug.Name(), manifest.Name)
+	}
+	if !sameCapabilities(plug.Capabilities(), manifest.Capabilities) {
+		m.logger.Warn("Plugin capabilities differ from its manifest", "name", manifest.Name,
+			"capabilities", plug.Capabilities(), "manifest_capabilities", manifest.Capabilities)
+	}
+	return nil
+}
+
+// pluginManifest returns the manifest recorded when the plugin was loaded,
+// falling back to the plugin's own ManifestProvider.
+func (m *ModuleManager) pluginManifest(plug Plugin) (PluginManifest, bool) {
+	m.modulesMu.RLock()
+	manifest, ok := m.manifests[plug.Name()]
+	m.modulesMu.RUnlock()
+	if ok {
+		return manifest, true
+	}
+	if mp, ok := plug.(ManifestProvider); ok {
+		if manifest := mp.Manifest(); manifest.APIVersion != "" {
+			return manifest, true
+		}
+	}
+	return PluginManifest{}, false
+}
+
+// Manifest returns the manifest of the named plugin, if it has one.
+func (m *ModuleManager) Manifest(name string) (PluginManifest, bool) {
+	plug, err := m.GetPlugin(name)
+	if err != nil {
+		return PluginManifest{}, false
+	}
+	return m.pluginManifest(plug)
+}
+
+// PluginSource is where a plugin was loaded from.
+type PluginSource string
+
+const (
+	SourceBuiltin    PluginSource = "built-in"
+	SourcePluginsDir PluginSource = "plugins_dir"
+)
+
+// PluginSource returns where the named plugin was loaded from. Plugins
+// registered directly with Register have no source.
+func (m *ModuleManager) PluginSource(name string) (PluginSource, bool) {
+	m.modulesMu.RLock()
+	defer m.modulesMu.RUnlock()
+	source, ok := m.sources[name]
+	return source, ok
+}
+
+func (m *ModuleManager) registerWithManifest(plug Plugin, manifest PluginManifest, source PluginSource) {
+	m.Register(plug)
+	m.modulesMu.Lock()
+	m.sources[plug.Name()] = source
+	m.modulesMu.Unlock()
+	if manifest.APIVersion == "" {
+		return
+	}
+	m.modulesMu.Lock()
+	m.manifests[plug.Name()] = manifest
+	m.modulesMu.Unlock()
+}
+
+func sameCapabilities(a, b []Capability) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[Capability]int, len(a))
+	for _, c := range a {
+		seen[c]++
+	}
+	for _, c := range b {
+		if seen[c] == 0 {
+			return false
+		}
+		seen[c]--
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type manifestPlugin struct {
+	testPlugin
+	manifest PluginManifest
+}
+
+func (p *manifestPlugin) Manifest() PluginManifest { return p.manifest }
+
+func init() {
+	RegisterBuiltin("builtin_versioned", func() Plugin {
+		return &manifestPlugin{
+			testPlugin: testPlugin{name: "builtin_versioned"},
+			manifest: PluginManifest{
+				Name:         "builtin_versioned",
+				Version:      "1.2.3",
+				APIVersion:   APIVersion,
+				Capabilities: []Capability{CapabilityAPI},
+			},
+		}
+	})
+}
+
+func TestPluginManifest_CheckCompatible(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest PluginManifest
+		wantErr  string
+	}{
+		{name: "current", manifest: PluginManifest{Name: "p", APIVersion: APIVersion}},
+		{name: "older minor", manifest: PluginManifest{Name: "p", APIVersion: "1"}},
+		{name: "newer minor", manifest: PluginManifest{Name: "p", APIVersion: "1.99"}, wantErr: "requires core API 1.99"},
+		{name: "other major", manifest: PluginManifest{Name: "p", APIVersion: "2.0"}, wantErr: "requires core API 2.0"},
+		{name: "invalid", manifest: PluginManifest{Name: "p", APIVersion: "one"}, wantErr: `invalid api_version "one"`},
+		{name: "missing version", manifest: PluginManifest{Name: "p"}, wantErr: "has no api_version"},
+		{name: "missing name", manifest: PluginManifest{APIVersion: APIVersion}, wantErr: "has no plugin name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.manifest.CheckCompatible()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckManifest_NameMismatch(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	err := mgr.checkManifest(&testPlugin{name: "actual"}, PluginManifest{Name: "declared", APIVersion: APIVersion})
+	assert.EqualError(t, err, "plugin reports name actual but its manifest declares declared")
+}
+
+func TestPluginsAPI_Version(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mgr.SetConfig(map[string]map[string]any{"core": {"builtin_plugins": "builtin_versioned"}})
+	require.NoError(t, mgr.LoadBuiltins())
+	mgr.Register(&testPlugin{name: "legacy"})
+
+	rr := httptest.NewRecorder()
+	mgr.handlePlugins(rr, httptest.NewRequest(http.MethodGet, "/api/plugins", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var out []pluginInfo
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&out))
+	require.Len(t, out, 2)
+	assert.Equal(t, "1.2.3", out[0].Version)
+	assert.Equal(t, APIVersion, out[0].APIVersion)
+	assert.Empty(t, out[1].Version)
+}
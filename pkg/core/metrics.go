@@ -0,0 +1,102 @@
+package core
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/mywio/git-ops/pkg/metrics"
+)
+
+// busMetrics are the metrics of an EventBus.
+type busMetrics struct {
+	published        *metrics.CounterVec
+	deliveries       *metrics.CounterVec
+	dispatchDuration *metrics.HistogramVec
+	listenerDuration *metrics.HistogramVec
+	inFlight         *metrics.GaugeVec
+	queueDepth       *metrics.GaugeVec
+	dropped          *metrics.CounterVec
+	panics           *metrics.CounterVec
+	unregistered     *metrics.CounterVec
+}
+
+func newBusMetrics(r *metrics.Registry) busMetrics {
+	return busMetrics{
+		published: r.Counter("gitops_events_published_total",
+			"Events published, by event type.", "type"),
+		deliveries: r.Counter("gitops_event_deliveries_total",
+			"Events queued for a subscriber, by event type.", "type"),
+		dispatchDuration: r.Histogram("gitops_event_dispatch_duration_seconds",
+			"Time Publish took to queue an event for its subscribers, by event type.",
+			[]float64{.00001, .0001, .001, .01, .1}, "type"),
+		listenerDuration: r.Histogram("gitops_event_listener_duration_seconds",
+			"Time listeners took to handle an event, by event type.", nil, "type"),
+		inFlight: r.Gauge("gitops_event_listeners_in_flight",
+			"Listeners currently running."),
+		queueDepth: r.Gauge("gitops_event_queue_depth",
+			"Events waiting in a subscriber's queue, by subscription (pattern#id).", "subscription"),
+		dropped: r.Counter("gitops_events_dropped_total",
+			"Events dropped because a subscriber's queue was full, by event type.", "type"),
+		panics: r.Counter("gitops_event_listener_panics_total",
+			"Listeners that panicked, by event type.", "type"),
+		unregistered: r.Counter("gitops_events_unregistered_total",
+			"Events published with a type that is not registered, by the policy applied (allow, warn or reject).", "policy"),
+	}
+}
+
+// coreMetrics are the metrics core records on the manager's registry.
+type coreMetrics struct {
+	httpRequests *metrics.CounterVec
+	httpDuration *metrics.HistogramVec
+	pluginUp     *metrics.GaugeVec
+	// processDropped counts notifications (publishes) from process plugins
+	// dropped because their queue was full.
+	processDropped *metrics.CounterVec
+}
+
+func newCoreMetrics(r *metrics.Registry) coreMetrics {
+	return coreMetrics{
+		httpRequests: r.Counter("gitops_http_requests_total",
+			"HTTP requests served, by plugin (core for core routes), method and status code.", "plugin", "method", "code"),
+		httpDuration: r.Histogram("gitops_http_request_duration_seconds",
+			"Time taken to serve HTTP requests, by plugin.", nil, "plugin"),
+		pluginUp: r.Gauge("gitops_plugin_up",
+			"1 if the plugin is healthy, 0 otherwise.", "plugin"),
+		processDropped: r.Counter("gitops_process_notifications_dropped_total",
+			"Notifications from process plugins dropped because their queue was full, by plugin.", "plugin"),
+	}
+}
+
+// Metrics returns the manager's metrics registry, served at /metrics.
+func (m *ModuleManager) Metrics() *metrics.Registry {
+	return m.metrics
+}
+
+// observeHTTPRequest records a served request.
+func (m *ModuleManager) observeHTTPRequest(plugin, method string, status int, seconds float64) {
+	if plugin == "" {
+		plugin = "core"
+	}
+	m.coreMetrics.httpRequests.With(plugin, method, strconv.Itoa(status)).Inc()
+	m.coreMetrics.httpDuration.With(plugin).Observe(seconds)
+}
+
+// handleMetrics serves /metrics in the Prometheus text format.
+func (m *ModuleManager) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	for _, plug := range m.ListPlugins() {
+		up := 0.0
+		if m.PluginStatus(plug).Status == StatusHealthy {
+			up = 1
+		}
+		m.coreMetrics.pluginUp.With(plug.Name()).Set(up)
+	}
+	metrics.Handler(m.metrics).ServeHTTP(w, r)
+}
+
+// NotificationsCounter registers the counter notifier plugins record their
+// deliveries on, by notifier and result ("sent" or "failed"), so every
+// notifier reports under the same metric.
+func NotificationsCounter(reg *metrics.Registry) *metrics.CounterVec {
+	return reg.Counter("gitops_notifications_total",
+		"Notifications delivered by notifier plugins, by notifier and result (sent or failed).", "notifier", "result")
+}
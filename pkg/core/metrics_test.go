@@ -0,0 +1,45 @@
+package core
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mgr.SetConfig(map[string]map[string]any{"core": {"auth": map[string]any{"tokens": []any{
+		map[string]any{"name": "prometheus", "token": "read-token", "scopes": "read"},
+	}}}})
+	mgr.Register(&readyPlugin{testPlugin: testPlugin{name: "good"}, report: StatusReport{Status: StatusHealthy}})
+	mgr.Register(&readyPlugin{testPlugin: testPlugin{name: "bad"}, report: StatusReport{Status: StatusDegraded}})
+	mgr.Metrics().Counter("gitops_test_plugin_total", "Recorded by a plugin.", "kind").With("x").Add(3)
+
+	require.NoError(t, mgr.RegisterEventType(EventTypeDesc{Name: "metrics_test_event"}))
+	mgr.Subscribe("metrics_test_event", func(context.Context, InternalEvent) {})
+	mgr.Publish(context.Background(), InternalEvent{Type: "metrics_test_event"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, mgr.WaitForEvents(ctx))
+
+	assert.Equal(t, http.StatusUnauthorized, serve(mgr.httpHandler(), http.MethodGet, "/metrics", "").Code)
+	rr := serve(mgr.httpHandler(), http.MethodGet, "/metrics", "read-token")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Type"), "text/plain")
+
+	body := rr.Body.String()
+	assert.Contains(t, body, `gitops_test_plugin_total{kind="x"} 3`)
+	assert.Contains(t, body, `gitops_plugin_up{plugin="bad"} 0`)
+	assert.Contains(t, body, `gitops_plugin_up{plugin="good"} 1`)
+	assert.Contains(t, body, `gitops_events_published_total{type="metrics_test_event"} 1`)
+	assert.Contains(t, body, `gitops_event_deliveries_total{type="metrics_test_event"} 1`)
+	assert.Contains(t, body, `gitops_event_listener_duration_seconds_count{type="metrics_test_event"} 1`)
+	assert.Contains(t, body, `gitops_event_listeners_in_flight 0`)
+	assert.Contains(t, body, `gitops_http_requests_total{plugin="core",method="GET",code="401"} 1`)
+}
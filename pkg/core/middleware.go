@@ -0,0 +1,213 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// requestIDHeader carries the request ID. An ID sent by the client (or a
+// proxy in front of core) is kept, so logs can be correlated across hops.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds client-supplied request IDs.
+const maxRequestIDLen = 128
+
+// probePaths are polled by health checks and metric scrapers; their
+// successful requests are logged at debug level only.
+var probePaths = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+// Middleware wraps an HTTP handler.
+type Middleware func(http.Handler) http.Handler
+
+// chainMiddleware wraps h in middlewares, the first being the outermost.
+func chainMiddleware(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// httpHandler returns the handler of the core HTTP server: the mux behind
+// core's middleware chain. Every route, core's and every plugin's, gets a
+// request ID, an access log line with its timing, panic recovery and token
+// authentication, in that order.
+func (m *ModuleManager) httpHandler() http.Handler {
+	return chainMiddleware(m.mux,
+		requestIDMiddleware,
+		m.accessLogMiddleware,
+		m.recoverMiddleware,
+		m.authMiddleware,
+	)
+}
+
+// requestInfo is filled in as a request passes the middleware chain, for
+// the access log.
+type requestInfo struct {
+	id     string
+	plugin string // set by the plugin's router
+	token  string // name of the API token, set by authMiddleware
+}
+
+type requestInfoKey struct{}
+
+func requestInfoFrom(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*requestInfo)
+	return info
+}
+
+// RequestID returns the ID of the core HTTP request ctx belongs to, or ""
+// outside of one. Plugins can log it to correlate with core's access log.
+func RequestID(ctx context.Context) string {
+	if info := requestInfoFrom(ctx); info != nil {
+		return info.id
+	}
+	return ""
+}
+
+// requestIDMiddleware assigns every request an ID, taken from the
+// X-Request-ID header if it is sane, and echoes it in the response.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		ctx := context.WithValue(r.Context(), requestInfoKey{}, &requestInfo{id: id})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// accessLogMiddleware logs every request with its status, size and
+// duration once it is served.
+func (m *ModuleManager) accessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		elapsed := time.Since(start)
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.statusCode(),
+			"bytes", rec.bytes,
+			"duration", elapsed.Round(time.Microsecond).String(),
+			"remote", r.RemoteAddr,
+		}
+		plugin := ""
+		if info := requestInfoFrom(r.Context()); info != nil {
+			plugin = info.plugin
+			attrs = append(attrs, "request_id", info.id)
+			if info.plugin != "" {
+				attrs = append(attrs, "plugin", info.plugin)
+			}
+			if info.token != "" {
+				attrs = append(attrs, "token", info.token)
+			}
+		}
+		m.observeHTTPRequest(plugin, r.Method, rec.statusCode(), elapsed.Seconds())
+		switch {
+		case rec.statusCode() >= http.StatusInternalServerError:
+			m.logger.Warn("HTTP request", attrs...)
+		case probePaths[r.URL.Path]:
+			m.logger.Debug("HTTP request", attrs...)
+		default:
+			m.logger.Info("HTTP request", attrs...)
+		}
+	})
+}
+
+// recoverMiddleware turns a panicking handler into a 500 response, if
+// nothing was written yet, instead of a dropped connection.
+// http.ErrAbortHandler is re-raised, as the server expects.
+func (m *ModuleManager) recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec, ok := w.(*statusRecorder)
+		if !ok {
+			rec = &statusRecorder{ResponseWriter: w}
+		}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			m.logger.Error("HTTP handler panicked", "path", r.URL.Path, "request_id", RequestID(r.Context()),
+				"panic", p, "stack", string(debug.Stack()))
+			if rec.status == 0 {
+				writeJSON(rec, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// statusRecorder records the status and size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush supports streaming handlers.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		if r.status == 0 {
+			r.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *statusRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
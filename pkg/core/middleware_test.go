@@ -0,0 +1,98 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	h := requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+	}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Len(t, seen, 16)
+	assert.Equal(t, seen, rr.Header().Get(requestIDHeader))
+
+	for id, kept := range map[string]bool{
+		"proxy-1234":             true,
+		"has space":              false,
+		strings.Repeat("x", 129): false,
+		"line\nbreak":            false,
+		strings.Repeat("y", 128): true,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(requestIDHeader, id)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		assert.Equal(t, kept, seen == id, "%q", id)
+		assert.Equal(t, seen, rr.Header().Get(requestIDHeader))
+	}
+	assert.Empty(t, RequestID(httptest.NewRequest(http.MethodGet, "/", nil).Context()))
+}
+
+// logLines decodes JSON log lines with the given message.
+func logLines(t *testing.T, logs *bytes.Buffer, msg string) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		if entry["msg"] == msg {
+			lines = append(lines, entry)
+		}
+	}
+	return lines
+}
+
+func TestHTTPHandler_AccessLogAndRecovery(t *testing.T) {
+	var logs bytes.Buffer
+	mgr := NewModuleManager(slog.New(slog.NewJSONHandler(&logs, nil)))
+	mgr.SetConfig(map[string]map[string]any{"core": {"auth": map[string]any{"tokens": []any{
+		map[string]any{"name": "ops", "token": "secret-token", "scopes": "admin"},
+	}}}})
+	router := mgr.Router("boom")
+	router.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) { panic("kaboom") })
+	router.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("fine")) })
+
+	rr := serve(mgr.httpHandler(), http.MethodGet, "/plugins/boom/panic", "secret-token")
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.JSONEq(t, `{"error":"internal error"}`, rr.Body.String())
+	id := rr.Header().Get(requestIDHeader)
+	require.NotEmpty(t, id)
+
+	panics := logLines(t, &logs, "HTTP handler panicked")
+	require.Len(t, panics, 1)
+	assert.Equal(t, "kaboom", panics[0]["panic"])
+	assert.Equal(t, id, panics[0]["request_id"])
+
+	serve(mgr.httpHandler(), http.MethodGet, "/plugins/boom/ok", "secret-token")
+	serve(mgr.httpHandler(), http.MethodGet, "/plugins/boom/ok", "")
+
+	access := logLines(t, &logs, "HTTP request")
+	require.Len(t, access, 3)
+	assert.Equal(t, "WARN", access[0]["level"])
+	assert.Equal(t, float64(500), access[0]["status"])
+	assert.Equal(t, id, access[0]["request_id"])
+	assert.Equal(t, "boom", access[0]["plugin"])
+	assert.Equal(t, "ops", access[0]["token"])
+
+	assert.Equal(t, "INFO", access[1]["level"])
+	assert.Equal(t, "/plugins/boom/ok", access[1]["path"])
+	assert.Equal(t, float64(200), access[1]["status"])
+	assert.Equal(t, float64(4), access[1]["bytes"])
+	assert.NotEmpty(t, access[1]["duration"])
+
+	assert.Equal(t, float64(401), access[2]["status"], "rejected requests are logged too")
+	assert.NotContains(t, access[2], "token")
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -13,6 +14,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/mywio/git-ops/pkg/metrics"
 )
 
 // PluginRegistry allows modules to query for other plugins/capabilities.
@@ -20,9 +23,22 @@ type PluginRegistry interface {
 	GetPlugin(name string) (Plugin, error)
 	GetPluginsWithCapability(cap Capability) []Plugin
 	ListPlugins() []Plugin
+	// RegisterEventType adds an event type to the catalog served at
+	// /api/events/types; see ValidateEventTypeName for the naming rule.
 	RegisterEventType(desc EventTypeDesc) error
+	// Deprecated: use Router, which namespaces routes under /plugins/<name>/
+	// and cannot collide with other plugins.
 	GetMuxServer() *http.ServeMux
-	Subscribe(pattern string, handler Listener)
+	// Router returns the HTTP router of the named plugin; pass the plugin's
+	// own name.
+	Router(name string) *PluginRouter
+	// Metrics returns the registry of the metrics served at /metrics.
+	Metrics() *metrics.Registry
+	// Subscribe registers a listener for an event type or pattern. The
+	// manager removes a plugin's subscriptions when it stops the plugin.
+	Subscribe(pattern string, handler Listener) Subscription
+	// Publish sends an event to the listeners subscribed to it.
+	Publish(ctx context.Context, event InternalEvent)
 	GetHTTPClient() *http.Client
 	GetConfig() map[string]map[string]any
 }
@@ -51,21 +67,48 @@ type ConfigProvider interface {
 }
 
 type ModuleManager struct {
-	modules []Module
-	logger  *slog.Logger
-	mux     *http.ServeMux
-	server  *http.Server
+	logger *slog.Logger
+	mux    *http.ServeMux
+	server *http.Server
+
+	modulesMu sync.RWMutex
+	modules   []Module
+	order     []Module        // dependency order, resolved by Init
+	loaded    map[string]bool // plugin files already loaded from plugins_dir
+	manifests map[string]PluginManifest
+	sources   map[string]PluginSource
+	rejected  map[string]RejectedPlugin // plugin files that failed verification
+	runCtx    context.Context
 
 	httpClient *http.Client
 	configMu   sync.RWMutex
 	config     map[string]map[string]any
 	serverOnce sync.Once
+
+	lifecycleMu sync.RWMutex
+	lifecycle   map[string]*ModuleLifecycle
+	stopCh      chan struct{}
+	stopOnce    sync.Once
+
+	reloadMu     sync.Mutex
+	configLoader ConfigLoader
+
+	routersMu sync.Mutex
+	routers   map[string]*PluginRouter
+
+	metrics     *metrics.Registry
+	coreMetrics coreMetrics
+	events      *EventBus
 }
 
 func (m *ModuleManager) RegisterEventType(desc EventTypeDesc) error {
-	return registerEventType(desc)
+	return m.events.RegisterEventType(desc)
 }
 
+// GetMuxServer returns the core HTTP mux. Routes registered on it are served
+// at their absolute path and panic on conflicts.
+//
+// Deprecated: use Router.
 func (m *ModuleManager) GetMuxServer() *http.ServeMux {
 	return m.mux
 }
@@ -79,14 +122,65 @@ func NewModuleManager(logger *slog.Logger) *ModuleManager {
 		httpClient: &http.Client{
 			Timeout: 15 * time.Second,
 		},
-		config: map[string]map[string]any{},
+		config:    map[string]map[string]any{},
+		lifecycle: map[string]*ModuleLifecycle{},
+		loaded:    map[string]bool{},
+		manifests: map[string]PluginManifest{},
+		sources:   map[string]PluginSource{},
+		rejected:  map[string]RejectedPlugin{},
+		routers:   map[string]*PluginRouter{},
+		stopCh:    make(chan struct{}),
+		metrics:   metrics.NewRegistry(logger),
 	}
+	mgr.coreMetrics = newCoreMetrics(mgr.metrics)
+	mgr.events = NewEventBus(logger, mgr.metrics)
+	mgr.events.policy = mgr.deliveryPolicy
 	mgr.registerCoreRoutes()
 	return mgr
 }
 
-func (m *ModuleManager) Subscribe(pattern string, handler Listener) {
-	Subscribe(pattern, handler)
+// EventBus returns the manager's event bus.
+func (m *ModuleManager) EventBus() *EventBus {
+	return m.events
+}
+
+func (m *ModuleManager) Subscribe(pattern string, handler Listener) Subscription {
+	return m.events.Subscribe(pattern, handler)
+}
+
+// pluginRegistry is the PluginRegistry passed to a module's Init. It records
+// the module as the owner of its event types and of its subscriptions, so
+// that they are dropped when the module is stopped.
+type pluginRegistry struct {
+	*ModuleManager
+	owner string
+}
+
+func (r pluginRegistry) RegisterEventType(desc EventTypeDesc) error {
+	return r.events.registerEventType(r.owner, desc)
+}
+
+func (r pluginRegistry) Subscribe(pattern string, handler Listener) Subscription {
+	return r.events.subscribe(r.owner, pattern, handler, r.events.policy())
+}
+
+// dropSubscriptions removes the subscriptions of a stopped module; it
+// subscribes again when it is initialized or started again.
+func (m *ModuleManager) dropSubscriptions(name string) {
+	if n := m.events.unsubscribeOwner(name); n > 0 {
+		m.logger.Debug("Removed subscriptions of stopped module", "module", name, "subscriptions", n)
+	}
+}
+
+// Publish sends an event to the listeners subscribed on the manager's bus.
+func (m *ModuleManager) Publish(ctx context.Context, event InternalEvent) {
+	m.events.Publish(ctx, event)
+}
+
+// WaitForEvents blocks until the listeners of every published event have
+// returned, or until ctx is done.
+func (m *ModuleManager) WaitForEvents(ctx context.Context) error {
+	return m.events.Wait(ctx)
 }
 
 func (m *ModuleManager) GetHTTPClient() *http.Client {
@@ -114,14 +208,19 @@ func (m *ModuleManager) SetConfig(cfg map[string]map[string]any) {
 	m.configMu.Lock()
 	defer m.configMu.Unlock()
 	m.config = cloneConfigMap(cfg)
+	m.events.SetUnregisteredPolicy(unregisteredEventPolicy(cfg))
 }
 
 func (m *ModuleManager) Register(mod Module) {
+	m.modulesMu.Lock()
+	defer m.modulesMu.Unlock()
 	m.modules = append(m.modules, mod)
 }
 
 // GetPlugin implements PluginRegistry
 func (m *ModuleManager) GetPlugin(name string) (Plugin, error) {
+	m.modulesMu.RLock()
+	defer m.modulesMu.RUnlock()
 	for _, mod := range m.modules {
 		if mod.Name() == name {
 			if plug, ok := mod.(Plugin); ok {
@@ -135,6 +234,8 @@ func (m *ModuleManager) GetPlugin(name string) (Plugin, error) {
 
 // GetPluginsWithCapability implements PluginRegistry
 func (m *ModuleManager) GetPluginsWithCapability(cap Capability) []Plugin {
+	m.modulesMu.RLock()
+	defer m.modulesMu.RUnlock()
 	var results []Plugin
 	for _, mod := range m.modules {
 		if plug, ok := mod.(Plugin); ok {
@@ -151,6 +252,8 @@ func (m *ModuleManager) GetPluginsWithCapability(cap Capability) []Plugin {
 
 // ListPlugins returns all registered plugins in registration order.
 func (m *ModuleManager) ListPlugins() []Plugin {
+	m.modulesMu.RLock()
+	defer m.modulesMu.RUnlock()
 	results := make([]Plugin, 0, len(m.modules))
 	for _, mod := range m.modules {
 		if plug, ok := mod.(Plugin); ok {
@@ -161,7 +264,17 @@ func (m *ModuleManager) ListPlugins() []Plugin {
 }
 
 // LoadPlugins loads plugins from a directory and registers them with the module manager.
+// Shared objects (`.so`) are opened in-process; other executable files are
+// launched as process plugins speaking the stdio plugin protocol. Files that
+// were already loaded are skipped, so it can be called again on reload to
+// pick up new plugins. When `core.plugin_verification` is set, every file is
+// verified before it is opened or executed and unverified files are refused.
 func (m *ModuleManager) LoadPlugins(dir string) error {
+	verifier, err := m.pluginVerifier()
+	if err != nil {
+		return err
+	}
+
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -175,37 +288,148 @@ func (m *ModuleManager) LoadPlugins(dir string) error {
 	})
 
 	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".so") {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
 			continue
 		}
 
 		path := filepath.Join(dir, entry.Name())
-		m.logger.Info("Loading plugin", "path", path)
+		m.modulesMu.RLock()
+		seen := m.loaded[path]
+		m.modulesMu.RUnlock()
+		if seen {
+			continue
+		}
 
-		p, err := plugin.Open(path)
-		if err != nil {
-			m.logger.Error("Failed to open plugin", "path", path, "error", err)
+		var load func(string) (Plugin, PluginManifest)
+		switch {
+		case strings.HasSuffix(entry.Name(), signatureSuffix):
+			continue
+		case strings.HasSuffix(entry.Name(), ".so"):
+			load = m.loadSharedPlugin
+		case isExecutableFile(entry):
+			load = m.loadProcessPlugin
+		default:
 			continue
 		}
 
-		sym, err := p.Lookup("Plugin")
-		if err != nil {
-			m.logger.Error("Plugin symbol not found", "path", path, "error", err)
+		if verifier != nil {
+			digest, err := verifier.verify(path)
+			if err != nil {
+				m.rejectPlugin(path, digest, err)
+				continue
+			}
+			m.modulesMu.Lock()
+			delete(m.rejected, path)
+			m.modulesMu.Unlock()
+			m.logger.Info("Plugin verified", "path", path, "sha256", digest)
+		}
+
+		plug, manifest := load(path)
+		if plug == nil {
 			continue
 		}
 
-		plug, ok := resolvePluginSymbol(sym)
-		if !ok || plug == nil {
-			m.logger.Error("Plugin has wrong type (must implement core.Plugin)", "path", path)
+		m.modulesMu.Lock()
+		m.loaded[path] = true
+		m.modulesMu.Unlock()
+
+		if _, err := m.GetPlugin(plug.Name()); err == nil {
+			m.logger.Error("Plugin with the same name already registered, skipping", "path", path, "name", plug.Name())
+			if pp, ok := plug.(*processPlugin); ok {
+				_ = pp.Stop(context.Background())
+			}
 			continue
 		}
 
-		m.Register(plug)
-		m.logger.Info("Plugin loaded successfully", "name", plug.Name())
+		m.registerWithManifest(plug, manifest, SourcePluginsDir)
+		m.logger.Info("Plugin loaded successfully", "name", plug.Name(), "version", manifest.Version, "api_version", manifest.APIVersion)
 	}
 	return nil
 }
 
+// loadSharedPlugin opens a `.so` and validates its `Manifest` symbol before
+// resolving the `Plugin` symbol, so plugins built for another core API are
+// rejected with a clear message instead of failing at runtime.
+func (m *ModuleManager) loadSharedPlugin(path string) (Plugin, PluginManifest) {
+	m.logger.Info("Loading plugin", "path", path)
+
+	p, err := plugin.Open(path)
+	if err != nil {
+		if strings.Contains(err.Error(), "different version of package") {
+			m.logger.Error("Plugin was built against a different version of git-ops; rebuild it with this release",
+				"path", path, "core_version", Version, "api_version", APIVersion, "error", err)
+		} else {
+			m.logger.Error("Failed to open plugin", "path", path, "error", err)
+		}
+		return nil, PluginManifest{}
+	}
+
+	manifestSym, err := p.Lookup("Manifest")
+	if err != nil {
+		m.logger.Error("Plugin has no Manifest symbol; rebuild it with this release", "path", path, "api_version", APIVersion)
+		return nil, PluginManifest{}
+	}
+	manifestPtr, ok := manifestSym.(*PluginManifest)
+	if !ok || manifestPtr == nil {
+		m.logger.Error("Plugin Manifest has wrong type (must be core.PluginManifest)", "path", path)
+		return nil, PluginManifest{}
+	}
+	manifest := *manifestPtr
+	if err := manifest.CheckCompatible(); err != nil {
+		m.logger.Error("Plugin is incompatible with this core", "path", path, "error", err)
+		return nil, PluginManifest{}
+	}
+
+	sym, err := p.Lookup("Plugin")
+	if err != nil {
+		m.logger.Error("Plugin symbol not found", "path", path, "error", err)
+		return nil, PluginManifest{}
+	}
+
+	plug, ok := resolvePluginSymbol(sym)
+	if !ok || plug == nil {
+		m.logger.Error("Plugin has wrong type (must implement core.Plugin)", "path", path)
+		return nil, PluginManifest{}
+	}
+	if err := m.checkManifest(plug, manifest); err != nil {
+		m.logger.Error("Plugin does not match its manifest", "path", path, "error", err)
+		return nil, PluginManifest{}
+	}
+	return plug, manifest
+}
+
+// loadProcessPlugin launches an executable plugin. The manifest is optional
+// for process plugins because the wire protocol is versioned separately, but
+// when one is sent it must be compatible.
+func (m *ModuleManager) loadProcessPlugin(path string) (Plugin, PluginManifest) {
+	m.logger.Info("Launching process plugin", "path", path)
+
+	plug, err := startProcessPlugin(path, nil, m.logger, m.deliveryPolicy(), m.coreMetrics.processDropped)
+	if err != nil {
+		m.logger.Error("Failed to launch process plugin", "path", path, "error", err)
+		return nil, PluginManifest{}
+	}
+	manifest := plug.Manifest()
+	if manifest.APIVersion == "" {
+		m.logger.Warn("Process plugin sent no manifest; skipping API version check", "path", path, "name", plug.Name())
+		return plug, manifest
+	}
+	if err := m.checkManifest(plug, manifest); err != nil {
+		m.logger.Error("Process plugin is incompatible with this core", "path", path, "error", err)
+		_ = plug.Stop(context.Background())
+		return nil, PluginManifest{}
+	}
+	return plug, manifest
+}
+
+func isExecutableFile(entry os.DirEntry) bool {
+	info, err := entry.Info()
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	return info.Mode().Perm()&0o111 != 0
+}
+
 func resolvePluginSymbol(sym any) (Plugin, bool) {
 	if plug, ok := sym.(Plugin); ok {
 		return plug, true
@@ -244,35 +468,84 @@ func resolvePluginSymbol(sym any) (Plugin, bool) {
 	return nil, false
 }
 
-// Init initializes all modules in the manager.
+// Init resolves the dependency order of all modules and initializes them in
+// that order. It fails without initializing anything if a required
+// dependency is missing or the dependencies form a cycle.
 func (m *ModuleManager) Init(ctx context.Context) error {
-	for _, mod := range m.modules {
-		if err := mod.Init(ctx, m.logger.With("module", mod.Name()), m); err != nil {
-			return fmt.Errorf("failed to init module %s: %w", mod.Name(), err)
+	m.modulesMu.Lock()
+	order, err := resolveOrder(m.modules)
+	if err == nil {
+		m.order = order
+	}
+	m.modulesMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to resolve module order: %w", err)
+	}
+	if len(order) > 1 {
+		names := make([]string, 0, len(order))
+		for _, mod := range order {
+			names = append(names, mod.Name())
+		}
+		m.logger.Info("Resolved module order", "order", strings.Join(names, ","))
+	}
+	m.logConfigProblems()
+
+	for _, mod := range order {
+		if err := m.initModule(ctx, mod); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-// Start starts all modules in the manager.
+// Start starts all modules in dependency order. Each module is supervised:
+// a panic or error from Start marks it failed and it is restarted according
+// to its restart policy.
 func (m *ModuleManager) Start(ctx context.Context) {
+	m.modulesMu.Lock()
+	m.runCtx = ctx
+	m.modulesMu.Unlock()
 	m.startHTTPServer()
-	for _, mod := range m.modules {
-		go func(mod Module) {
-			m.logger.Info("Starting module", "module", mod.Name())
-			if err := mod.Start(ctx); err != nil {
-				m.logger.Error("Module failed", "module", mod.Name(), "error", err)
-			}
-		}(mod)
+	for _, mod := range m.ordered() {
+		go m.supervise(ctx, mod)
 	}
 }
 
-// Stop stops all modules in the manager.
+// StartModules starts the modules once, in dependency order and without the
+// HTTP API or supervision, skipping the named modules. One-shot commands use
+// it to bring plugins up as the daemon does while driving the skipped ones
+// (typically the reconciler) themselves. Modules that fail to start are
+// marked failed and their errors are returned joined.
+func (m *ModuleManager) StartModules(ctx context.Context, skip ...string) error {
+	m.modulesMu.Lock()
+	m.runCtx = ctx
+	m.modulesMu.Unlock()
+	var errs []error
+	for _, mod := range m.ordered() {
+		if containsString(skip, mod.Name()) {
+			continue
+		}
+		m.setState(mod.Name(), StateRunning, nil)
+		if err := m.safeCall(mod, "start", func() error { return mod.Start(ctx) }); err != nil {
+			m.setState(mod.Name(), StateFailed, err)
+			errs = append(errs, fmt.Errorf("start %s: %w", mod.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stop cancels pending restarts and stops all modules in reverse
+// dependency order.
 func (m *ModuleManager) Stop(ctx context.Context) {
-	for i := len(m.modules) - 1; i >= 0; i-- {
-		mod := m.modules[i]
+	m.stopOnce.Do(func() { close(m.stopCh) })
+	modules := m.ordered()
+	for i := len(modules) - 1; i >= 0; i-- {
+		mod := modules[i]
 		m.logger.Info("Stopping module", "module", mod.Name())
-		if err := mod.Stop(ctx); err != nil {
+		err := m.safeCall(mod, "stop", func() error { return mod.Stop(ctx) })
+		m.setState(mod.Name(), StateStopped, err)
+		m.dropSubscriptions(mod.Name())
+		if err != nil {
 			m.logger.Error("Error stopping module", "module", mod.Name(), "error", err)
 		}
 	}
@@ -283,6 +556,17 @@ func (m *ModuleManager) Stop(ctx context.Context) {
 	}
 }
 
+// ordered returns the modules in the order resolved by Init, or in
+// registration order if Init has not run.
+func (m *ModuleManager) ordered() []Module {
+	m.modulesMu.RLock()
+	defer m.modulesMu.RUnlock()
+	if len(m.order) == len(m.modules) {
+		return append([]Module(nil), m.order...)
+	}
+	return append([]Module(nil), m.modules...)
+}
+
 // cloneConfigMap creates a deep copy of a configuration map.
 func cloneConfigMap(src map[string]map[string]any) map[string]map[string]any {
 	if len(src) == 0 {
@@ -0,0 +1,464 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mywio/git-ops/pkg/metrics"
+)
+
+const (
+	processHandshakeTimeout = 10 * time.Second
+	processStatusTimeout    = 2 * time.Second
+	processStopGrace        = 5 * time.Second
+)
+
+// processPlugin is the host-side proxy for a plugin running as a child
+// process. It implements Plugin by forwarding every call over stdio and
+// serves the plugin's PluginRegistry callbacks. If the process exits, Init
+// launches it again (see relaunch).
+type processPlugin struct {
+	path string
+	args []string
+	// logger is replaced by Init while the stderr and exit goroutines log.
+	logger atomic.Pointer[slog.Logger]
+	desc   processDescriptor // from the first launch
+	// overflow bounds the notifications queued from the process; dropped
+	// counts those dropped, by plugin.
+	overflow DeliveryPolicy
+	dropped  *metrics.CounterVec
+
+	mu       sync.RWMutex
+	proc     *processInstance
+	registry PluginRegistry
+
+	subsMu sync.Mutex
+	subs   map[uint64]Subscription // by the plugin's subscription ID
+}
+
+// processInstance is one run of a process plugin's executable.
+type processInstance struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	conn    *rpcConn
+	exited  chan struct{}
+	exitErr error // set before exited is closed
+	stopped atomic.Bool
+}
+
+// startProcessPlugin launches an executable plugin and performs the describe
+// handshake. The process keeps running until Stop is called.
+func startProcessPlugin(path string, args []string, logger *slog.Logger, overflow DeliveryPolicy, dropped *metrics.CounterVec) (*processPlugin, error) {
+	p := &processPlugin{
+		path:     path,
+		args:     args,
+		overflow: overflow,
+		dropped:  dropped,
+		subs:     make(map[uint64]Subscription),
+	}
+	p.logger.Store(logger)
+	proc, desc, err := p.launch()
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.proc, p.desc = proc, desc
+	p.mu.Unlock()
+	p.logger.Store(logger.With("plugin", p.desc.Name))
+	return p, nil
+}
+
+// launch starts the executable and performs the describe handshake.
+func (p *processPlugin) launch() (*processInstance, processDescriptor, error) {
+	var desc processDescriptor
+	cmd := exec.Command(p.path, p.args...)
+	cmd.Dir = filepath.Dir(p.path)
+	cmd.Env = append(os.Environ(), ProcessPluginProtocolEnv+"="+ProcessPluginProtocolVersion)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, desc, fmt.Errorf("stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, desc, fmt.Errorf("stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, desc, fmt.Errorf("stderr pipe: %w", err)
+	}
+
+	proc := &processInstance{
+		cmd:    cmd,
+		stdin:  stdin,
+		exited: make(chan struct{}),
+	}
+	proc.conn = newRPCConn(stdout, stdin, func(ctx context.Context, method string, params json.RawMessage) (any, error) {
+		return p.handle(ctx, proc, method, params)
+	}, p.overflow, p.dropNotification)
+
+	if err := cmd.Start(); err != nil {
+		return nil, desc, fmt.Errorf("start process: %w", err)
+	}
+
+	go p.forwardStderr(stderr)
+	go func() {
+		_ = proc.conn.serve()
+		proc.exitErr = cmd.Wait()
+		close(proc.exited)
+		if proc.exitErr != nil {
+			p.logger.Load().Error("Process plugin exited", "path", p.path, "error", proc.exitErr)
+		} else {
+			p.logger.Load().Info("Process plugin exited", "path", p.path)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), processHandshakeTimeout)
+	defer cancel()
+	if err := proc.conn.call(ctx, "describe", nil, &desc); err != nil {
+		proc.kill()
+		return nil, desc, fmt.Errorf("describe handshake: %w", err)
+	}
+	if desc.Name == "" {
+		proc.kill()
+		return nil, desc, fmt.Errorf("describe handshake: plugin reported an empty name")
+	}
+	return proc, desc, nil
+}
+
+// relaunch starts the executable again after its process exited. The
+// plugin keeps the description of its first launch, so it must report the
+// same name. Subscriptions of the old process are forgotten; the new one
+// subscribes again in init.
+func (p *processPlugin) relaunch() error {
+	p.logger.Load().Info("Relaunching process plugin", "path", p.path)
+	proc, desc, err := p.launch()
+	if err != nil {
+		return fmt.Errorf("relaunch: %w", err)
+	}
+	if desc.Name != p.desc.Name {
+		proc.kill()
+		return fmt.Errorf("relaunch: plugin reported name %q, was %q", desc.Name, p.desc.Name)
+	}
+	p.subsMu.Lock()
+	p.subs = make(map[uint64]Subscription)
+	p.subsMu.Unlock()
+	p.mu.Lock()
+	p.proc = proc
+	p.mu.Unlock()
+	return nil
+}
+
+// dropNotification records a notification the process sent while its queue
+// was full. The describe handshake may still be running, so desc is read
+// under mu.
+func (p *processPlugin) dropNotification(method string) {
+	p.mu.RLock()
+	name := p.desc.Name
+	p.mu.RUnlock()
+	if p.dropped != nil {
+		p.dropped.With(name).Inc()
+	}
+	p.logger.Load().Warn("Process plugin notification queue is full, dropping notification", "path", p.path,
+		"method", method, "overflow", p.overflow.Overflow)
+}
+
+// current returns the running (or last) process.
+func (p *processPlugin) current() *processInstance {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.proc
+}
+
+// exited reports whether the current process has exited.
+func (p *processPlugin) exited() bool {
+	select {
+	case <-p.current().exited:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *processPlugin) forwardStderr(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		p.logger.Load().Info("Process plugin output", "path", p.path, "line", scanner.Text())
+	}
+}
+
+func (p *processPlugin) Name() string { return p.desc.Name }
+
+func (p *processPlugin) Description() string { return p.desc.Description }
+
+func (p *processPlugin) Capabilities() []Capability {
+	return append([]Capability(nil), p.desc.Capabilities...)
+}
+
+func (p *processPlugin) Dependencies() []Dependency {
+	return append([]Dependency(nil), p.desc.Dependencies...)
+}
+
+// Manifest returns the manifest reported in the describe handshake, or the
+// zero value if the plugin did not send one.
+func (p *processPlugin) Manifest() PluginManifest {
+	if p.desc.Manifest == nil {
+		return PluginManifest{}
+	}
+	return *p.desc.Manifest
+}
+
+// ConfigSchema returns the config schema reported in the describe handshake.
+func (p *processPlugin) ConfigSchema() []ConfigSchema {
+	return p.desc.ConfigSchema
+}
+
+// Actions returns the actions reported in the describe handshake.
+func (p *processPlugin) Actions() []ActionDesc {
+	return p.desc.Actions
+}
+
+// Init relaunches the process if it has exited, e.g. when the supervisor
+// restarts the plugin or a reload restarts it to apply configuration.
+
+func (p *processPlugin) Init(ctx context.Context, logger *slog.Logger, registry PluginRegistry) error {
+	if p.exited() {
+		if err := p.relaunch(); err != nil {
+			return err
+		}
+	}
+	p.mu.Lock()
+	p.registry = registry
+	p.mu.Unlock()
+	if logger != nil {
+		p.logger.Store(logger)
+	}
+	return p.current().conn.call(ctx, "init", nil, nil)
+}
+
+func (p *processPlugin) Start(ctx context.Context) error {
+	return p.current().conn.call(ctx, "start", nil, nil)
+}
+
+// Stop stops the current process. Its exit is not a failure.
+func (p *processPlugin) Stop(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	proc := p.current()
+	proc.stopped.Store(true)
+	callErr := proc.conn.call(ctx, "stop", nil, nil)
+	if callErr == errConnClosed {
+		callErr = nil
+	}
+	_ = proc.stdin.Close()
+
+	select {
+	case <-proc.exited:
+	case <-time.After(processStopGrace):
+		p.logger.Load().Warn("Process plugin did not exit after stop, killing", "path", p.path)
+		proc.kill()
+	case <-ctx.Done():
+		proc.kill()
+	}
+	return callErr
+}
+
+func (p *processPlugin) Status() ServiceStatus {
+	proc := p.current()
+	select {
+	case <-proc.exited:
+		return StatusUnhealthy
+	default:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), processStatusTimeout)
+	defer cancel()
+	var status ServiceStatus
+	if err := proc.conn.call(ctx, "status", nil, &status); err != nil {
+		return StatusUnknown
+	}
+	return status
+}
+
+func (p *processPlugin) Execute(ctx context.Context, action string, params map[string]interface{}) (interface{}, error) {
+	var res processExecuteResult
+	if err := p.current().conn.call(ctx, "execute", processExecuteParams{Action: action, Params: params}, &res); err != nil {
+		return nil, err
+	}
+	return decodeExecuteResult(res)
+}
+
+// Config returns the plugin's own (already redacted) config view.
+func (p *processPlugin) Config() any {
+	if !p.desc.HasConfig {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), processStatusTimeout)
+	defer cancel()
+	var raw json.RawMessage
+	if err := p.current().conn.call(ctx, "config", nil, &raw); err != nil {
+		return map[string]string{"error": err.Error()}
+	}
+	return raw
+}
+
+// processConfig is the config a process plugin is sent: its own sections
+// (its name and those of its config schema) with secrets unwrapped, since
+// JSON would redact them, and core with secrets redacted. Other plugins'
+// sections stay in core.
+func (p *processPlugin) processConfig(cfg map[string]map[string]any) map[string]map[string]any {
+	own := map[string]map[string]any{}
+	for _, section := range append([]string{p.Name()}, p.schemaSections()...) {
+		if values, ok := cfg[section]; ok && section != "core" {
+			own[section] = values
+		}
+	}
+	out := UnwrapConfig(own)
+	if values, ok := cfg["core"]; ok {
+		out["core"] = RedactConfig(map[string]map[string]any{"core": values}, []ConfigSchema{CoreConfigSchema()})["core"]
+	}
+	return out
+}
+
+func (p *processPlugin) schemaSections() []string {
+	var sections []string
+	for _, schema := range p.desc.ConfigSchema {
+		sections = append(sections, schema.Section)
+	}
+	return sections
+}
+
+func (proc *processInstance) kill() {
+	if proc.cmd.Process != nil {
+		_ = proc.cmd.Process.Kill()
+	}
+}
+
+// handle serves PluginRegistry callbacks issued by the plugin process proc.
+func (p *processPlugin) handle(ctx context.Context, proc *processInstance, method string, params json.RawMessage) (any, error) {
+	p.mu.RLock()
+	registry := p.registry
+	p.mu.RUnlock()
+	if registry == nil {
+		return nil, fmt.Errorf("registry not available before init")
+	}
+
+	switch method {
+	case "get_config":
+		return p.processConfig(registry.GetConfig()), nil
+	case "register_event_type":
+		var desc EventTypeDesc
+		if err := json.Unmarshal(params, &desc); err != nil {
+			return nil, err
+		}
+		return nil, registry.RegisterEventType(desc)
+	case "subscribe":
+		var sub processSubscribeParams
+		if err := json.Unmarshal(params, &sub); err != nil {
+			return nil, err
+		}
+		handle := registry.Subscribe(sub.Pattern, func(ctx context.Context, event InternalEvent) {
+			select {
+			case <-proc.exited:
+				return
+			default:
+			}
+			if err := proc.conn.notify("event", processEventParams{Subscription: sub.ID, Event: event}); err != nil {
+				p.logger.Load().Warn("Failed to deliver event to process plugin", "event", event.Type, "error", err)
+			}
+		})
+		p.subsMu.Lock()
+		p.subs[sub.ID] = handle
+		p.subsMu.Unlock()
+		return nil, nil
+	case "unsubscribe":
+		var sub processSubscribeParams
+		if err := json.Unmarshal(params, &sub); err != nil {
+			return nil, err
+		}
+		p.subsMu.Lock()
+		handle, ok := p.subs[sub.ID]
+		delete(p.subs, sub.ID)
+		p.subsMu.Unlock()
+		if ok {
+			handle.Unsubscribe()
+		}
+		return nil, nil
+	case "list_plugins":
+		var req processPeerParams
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, err
+			}
+		}
+		var plugins []Plugin
+		switch {
+		case req.Name != "":
+			plug, err := registry.GetPlugin(req.Name)
+			if err != nil {
+				return nil, err
+			}
+			plugins = []Plugin{plug}
+		case req.Capability != "":
+			plugins = registry.GetPluginsWithCapability(req.Capability)
+		default:
+			plugins = registry.ListPlugins()
+		}
+		out := make([]processDescriptor, 0, len(plugins))
+		for _, plug := range plugins {
+			if plug.Name() == p.Name() {
+				continue
+			}
+			out = append(out, processDescriptor{
+				Name:         plug.Name(),
+				Description:  plug.Description(),
+				Capabilities: plug.Capabilities(),
+			})
+		}
+		return out, nil
+	case "plugin_status":
+		var req processPeerParams
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, err
+		}
+		plug, err := registry.GetPlugin(req.Name)
+		if err != nil {
+			return nil, err
+		}
+		return plug.Status(), nil
+	case "plugin_execute":
+		var req processPeerParams
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, err
+		}
+		plug, err := registry.GetPlugin(req.Name)
+		if err != nil {
+			return nil, err
+		}
+		res, err := plug.Execute(ctx, req.Action, req.Params)
+		if err != nil {
+			return nil, err
+		}
+		return encodeExecuteResult(res)
+	case "publish":
+		var pub processPublishParams
+		if err := json.Unmarshal(params, &pub); err != nil {
+			return nil, err
+		}
+		registry.Publish(ctx, pub.Event)
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown method: %s", method)
+	}
+}
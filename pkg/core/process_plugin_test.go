@@ -0,0 +1,250 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const helperProcessEnv = "GITOPS_TEST_PROCESS_PLUGIN"
+
+// helperPlugin runs inside the test binary when it is re-executed as a
+// process plugin by TestProcessPlugin.
+type helperPlugin struct {
+	registry PluginRegistry
+	pings    Subscription
+}
+
+func (p *helperPlugin) Name() string { return "helper" }
+func (p *helperPlugin) Init(ctx context.Context, logger *slog.Logger, registry PluginRegistry) error {
+	// Forwarded by core while it swaps in the logger passed to Init.
+	fmt.Fprintln(os.Stderr, "helper init")
+	p.registry = registry
+	p.pings = registry.Subscribe("ping_*", func(ctx context.Context, event InternalEvent) {
+		registry.Publish(ctx, InternalEvent{Type: "pong_received", Source: "helper", Repo: event.Repo})
+		// The deprecated package-level Publish is relayed to core too.
+		Publish(ctx, InternalEvent{Type: "pong_legacy", Source: "helper", Repo: event.Repo})
+	})
+	return nil
+}
+func (p *helperPlugin) Start(ctx context.Context) error { return nil }
+func (p *helperPlugin) Stop(ctx context.Context) error  { return nil }
+func (p *helperPlugin) Description() string             { return "helper process plugin" }
+func (p *helperPlugin) Capabilities() []Capability      { return []Capability{CapabilitySecrets} }
+func (p *helperPlugin) Status() ServiceStatus           { return StatusHealthy }
+func (p *helperPlugin) Execute(ctx context.Context, action string, params map[string]interface{}) (interface{}, error) {
+	switch action {
+	case "get_secrets":
+		cfg := p.registry.GetConfig()
+		return map[string]string{"TOKEN": fmt.Sprint(cfg["helper"]["token"])}, nil
+	case "get_runtime_files":
+		return []RuntimeFile{{EnvKey: "CERT_FILE", Filename: "cert.pem", Content: []byte("data"), Mode: 0o600}}, nil
+	case "unsubscribe":
+		p.pings.Unsubscribe()
+		return nil, nil
+	case "exit":
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			os.Exit(3)
+		}()
+		return nil, nil
+	case "panic":
+		panic("boom")
+	default:
+		return nil, fmt.Errorf("unknown action: %s", action)
+	}
+}
+func (p *helperPlugin) Actions() []ActionDesc {
+	return []ActionDesc{
+		{Name: "get_secrets", Internal: true},
+		{Name: "get_runtime_files", Internal: true},
+		{Name: "unsubscribe", Scope: ScopeTrigger},
+	}
+}
+func (p *helperPlugin) Manifest() PluginManifest {
+	return PluginManifest{Name: "helper", Version: "0.1.0", APIVersion: APIVersion, Capabilities: []Capability{CapabilitySecrets}}
+}
+func (p *helperPlugin) Config() any {
+	return map[string]any{"token": Secret{Value: "abc"}}
+}
+
+func TestHelperProcessPlugin(t *testing.T) {
+	if os.Getenv(helperProcessEnv) != "1" {
+		t.Skip("helper process only")
+	}
+	if err := ServeProcessPlugin(&helperPlugin{}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestProcessPlugin(t *testing.T) {
+	t.Setenv(helperProcessEnv, "1")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	plug, err := startProcessPlugin(os.Args[0], []string{"-test.run=^TestHelperProcessPlugin$"}, logger, DefaultDeliveryPolicy, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "helper", plug.Name())
+	assert.Equal(t, []Capability{CapabilitySecrets}, plug.Capabilities())
+	assert.Equal(t, "0.1.0", plug.Manifest().Version)
+
+	mgr := NewModuleManager(logger)
+	mgr.SetConfig(map[string]map[string]any{"helper": {"token": "s3cret"}})
+	mgr.Register(plug)
+
+	ctx := context.Background()
+	require.NoError(t, mgr.Init(ctx))
+	assert.Equal(t, StatusHealthy, plug.Status())
+
+	secrets, err := plug.Execute(ctx, "get_secrets", nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"TOKEN": "s3cret"}, secrets)
+
+	files, err := plug.Execute(ctx, "get_runtime_files", nil)
+	require.NoError(t, err)
+	require.IsType(t, []RuntimeFile{}, files)
+	assert.Equal(t, []byte("data"), files.([]RuntimeFile)[0].Content)
+
+	_, err = plug.Execute(ctx, "panic", nil)
+	assert.ErrorContains(t, err, "boom")
+	assert.Equal(t, StatusHealthy, plug.Status())
+
+	got := make(chan InternalEvent, 2)
+	mgr.Subscribe("pong_*", func(ctx context.Context, event InternalEvent) {
+		got <- event
+	})
+	mgr.Publish(ctx, InternalEvent{Type: "ping_process", Source: "test", Repo: "repo1"})
+	types := map[EventTypeName]bool{}
+	for len(types) < 2 {
+		select {
+		case ev := <-got:
+			assert.Equal(t, "helper", ev.Source)
+			assert.Equal(t, "repo1", ev.Repo)
+			types[ev.Type] = true
+		case <-time.After(5 * time.Second):
+			t.Fatal("event was not relayed through the process plugin")
+		}
+	}
+	assert.Equal(t, map[EventTypeName]bool{"pong_received": true, "pong_legacy": true}, types)
+
+	subs := mgr.EventBus().Subscriptions()
+	require.Len(t, subs, 2)
+	assert.Equal(t, SubscriptionInfo{ID: 1, Pattern: "ping_*", Plugin: "helper", Buffer: 256, Workers: 1, Overflow: OverflowBlock}, subs[0])
+	_, err = plug.Execute(ctx, "unsubscribe", nil)
+	require.NoError(t, err)
+	subs = mgr.EventBus().Subscriptions()
+	require.Len(t, subs, 1, "the plugin's subscription is removed in core")
+	assert.Equal(t, "pong_*", subs[0].Pattern)
+
+	info := buildPluginInfo(plug, true)
+	assert.Equal(t, map[string]any{"token": "REDACTED"}, info.Config)
+
+	require.NoError(t, plug.Stop(ctx))
+	assert.Equal(t, StatusUnhealthy, plug.Status())
+}
+
+func TestProcessPlugin_RelaunchedAfterExit(t *testing.T) {
+	t.Setenv(helperProcessEnv, "1")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	plug, err := startProcessPlugin(os.Args[0], []string{"-test.run=^TestHelperProcessPlugin$"}, logger, DefaultDeliveryPolicy, nil)
+	require.NoError(t, err)
+	mgr := NewModuleManager(logger)
+	mgr.SetConfig(map[string]map[string]any{"core": {"restart_policy": map[string]any{"backoff": "10ms"}}})
+	mgr.Register(plug)
+
+	ctx := context.Background()
+	require.NoError(t, mgr.Init(ctx))
+	mgr.Start(ctx)
+	defer mgr.Stop(ctx)
+
+	_, err = plug.Execute(ctx, "exit", nil)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		lc, _ := mgr.Lifecycle("helper")
+		return lc.Restarts == 1 && lc.State == StateRunning && plug.Status() == StatusHealthy
+	}, 10*time.Second, 20*time.Millisecond)
+
+	// The relaunched process subscribed again, and only once.
+	got := make(chan InternalEvent, 2)
+	mgr.Subscribe("pong_received", func(ctx context.Context, event InternalEvent) {
+		got <- event
+	})
+	subs := mgr.EventBus().Subscriptions()
+	require.Len(t, subs, 2)
+	mgr.Publish(ctx, InternalEvent{Type: "ping_process", Source: "test", Repo: "repo1"})
+	select {
+	case ev := <-got:
+		assert.Equal(t, "helper", ev.Source)
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not relayed through the relaunched process plugin")
+	}
+}
+
+func TestProcessPlugin_ConfigIsScoped(t *testing.T) {
+	t.Setenv(helperProcessEnv, "1")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	plug, err := startProcessPlugin(os.Args[0], []string{"-test.run=^TestHelperProcessPlugin$"}, logger, DefaultDeliveryPolicy, nil)
+	require.NoError(t, err)
+	defer plug.Stop(context.Background())
+	mgr := NewModuleManager(logger)
+	mgr.SetConfig(map[string]map[string]any{
+		"core": {"target_dir": "/opt/stacks", "token": NewSecret("ghp_core"), "auth": map[string]any{"tokens": []any{
+			map[string]any{"name": "ops", "token": "a-token", "scopes": "admin"},
+		}}},
+		"helper": {"token": NewSecret("s3cret")},
+		"other":  {"api_key": "other-secret"},
+	})
+	mgr.Register(plug)
+	require.NoError(t, mgr.Init(context.Background()))
+
+	cfg, err := plug.handle(context.Background(), plug.current(), "get_config", nil)
+	require.NoError(t, err)
+	data, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	body := string(data)
+	assert.Contains(t, body, `"helper":{"token":"s3cret"}`, "its own secrets are sent")
+	assert.Contains(t, body, `"target_dir":"/opt/stacks"`)
+	assert.NotContains(t, body, "other")
+	assert.NotContains(t, body, "ghp_core")
+	assert.NotContains(t, body, "a-token")
+}
+
+func TestProcessPlugin_Actions(t *testing.T) {
+	t.Setenv(helperProcessEnv, "1")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	plug, err := startProcessPlugin(os.Args[0], []string{"-test.run=^TestHelperProcessPlugin$"}, logger, DefaultDeliveryPolicy, nil)
+	require.NoError(t, err)
+	defer plug.Stop(context.Background())
+	mgr := NewModuleManager(logger)
+	mgr.SetConfig(map[string]map[string]any{"helper": {"token": "s3cret"}})
+	mgr.Register(plug)
+	require.NoError(t, mgr.Init(context.Background()))
+	h := mgr.httpHandler()
+
+	rr := serve(h, http.MethodGet, "/api/plugins/helper", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	var info pluginInfo
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &info))
+	require.Len(t, info.Actions, 3)
+	assert.True(t, info.Actions[0].Internal)
+
+	rr = invokeAction(h, "/api/plugins/helper/actions/get_secrets", "", "")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "s3cret")
+	assert.Equal(t, http.StatusNotFound, invokeAction(h, "/api/plugins/helper/actions/panic", "", "").Code, "undescribed actions are refused")
+	assert.Equal(t, http.StatusOK, invokeAction(h, "/api/plugins/helper/actions/unsubscribe", "", "").Code)
+}
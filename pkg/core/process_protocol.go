@@ -0,0 +1,380 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProcessPluginProtocolEnv is set by core when launching an executable plugin.
+// Plugin binaries use it to detect that they were started by git-ops.
+const ProcessPluginProtocolEnv = "GITOPS_PLUGIN_PROTOCOL"
+
+// ProcessPluginProtocolVersion is the wire protocol version spoken over stdio.
+const ProcessPluginProtocolVersion = "1"
+
+// errConnClosed is returned for calls on a connection whose peer went away.
+var errConnClosed = errors.New("plugin connection closed")
+
+// rpcMessage is a single line on the wire. Requests carry a Method, responses
+// carry the ID of the request they answer. Requests with ID 0 are
+// notifications and never get a response.
+type rpcMessage struct {
+	ID     uint64          `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type rpcHandler func(ctx context.Context, method string, params json.RawMessage) (any, error)
+
+// rpcConn is a symmetric JSON-lines RPC connection: both ends can issue calls
+// and notifications while serving the calls of the other end.
+type rpcConn struct {
+	writeMu sync.Mutex
+	enc     *json.Encoder
+	reader  *bufio.Reader
+	handler rpcHandler
+
+	nextID    atomic.Uint64
+	pendingMu sync.Mutex
+	pending   map[uint64]chan rpcMessage
+
+	// Notifications (events, publishes) are handled one at a time, in the
+	// order they arrived, from a queue of overflow.Buffer. When it is full
+	// the read loop waits for room or drops the notification, as the event
+	// bus does for a subscriber, and calls onDrop.
+	notifications chan rpcMessage
+	overflow      DeliveryPolicy
+	onDrop        func(method string)
+
+	done chan struct{}
+}
+
+// newRPCConn returns a connection that queues notifications as policy
+// allows; onDrop, if set, is called for each notification dropped.
+func newRPCConn(r io.Reader, w io.Writer, handler rpcHandler, policy DeliveryPolicy, onDrop func(method string)) *rpcConn {
+	if policy.Buffer < 0 {
+		policy.Buffer = 0
+	}
+	return &rpcConn{
+		enc:           json.NewEncoder(w),
+		reader:        bufio.NewReaderSize(r, 64*1024),
+		handler:       handler,
+		pending:       make(map[uint64]chan rpcMessage),
+		notifications: make(chan rpcMessage, policy.Buffer),
+		overflow:      policy,
+		onDrop:        onDrop,
+		done:          make(chan struct{}),
+	}
+}
+
+// serve reads messages until the peer closes the stream. It must run in its
+// own goroutine; the returned error is io.EOF on a clean shutdown.
+// Notifications still queued when the stream ends are handled afterwards.
+func (c *rpcConn) serve() error {
+	go c.handleNotifications()
+	defer close(c.notifications)
+
+	var err error
+	for {
+		var line []byte
+		line, err = c.reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var msg rpcMessage
+			if jerr := json.Unmarshal(line, &msg); jerr != nil {
+				continue
+			}
+			c.dispatch(msg)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	c.pendingMu.Lock()
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	close(c.done)
+	c.pendingMu.Unlock()
+	return err
+}
+
+func (c *rpcConn) dispatch(msg rpcMessage) {
+	if msg.Method == "" {
+		c.pendingMu.Lock()
+		ch, ok := c.pending[msg.ID]
+		delete(c.pending, msg.ID)
+		c.pendingMu.Unlock()
+		if ok {
+			ch <- msg
+		}
+		return
+	}
+	if msg.ID == 0 {
+		if !c.queueNotification(msg) && c.onDrop != nil {
+			c.onDrop(msg.Method)
+		}
+		return
+	}
+
+	go func() {
+		result, err := c.invoke(msg)
+		reply := rpcMessage{ID: msg.ID}
+		if err != nil {
+			reply.Error = err.Error()
+		} else if result != nil {
+			data, merr := json.Marshal(result)
+			if merr != nil {
+				reply.Error = fmt.Sprintf("marshal result: %v", merr)
+			} else {
+				reply.Result = data
+			}
+		}
+		_ = c.write(reply)
+	}()
+}
+
+// queueNotification queues msg for handleNotifications, applying the
+// overflow policy if the queue is full. It reports whether msg was queued.
+// While it blocks, the read loop does not read responses either, so a
+// handler waiting on a call resumes once the block timeout drops msg.
+func (c *rpcConn) queueNotification(msg rpcMessage) bool {
+	select {
+	case c.notifications <- msg:
+		return true
+	default:
+	}
+	if c.overflow.Overflow == OverflowDrop {
+		return false
+	}
+
+	var timeout <-chan time.Time
+	if c.overflow.BlockTimeout > 0 {
+		timer := time.NewTimer(c.overflow.BlockTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case c.notifications <- msg:
+		return true
+	case <-timeout:
+		return false
+	}
+}
+
+// handleNotifications runs the handler for queued notifications, one at a
+// time, until serve has returned and the queue is empty.
+func (c *rpcConn) handleNotifications() {
+	for msg := range c.notifications {
+		_, _ = c.invoke(msg)
+	}
+}
+
+// invoke runs the handler and converts panics into errors so a misbehaving
+// handler cannot tear down the connection.
+func (c *rpcConn) invoke(msg rpcMessage) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic in %s: %v", msg.Method, r)
+		}
+	}()
+	if c.handler == nil {
+		return nil, fmt.Errorf("unknown method: %s", msg.Method)
+	}
+	return c.handler(context.Background(), msg.Method, msg.Params)
+}
+
+func (c *rpcConn) write(msg rpcMessage) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	select {
+	case <-c.done:
+		return errConnClosed
+	default:
+	}
+	return c.enc.Encode(msg)
+}
+
+// call sends a request and waits for the response, decoding it into out
+// when out is non-nil.
+func (c *rpcConn) call(ctx context.Context, method string, params any, out any) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	msg := rpcMessage{ID: c.nextID.Add(1), Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("marshal %s params: %w", method, err)
+		}
+		msg.Params = data
+	}
+
+	ch := make(chan rpcMessage, 1)
+	c.pendingMu.Lock()
+	select {
+	case <-c.done:
+		c.pendingMu.Unlock()
+		return errConnClosed
+	default:
+	}
+	c.pending[msg.ID] = ch
+	c.pendingMu.Unlock()
+
+	if err := c.write(msg); err != nil {
+		c.pendingMu.Lock()
+		delete(c.pending, msg.ID)
+		c.pendingMu.Unlock()
+		return err
+	}
+
+	select {
+	case reply, ok := <-ch:
+		if !ok {
+			return errConnClosed
+		}
+		if reply.Error != "" {
+			return errors.New(reply.Error)
+		}
+		if out != nil && len(reply.Result) > 0 {
+			return json.Unmarshal(reply.Result, out)
+		}
+		return nil
+	case <-ctx.Done():
+		c.pendingMu.Lock()
+		delete(c.pending, msg.ID)
+		c.pendingMu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// notify sends a request without waiting for (or expecting) a response.
+func (c *rpcConn) notify(method string, params any) error {
+	msg := rpcMessage{Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("marshal %s params: %w", method, err)
+		}
+		msg.Params = data
+	}
+	return c.write(msg)
+}
+
+// Wire payloads shared by host and plugin.
+
+type processDescriptor struct {
+	Name         string          `json:"name"`
+	Description  string          `json:"description,omitempty"`
+	Capabilities []Capability    `json:"capabilities,omitempty"`
+	HasConfig    bool            `json:"has_config,omitempty"`
+	Dependencies []Dependency    `json:"dependencies,omitempty"`
+	Manifest     *PluginManifest `json:"manifest,omitempty"`
+	ConfigSchema []ConfigSchema  `json:"config_schema,omitempty"`
+	Actions      []ActionDesc    `json:"actions,omitempty"`
+}
+
+type processExecuteParams struct {
+	Action string         `json:"action"`
+	Params map[string]any `json:"params,omitempty"`
+}
+
+// processExecuteResult tags well-known result types so the host can hand
+// plugins the same Go types an in-process plugin would return.
+type processExecuteResult struct {
+	Kind  string          `json:"kind,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+const (
+	resultKindStringMap    = "string_map"
+	resultKindRuntimeFiles = "runtime_files"
+)
+
+// processRuntimeFile mirrors RuntimeFile but keeps Content on the wire.
+type processRuntimeFile struct {
+	EnvKey   string `json:"env_key"`
+	Filename string `json:"filename,omitempty"`
+	Content  []byte `json:"content,omitempty"`
+	Mode     uint32 `json:"mode,omitempty"`
+}
+
+type processPeerParams struct {
+	Name       string         `json:"name,omitempty"`
+	Capability Capability     `json:"capability,omitempty"`
+	Action     string         `json:"action,omitempty"`
+	Params     map[string]any `json:"params,omitempty"`
+}
+
+type processSubscribeParams struct {
+	ID      uint64 `json:"id"`
+	Pattern string `json:"pattern"`
+}
+
+type processEventParams struct {
+	Subscription uint64        `json:"subscription"`
+	Event        InternalEvent `json:"event"`
+}
+
+type processPublishParams struct {
+	Event InternalEvent `json:"event"`
+}
+
+func encodeExecuteResult(v any) (processExecuteResult, error) {
+	var res processExecuteResult
+	switch t := v.(type) {
+	case nil:
+		return res, nil
+	case map[string]string:
+		res.Kind = resultKindStringMap
+	case []RuntimeFile:
+		res.Kind = resultKindRuntimeFiles
+		files := make([]processRuntimeFile, 0, len(t))
+		for _, f := range t {
+			files = append(files, processRuntimeFile(f))
+		}
+		v = files
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return res, err
+	}
+	res.Value = data
+	return res, nil
+}
+
+func decodeExecuteResult(res processExecuteResult) (any, error) {
+	if len(res.Value) == 0 {
+		return nil, nil
+	}
+	switch res.Kind {
+	case resultKindStringMap:
+		var out map[string]string
+		err := json.Unmarshal(res.Value, &out)
+		return out, err
+	case resultKindRuntimeFiles:
+		var files []processRuntimeFile
+		if err := json.Unmarshal(res.Value, &files); err != nil {
+			return nil, err
+		}
+		out := make([]RuntimeFile, 0, len(files))
+		for _, f := range files {
+			out = append(out, RuntimeFile(f))
+		}
+		return out, nil
+	default:
+		var out any
+		err := json.Unmarshal(res.Value, &out)
+		return out, err
+	}
+}
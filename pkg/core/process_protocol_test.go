@@ -0,0 +1,142 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPCConn_NotificationsInOrder(t *testing.T) {
+	hostR, guestW := io.Pipe()
+	guestR, hostW := io.Pipe()
+	defer guestW.Close()
+	defer hostW.Close()
+
+	var (
+		mu       sync.Mutex
+		got      []int
+		running  atomic.Int32
+		parallel atomic.Bool
+	)
+	var guest *rpcConn
+	guest = newRPCConn(guestR, guestW, func(ctx context.Context, method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "event":
+			if running.Add(1) > 1 {
+				parallel.Store(true)
+			}
+			defer running.Add(-1)
+			var n int
+			require.NoError(t, json.Unmarshal(params, &n))
+			if n%10 == 0 {
+				// A handler may call back into the host while it runs.
+				var pong string
+				assert.NoError(t, guest.call(ctx, "ping", nil, &pong))
+			}
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			got = append(got, n)
+			mu.Unlock()
+			return nil, nil
+		default:
+			return nil, nil
+		}
+	}, DefaultDeliveryPolicy, nil)
+	host := newRPCConn(hostR, hostW, func(ctx context.Context, method string, params json.RawMessage) (any, error) {
+		return "pong", nil
+	}, DefaultDeliveryPolicy, nil)
+	go guest.serve()
+	go host.serve()
+
+	const n = 50
+	for i := 0; i < n; i++ {
+		require.NoError(t, host.notify("event", i))
+	}
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == n
+	}, 5*time.Second, 5*time.Millisecond)
+
+	for i, v := range got {
+		assert.Equal(t, i, v)
+	}
+	assert.False(t, parallel.Load(), "notifications are handled one at a time")
+}
+
+func TestRPCConn_NotificationOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		overflow OverflowPolicy
+		want     []int
+		dropped  int32
+	}{
+		{OverflowDrop, []int{0, 1}, 2},
+		{OverflowBlock, []int{0, 1, 2, 3}, 0},
+	} {
+		t.Run(string(tc.overflow), func(t *testing.T) {
+			hostR, guestW := io.Pipe()
+			guestR, hostW := io.Pipe()
+			defer guestW.Close()
+			defer hostW.Close()
+
+			var (
+				mu      sync.Mutex
+				got     []int
+				dropped atomic.Int32
+			)
+			started := make(chan struct{}, 1)
+			release := make(chan struct{})
+			policy := DeliveryPolicy{Buffer: 1, Overflow: tc.overflow, BlockTimeout: 10 * time.Second}
+			guest := newRPCConn(guestR, guestW, func(ctx context.Context, method string, params json.RawMessage) (any, error) {
+				var n int
+				require.NoError(t, json.Unmarshal(params, &n))
+				if n == 0 {
+					started <- struct{}{}
+					<-release
+				}
+				mu.Lock()
+				got = append(got, n)
+				mu.Unlock()
+				return nil, nil
+			}, policy, func(method string) {
+				assert.Equal(t, "event", method)
+				dropped.Add(1)
+			})
+			host := newRPCConn(hostR, hostW, nil, DefaultDeliveryPolicy, nil)
+			go guest.serve()
+			go host.serve()
+
+			// The first notification occupies the handler, the second the
+			// queue. Writes block while the guest's read loop does, so the
+			// rest are sent from another goroutine.
+			require.NoError(t, host.notify("event", 0))
+			<-started
+			go func() {
+				for i := 1; i < 4; i++ {
+					assert.NoError(t, host.notify("event", i))
+				}
+			}()
+			if tc.overflow == OverflowDrop {
+				require.Eventually(t, func() bool { return dropped.Load() == tc.dropped }, 5*time.Second, 5*time.Millisecond)
+			} else {
+				time.Sleep(50 * time.Millisecond)
+			}
+			close(release)
+
+			require.Eventually(t, func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(got) == len(tc.want)
+			}, 5*time.Second, 5*time.Millisecond)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.dropped, dropped.Load())
+		})
+	}
+}
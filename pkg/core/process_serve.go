@@ -0,0 +1,350 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mywio/git-ops/pkg/metrics"
+)
+
+// ServeProcessPlugin runs p as an out-of-process plugin, speaking the plugin
+// protocol over stdin/stdout until core closes stdin. Plugin binaries call it
+// from main:
+//
+//	func main() {
+//		if err := core.ServeProcessPlugin(Plugin); err != nil {
+//			fmt.Fprintln(os.Stderr, err)
+//			os.Exit(1)
+//		}
+//	}
+//
+// Stdout is reserved for the protocol, so anything the plugin writes to
+// os.Stdout is redirected to stderr, which core forwards to its log.
+func ServeProcessPlugin(p Plugin) error {
+	if p == nil {
+		return fmt.Errorf("nil plugin")
+	}
+	if v := os.Getenv(ProcessPluginProtocolEnv); v != ProcessPluginProtocolVersion {
+		return fmt.Errorf("this binary is a git-ops plugin and must be launched by git-ops (%s=%q, want %q)",
+			ProcessPluginProtocolEnv, v, ProcessPluginProtocolVersion)
+	}
+
+	out := os.Stdout
+	os.Stdout = os.Stderr
+
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil)).With("module", p.Name())
+	g := &processGuest{
+		plugin: p,
+		logger: logger,
+		subs:   make(map[uint64]Listener),
+	}
+	g.conn = newRPCConn(os.Stdin, out, g.handle, DefaultDeliveryPolicy, func(method string) {
+		logger.Warn("Notification queue is full, dropping notification", "method", method)
+	})
+	g.registry = &remoteRegistry{guest: g, mux: http.NewServeMux(), metrics: metrics.NewRegistry(g.logger)}
+
+	err := g.conn.serve()
+	ctx, cancel := context.WithTimeout(context.Background(), processStopGrace)
+	defer cancel()
+	if !g.stopped.Load() {
+		_ = p.Stop(ctx)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// processGuest is the plugin-side half of the protocol.
+type processGuest struct {
+	plugin   Plugin
+	logger   *slog.Logger
+	conn     *rpcConn
+	registry *remoteRegistry
+	stopped  atomic.Bool
+
+	subsMu  sync.RWMutex
+	subs    map[uint64]Listener
+	nextSub atomic.Uint64
+
+	forwardOnce sync.Once
+}
+
+func (g *processGuest) handle(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "describe":
+		_, hasConfig := g.plugin.(ConfigProvider)
+		desc := processDescriptor{
+			Name:         g.plugin.Name(),
+			Description:  g.plugin.Description(),
+			Capabilities: g.plugin.Capabilities(),
+			HasConfig:    hasConfig,
+		}
+		if dp, ok := g.plugin.(DependencyProvider); ok {
+			desc.Dependencies = dp.Dependencies()
+		}
+		if mp, ok := g.plugin.(ManifestProvider); ok {
+			manifest := mp.Manifest()
+			desc.Manifest = &manifest
+		}
+		if sp, ok := g.plugin.(ConfigSchemaProvider); ok {
+			desc.ConfigSchema = sp.ConfigSchema()
+		}
+		if ap, ok := g.plugin.(ActionProvider); ok {
+			desc.Actions = ap.Actions()
+		}
+		return desc, nil
+	case "init":
+		g.forwardLocalEvents()
+		return nil, g.plugin.Init(ctx, g.logger, g.registry)
+	case "start":
+		return nil, g.plugin.Start(ctx)
+	case "stop":
+		g.stopped.Store(true)
+		return nil, g.plugin.Stop(ctx)
+	case "status":
+		return g.plugin.Status(), nil
+	case "config":
+		cfg, ok := g.plugin.(ConfigProvider)
+		if !ok {
+			return nil, nil
+		}
+		return RedactConfigView(cfg.Config()), nil
+	case "execute":
+		var req processExecuteParams
+		if err := json.Unmarshal(params, &req); err != nil {
+			return nil, err
+		}
+		res, err := g.plugin.Execute(ctx, req.Action, req.Params)
+		if err != nil {
+			return nil, err
+		}
+		return encodeExecuteResult(res)
+	case "event":
+		var ev processEventParams
+		if err := json.Unmarshal(params, &ev); err != nil {
+			return nil, err
+		}
+		g.subsMu.RLock()
+		handler, ok := g.subs[ev.Subscription]
+		g.subsMu.RUnlock()
+		if ok {
+			handler(ctx, ev.Event)
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown method: %s", method)
+	}
+}
+
+// forwardLocalEvents relays events published through the deprecated
+// package-level Publish inside the plugin process to core, so existing
+// plugins that call core.Publish directly keep working unchanged.
+func (g *processGuest) forwardLocalEvents() {
+	g.forwardOnce.Do(func() {
+		// Core checks the types of the relayed events.
+		DefaultEventBus().SetUnregisteredPolicy(UnregisteredAllow)
+		Subscribe("*", func(ctx context.Context, event InternalEvent) {
+			if err := g.conn.notify("publish", processPublishParams{Event: event}); err != nil {
+				g.logger.Warn("Failed to forward event to core", "event", event.Type, "error", err)
+			}
+		})
+	})
+}
+
+// remoteRegistry is the PluginRegistry handed to a process plugin. Config,
+// events, subscriptions and calls to other plugins are proxied to core; the
+// HTTP mux is not available across the process boundary.
+type remoteRegistry struct {
+	guest   *processGuest
+	mux     *http.ServeMux
+	metrics *metrics.Registry
+}
+
+func (r *remoteRegistry) GetPlugin(name string) (Plugin, error) {
+	peers, err := r.listPeers(processPeerParams{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	if len(peers) == 0 {
+		return nil, fmt.Errorf("plugin %s not found", name)
+	}
+	return peers[0], nil
+}
+
+func (r *remoteRegistry) GetPluginsWithCapability(cap Capability) []Plugin {
+	peers, err := r.listPeers(processPeerParams{Capability: cap})
+	if err != nil {
+		r.guest.logger.Error("Failed to list plugins via core", "capability", cap, "error", err)
+		return nil
+	}
+	return peers
+}
+
+func (r *remoteRegistry) ListPlugins() []Plugin {
+	peers, err := r.listPeers(processPeerParams{})
+	if err != nil {
+		r.guest.logger.Error("Failed to list plugins via core", "error", err)
+		return nil
+	}
+	return peers
+}
+
+func (r *remoteRegistry) listPeers(req processPeerParams) ([]Plugin, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), processHandshakeTimeout)
+	defer cancel()
+	var descs []processDescriptor
+	if err := r.guest.conn.call(ctx, "list_plugins", req, &descs); err != nil {
+		return nil, err
+	}
+	out := make([]Plugin, 0, len(descs))
+	for _, desc := range descs {
+		out = append(out, &remotePeer{conn: r.guest.conn, desc: desc})
+	}
+	return out, nil
+}
+
+func (r *remoteRegistry) RegisterEventType(desc EventTypeDesc) error {
+	ctx, cancel := context.WithTimeout(context.Background(), processHandshakeTimeout)
+	defer cancel()
+	return r.guest.conn.call(ctx, "register_event_type", desc, nil)
+}
+
+// GetMuxServer returns a mux local to the plugin process. Routes registered
+// on it are not served by core.
+func (r *remoteRegistry) GetMuxServer() *http.ServeMux {
+	r.guest.logger.Warn("HTTP routes are not supported for process plugins; registered handlers will not be served")
+	return r.mux
+}
+
+// Router returns a router on the plugin process's local mux; like
+// GetMuxServer, its routes are not served by core.
+func (r *remoteRegistry) Router(name string) *PluginRouter {
+	r.guest.logger.Warn("HTTP routes are not supported for process plugins; registered handlers will not be served")
+	return newPluginRouter(name, r.mux, r.guest.logger, nil)
+}
+
+// Metrics returns a registry local to the plugin process; its metrics are
+// not served by core.
+func (r *remoteRegistry) Metrics() *metrics.Registry {
+	return r.metrics
+}
+
+func (r *remoteRegistry) Subscribe(pattern string, handler Listener) Subscription {
+	id := r.guest.nextSub.Add(1)
+	r.guest.subsMu.Lock()
+	r.guest.subs[id] = handler
+	r.guest.subsMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), processHandshakeTimeout)
+	defer cancel()
+	if err := r.guest.conn.call(ctx, "subscribe", processSubscribeParams{ID: id, Pattern: pattern}, nil); err != nil {
+		r.guest.logger.Error("Failed to subscribe via core", "pattern", pattern, "error", err)
+	}
+	return &remoteSubscription{guest: r.guest, id: id, pattern: pattern}
+}
+
+// remoteSubscription is a subscription of a process plugin, held by core.
+type remoteSubscription struct {
+	guest   *processGuest
+	id      uint64
+	pattern string
+	once    sync.Once
+}
+
+func (s *remoteSubscription) Pattern() string {
+	return s.pattern
+}
+
+// Unsubscribe stops local delivery right away and asks core to drop the
+// subscription.
+func (s *remoteSubscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.guest.subsMu.Lock()
+		delete(s.guest.subs, s.id)
+		s.guest.subsMu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), processHandshakeTimeout)
+		defer cancel()
+		if err := s.guest.conn.call(ctx, "unsubscribe", processSubscribeParams{ID: s.id, Pattern: s.pattern}, nil); err != nil {
+			s.guest.logger.Warn("Failed to unsubscribe via core", "pattern", s.pattern, "error", err)
+		}
+	})
+}
+
+// Publish forwards the event to core, which dispatches it on its bus.
+func (r *remoteRegistry) Publish(ctx context.Context, event InternalEvent) {
+	if err := r.guest.conn.notify("publish", processPublishParams{Event: event}); err != nil {
+		r.guest.logger.Warn("Failed to forward event to core", "event", event.Type, "error", err)
+	}
+}
+
+func (r *remoteRegistry) GetHTTPClient() *http.Client {
+	return &http.Client{Timeout: 15 * time.Second}
+}
+
+func (r *remoteRegistry) GetConfig() map[string]map[string]any {
+	ctx, cancel := context.WithTimeout(context.Background(), processHandshakeTimeout)
+	defer cancel()
+	var cfg map[string]map[string]any
+	if err := r.guest.conn.call(ctx, "get_config", nil, &cfg); err != nil {
+		r.guest.logger.Error("Failed to fetch config from core", "error", err)
+		return map[string]map[string]any{}
+	}
+	if cfg == nil {
+		cfg = map[string]map[string]any{}
+	}
+	return cfg
+}
+
+// remotePeer is a handle on another plugin hosted by core. Only the
+// descriptive methods, Status and Execute are available; lifecycle calls are
+// owned by core.
+type remotePeer struct {
+	conn *rpcConn
+	desc processDescriptor
+}
+
+func (p *remotePeer) Name() string               { return p.desc.Name }
+func (p *remotePeer) Description() string        { return p.desc.Description }
+func (p *remotePeer) Capabilities() []Capability { return p.desc.Capabilities }
+
+func (p *remotePeer) Init(ctx context.Context, logger *slog.Logger, registry PluginRegistry) error {
+	return fmt.Errorf("plugin %s lifecycle is managed by core", p.desc.Name)
+}
+
+func (p *remotePeer) Start(ctx context.Context) error {
+	return fmt.Errorf("plugin %s lifecycle is managed by core", p.desc.Name)
+}
+
+func (p *remotePeer) Stop(ctx context.Context) error {
+	return fmt.Errorf("plugin %s lifecycle is managed by core", p.desc.Name)
+}
+
+func (p *remotePeer) Status() ServiceStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), processStatusTimeout)
+	defer cancel()
+	var status ServiceStatus
+	if err := p.conn.call(ctx, "plugin_status", processPeerParams{Name: p.desc.Name}, &status); err != nil {
+		return StatusUnknown
+	}
+	return status
+}
+
+func (p *remotePeer) Execute(ctx context.Context, action string, params map[string]interface{}) (interface{}, error) {
+	var res processExecuteResult
+	req := processPeerParams{Name: p.desc.Name, Action: action, Params: params}
+	if err := p.conn.call(ctx, "plugin_execute", req, &res); err != nil {
+		return nil, err
+	}
+	return decodeExecuteResult(res)
+}
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	secretType        = reflect.TypeOf(Secret{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// RedactConfigView returns a JSON-friendly copy of a plugin's config view
+// (maps, lists and scalars) with secrets redacted:
+//
+//   - Secret values;
+//   - struct fields tagged `secret:"true"`, whatever their type;
+//   - string struct fields and map values whose JSON name looks like a
+//     credential (token, password, secret, api_key, private_key), unless
+//     tagged `secret:"false"`.
+//
+// Field names follow their json tags, as encoding/json would. Redacted
+// values read "REDACTED", or stay empty when unset. It is applied to every
+// ConfigProvider's view, so a plugin cannot leak a token by forgetting to
+// wrap it.
+func RedactConfigView(view any) any {
+	if view == nil {
+		return nil
+	}
+	return redactReflect(reflect.ValueOf(view), false)
+}
+
+func redactReflect(v reflect.Value, secret bool) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type() == secretType {
+		return v.Interface().(Secret).Redacted()
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Implements(jsonMarshalerType) && v.Elem().Type() != secretType {
+			return redactMarshaled(v, secret)
+		}
+		return redactReflect(v.Elem(), secret)
+	}
+	if secret {
+		if v.IsZero() {
+			return ""
+		}
+		return "REDACTED"
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		return redactMarshaled(v, false)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		out := map[string]any{}
+		redactStruct(v, out)
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			out[key] = redactReflect(iter.Value(), looksSecret(key) && isStringValue(iter.Value()))
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		fallthrough
+	case reflect.Array:
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = redactReflect(v.Index(i), false)
+		}
+		return out
+	default:
+		return v.Interface()
+	}
+}
+
+// redactStruct adds the exported fields of v to out, flattening untagged
+// embedded structs as encoding/json does.
+func redactStruct(v reflect.Value, out map[string]any) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		value := v.Field(i)
+		if field.Anonymous && name == "" {
+			if value.Kind() == reflect.Pointer {
+				if value.IsNil() {
+					continue
+				}
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				redactStruct(value, out)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyValue(value) {
+			continue
+		}
+		var secret bool
+		switch field.Tag.Get("secret") {
+		case "true":
+			secret = true
+		case "false":
+		default:
+			secret = looksSecret(name) && isStringValue(value)
+		}
+		out[name] = redactReflect(value, secret)
+	}
+}
+
+// redactMarshaled encodes a json.Marshaler and redacts the generic result,
+// so types with custom encodings are still checked for secret keys.
+func redactMarshaled(v reflect.Value, secret bool) any {
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil
+	}
+	return redactReflect(reflect.ValueOf(generic), secret)
+}
+
+func isStringValue(v reflect.Value) bool {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	return v.Kind() == reflect.String
+}
+
+// isEmptyValue reports whether encoding/json's omitempty would omit v.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
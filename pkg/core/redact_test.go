@@ -0,0 +1,104 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type redactBase struct {
+	Version string `json:"version"`
+}
+
+type redactView struct {
+	redactBase
+	Token      string            `json:"token"`
+	Password   *string           `json:"password"`
+	Key        []byte            `json:"key" secret:"true"`
+	Users      []string          `json:"users" secret:"true"`
+	SecretsDir string            `json:"secrets_dir" secret:"false"`
+	APIKey     Secret            `json:"api_key"`
+	Empty      string            `json:"empty_token" secret:"true"`
+	Omitted    string            `json:"omitted,omitempty"`
+	Skipped    string            `json:"-"`
+	Interval   time.Duration     `json:"interval"`
+	Headers    map[string]string `json:"headers"`
+	Nested     struct {
+		PrivateKey string `json:"private_key"`
+		Enabled    bool   `json:"enabled"`
+	} `json:"nested"`
+	Raw     json.RawMessage
+	private string
+}
+
+func TestRedactConfigView(t *testing.T) {
+	password := "hunter2"
+	view := redactView{
+		redactBase: redactBase{Version: "1.0"},
+		Token:      "ghp_secret",
+		Password:   &password,
+		Key:        []byte("k"),
+		Users:      []string{"a"},
+		SecretsDir: "/run/secrets",
+		APIKey:     NewSecret("abc"),
+		Skipped:    "x",
+		Interval:   time.Second,
+		Headers:    map[string]string{"Authorization-Token": "t", "Accept": "json"},
+		Raw:        json.RawMessage(`{"webhook_secret":"s","port":80}`),
+		private:    "p",
+	}
+	view.Nested.PrivateKey = "pk"
+	view.Nested.Enabled = true
+
+	assert.Equal(t, map[string]any{
+		"version":     "1.0",
+		"token":       "REDACTED",
+		"password":    "REDACTED",
+		"key":         "REDACTED",
+		"users":       "REDACTED",
+		"secrets_dir": "/run/secrets",
+		"api_key":     "REDACTED",
+		"empty_token": "",
+		"interval":    time.Second,
+		"headers":     map[string]any{"Authorization-Token": "REDACTED", "Accept": "json"},
+		"nested":      map[string]any{"private_key": "REDACTED", "enabled": true},
+		"Raw":         map[string]any{"webhook_secret": "REDACTED", "port": float64(80)},
+	}, RedactConfigView(&view))
+
+	assert.Nil(t, RedactConfigView(nil))
+	assert.Equal(t, map[string]any{"token": "REDACTED", "count": 1},
+		RedactConfigView(map[string]any{"token": "t", "count": 1}))
+}
+
+// leakyPlugin returns a config struct with a plain token field, as the
+// reconciler did.
+type leakyPlugin struct {
+	testPlugin
+}
+
+func (p *leakyPlugin) Config() any {
+	return struct {
+		Token string
+		Topic string
+	}{Token: "ghp_secret", Topic: "homelab"}
+}
+
+func TestPluginsAPI_RedactsUntaggedTokens(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mgr.Register(&leakyPlugin{testPlugin{name: "leaky"}})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/plugins/leaky", nil)
+	rr := httptest.NewRecorder()
+	mgr.handlePlugin(rr, req)
+
+	assert.NotContains(t, rr.Body.String(), "ghp_secret")
+	var out pluginInfo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&out))
+	assert.Equal(t, map[string]any{"Token": "REDACTED", "Topic": "homelab"}, out.Config)
+}
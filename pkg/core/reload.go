@@ -0,0 +1,253 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Reconfigurable is implemented by plugins that can apply a config change in
+// place. Plugins that don't implement it are restarted (Stop, Init, Start)
+// when one of their config sections changes.
+type Reconfigurable interface {
+	Reconfigure(ctx context.Context, cfg map[string]map[string]any) error
+}
+
+// ConfigSectionProvider declares the config sections a plugin reads. Without
+// it a plugin is assumed to read the section named after it.
+type ConfigSectionProvider interface {
+	ConfigSections() []string
+}
+
+// ConfigLoader re-reads the full configuration for a reload.
+type ConfigLoader func() (map[string]map[string]any, error)
+
+// ReloadResult summarizes a configuration reload.
+type ReloadResult struct {
+	Changed      []string          `json:"changed_sections"`
+	Added        []string          `json:"added,omitempty"`
+	Reconfigured []string          `json:"reconfigured,omitempty"`
+	Restarted    []string          `json:"restarted,omitempty"`
+	Errors       map[string]string `json:"errors,omitempty"`
+	// ConfigProblems lists validation failures of the reloaded config.
+	ConfigProblems []ConfigProblem `json:"config_problems,omitempty"`
+}
+
+// SetConfigLoader sets the function Reload uses to re-read configuration.
+func (m *ModuleManager) SetConfigLoader(loader ConfigLoader) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+	m.configLoader = loader
+}
+
+// PluginsDir returns `core.plugins_dir`, defaulting to "plugins".
+func (m *ModuleManager) PluginsDir() string {
+	if v, ok := m.GetConfig()["core"]["plugins_dir"]; ok {
+		if dir := configString(v); dir != "" {
+			return dir
+		}
+	}
+	return "plugins"
+}
+
+// Reload re-reads the configuration, enables newly configured built-ins and
+// plugins found in plugins_dir, and applies changed config sections to the
+// plugins that read them. Failures of individual plugins are reported in the
+// result; an error is only returned if the configuration could not be loaded
+// or has an invalid `core.auth`, and then the previous one is kept.
+func (m *ModuleManager) Reload(ctx context.Context) (ReloadResult, error) {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	result := ReloadResult{Errors: map[string]string{}}
+	if m.configLoader == nil {
+		return result, fmt.Errorf("config reload is not configured")
+	}
+	cfg, err := m.configLoader()
+	if err != nil {
+		return result, fmt.Errorf("failed to load config: %w", err)
+	}
+	// A config that would lock the HTTP API is not applied either.
+	if _, err := apiTokens(cfg); err != nil {
+		return result, fmt.Errorf("invalid config: core.auth: %w", err)
+	}
+
+	old := m.GetConfig()
+	m.SetConfig(cfg)
+	result.Changed = changedSections(old, m.GetConfig())
+	m.logger.Info("Configuration reloaded", "changed_sections", strings.Join(result.Changed, ","))
+
+	existing := m.ordered()
+	known := make(map[string]bool, len(existing))
+	for _, mod := range existing {
+		known[mod.Name()] = true
+	}
+
+	if err := m.LoadBuiltins(); err != nil {
+		result.Errors["core"] = err.Error()
+	}
+	if err := m.LoadPlugins(m.PluginsDir()); err != nil {
+		result.Errors["core"] = err.Error()
+	}
+	m.startAdded(ctx, known, &result)
+	result.ConfigProblems = m.logConfigProblems()
+
+	changed := make(map[string]bool, len(result.Changed))
+	for _, section := range result.Changed {
+		changed[section] = true
+	}
+	for _, mod := range existing {
+		if !readsChangedSection(mod, changed) {
+			continue
+		}
+		if err := m.reconfigure(ctx, mod, &result); err != nil {
+			result.Errors[mod.Name()] = err.Error()
+			m.logger.Error("Failed to apply configuration", "module", mod.Name(), "error", err)
+		}
+	}
+
+	if len(result.Errors) == 0 {
+		result.Errors = nil
+	}
+	return result, nil
+}
+
+// startAdded initializes and starts modules registered since the last
+// Init/Reload. If the new modules break dependency resolution they are
+// unregistered again.
+func (m *ModuleManager) startAdded(ctx context.Context, known map[string]bool, result *ReloadResult) {
+	m.modulesMu.Lock()
+	order, err := resolveOrder(m.modules)
+	if err != nil {
+		kept := m.modules[:0]
+		var dropped []Module
+		for _, mod := range m.modules {
+			if known[mod.Name()] {
+				kept = append(kept, mod)
+			} else {
+				dropped = append(dropped, mod)
+			}
+		}
+		m.modules = kept
+		m.modulesMu.Unlock()
+		for _, mod := range dropped {
+			result.Errors[mod.Name()] = err.Error()
+			if pp, ok := mod.(*processPlugin); ok {
+				_ = pp.Stop(ctx)
+			}
+		}
+		return
+	}
+	m.order = order
+	m.modulesMu.Unlock()
+
+	for _, mod := range order {
+		if known[mod.Name()] {
+			continue
+		}
+		result.Added = append(result.Added, mod.Name())
+		if err := m.initModule(ctx, mod); err != nil {
+			result.Errors[mod.Name()] = err.Error()
+			continue
+		}
+		go m.supervise(m.runContext(), mod)
+	}
+}
+
+// reconfigure applies the current config to one module, in place if it is
+// Reconfigurable and by restarting it otherwise.
+func (m *ModuleManager) reconfigure(ctx context.Context, mod Module, result *ReloadResult) error {
+	if rc, ok := mod.(Reconfigurable); ok {
+		m.logger.Info("Reconfiguring module", "module", mod.Name())
+		result.Reconfigured = append(result.Reconfigured, mod.Name())
+		return m.safeCall(mod, "reconfigure", func() error { return rc.Reconfigure(ctx, m.GetConfig()) })
+	}
+
+	m.logger.Info("Restarting module to apply configuration", "module", mod.Name())
+	result.Restarted = append(result.Restarted, mod.Name())
+	err := m.safeCall(mod, "stop", func() error { return mod.Stop(ctx) })
+	m.setState(mod.Name(), StateStopped, err)
+	m.dropSubscriptions(mod.Name())
+	if err != nil {
+		m.logger.Warn("Error stopping module for reload", "module", mod.Name(), "error", err)
+	}
+	if err := m.initModule(ctx, mod); err != nil {
+		return err
+	}
+	go m.supervise(m.runContext(), mod)
+	return nil
+}
+
+func (m *ModuleManager) initModule(ctx context.Context, mod Module) error {
+	m.setState(mod.Name(), StateInitializing, nil)
+	err := m.safeCall(mod, "init", func() error {
+		return mod.Init(ctx, m.logger.With("module", mod.Name()), pluginRegistry{m, mod.Name()})
+	})
+	if err != nil {
+		m.setState(mod.Name(), StateFailed, err)
+		return fmt.Errorf("failed to init module %s: %w", mod.Name(), err)
+	}
+	return nil
+}
+
+// runContext returns the context modules were started with, so modules
+// (re)started by a reload outlive the request that triggered it.
+func (m *ModuleManager) runContext() context.Context {
+	m.modulesMu.RLock()
+	defer m.modulesMu.RUnlock()
+	if m.runCtx != nil {
+		return m.runCtx
+	}
+	return context.Background()
+}
+
+// configSectionsOf returns the config sections a module reads.
+func configSectionsOf(mod Module) []string {
+	if sp, ok := mod.(ConfigSectionProvider); ok {
+		return sp.ConfigSections()
+	}
+	return []string{mod.Name()}
+}
+
+func readsChangedSection(mod Module, changed map[string]bool) bool {
+	for _, section := range configSectionsOf(mod) {
+		if changed[section] {
+			return true
+		}
+	}
+	return false
+}
+
+// changedSections returns the sorted names of sections that were added,
+// removed or modified.
+func changedSections(old, cur map[string]map[string]any) []string {
+	var out []string
+	for name, values := range cur {
+		if prev, ok := old[name]; !ok || !reflect.DeepEqual(prev, values) {
+			out = append(out, name)
+		}
+	}
+	for name := range old {
+		if _, ok := cur[name]; !ok {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (m *ModuleManager) handleConfigReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	result, err := m.Reload(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,224 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingPlugin records how often its lifecycle methods are called.
+type countingPlugin struct {
+	testPlugin
+	sections []string
+	inits    atomic.Int32
+	starts   atomic.Int32
+	stops    atomic.Int32
+}
+
+func (p *countingPlugin) Init(ctx context.Context, logger *slog.Logger, registry PluginRegistry) error {
+	p.inits.Add(1)
+	return nil
+}
+func (p *countingPlugin) Start(ctx context.Context) error { p.starts.Add(1); return nil }
+func (p *countingPlugin) Stop(ctx context.Context) error  { p.stops.Add(1); return nil }
+func (p *countingPlugin) ConfigSections() []string {
+	if p.sections != nil {
+		return p.sections
+	}
+	return []string{p.name}
+}
+
+type reconfigurablePlugin struct {
+	countingPlugin
+	got map[string]any
+}
+
+func (p *reconfigurablePlugin) Reconfigure(ctx context.Context, cfg map[string]map[string]any) error {
+	p.got = cfg[p.name]
+	return nil
+}
+
+// subscribingPlugin subscribes to events named after it in Init.
+type subscribingPlugin struct {
+	countingPlugin
+	got atomic.Int32
+}
+
+func (p *subscribingPlugin) Init(ctx context.Context, logger *slog.Logger, registry PluginRegistry) error {
+	registry.Subscribe(p.name, func(context.Context, InternalEvent) { p.got.Add(1) })
+	return p.countingPlugin.Init(ctx, logger, registry)
+}
+
+func newReloadManager(t *testing.T, cfg *map[string]map[string]any) *ModuleManager {
+	t.Helper()
+	mgr := newTestManager(t, nil)
+	mgr.SetConfig(*cfg)
+	mgr.SetConfigLoader(func() (map[string]map[string]any, error) { return *cfg, nil })
+	return mgr
+}
+
+func TestReload_AppliesChangedSections(t *testing.T) {
+	cfg := map[string]map[string]any{
+		"core":    {"plugins_dir": t.TempDir()},
+		"live":    {"interval": 1},
+		"restart": {"url": "a"},
+		"same":    {"x": 1},
+	}
+	mgr := newReloadManager(t, &cfg)
+	live := &reconfigurablePlugin{countingPlugin: countingPlugin{testPlugin: testPlugin{name: "live"}}}
+	restart := &countingPlugin{testPlugin: testPlugin{name: "restart"}}
+	same := &countingPlugin{testPlugin: testPlugin{name: "same"}}
+	mgr.Register(live)
+	mgr.Register(restart)
+	mgr.Register(same)
+
+	ctx := context.Background()
+	require.NoError(t, mgr.Init(ctx))
+	mgr.Start(ctx)
+	defer mgr.Stop(ctx)
+	require.Eventually(t, func() bool { return same.starts.Load() == 1 }, time.Second, 5*time.Millisecond)
+
+	cfg = map[string]map[string]any{
+		"core":    cfg["core"],
+		"live":    {"interval": 2},
+		"restart": {"url": "b"},
+		"same":    {"x": 1},
+	}
+	result, err := mgr.Reload(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"live", "restart"}, result.Changed)
+	assert.Equal(t, []string{"live"}, result.Reconfigured)
+	assert.Equal(t, []string{"restart"}, result.Restarted)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, map[string]any{"interval": 2}, live.got)
+	assert.Equal(t, int32(1), live.inits.Load())
+
+	require.Eventually(t, func() bool { return restart.starts.Load() == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(1), restart.stops.Load())
+	assert.Equal(t, int32(2), restart.inits.Load())
+	assert.Equal(t, int32(1), same.inits.Load())
+	assert.Equal(t, int32(0), same.stops.Load())
+}
+
+func TestReload_RestartReplacesSubscriptions(t *testing.T) {
+	cfg := map[string]map[string]any{"core": {"plugins_dir": t.TempDir()}, "sub": {"x": 1}}
+	mgr := newReloadManager(t, &cfg)
+	sub := &subscribingPlugin{countingPlugin: countingPlugin{testPlugin: testPlugin{name: "sub"}}}
+	mgr.Register(sub)
+
+	ctx := context.Background()
+	require.NoError(t, mgr.Init(ctx))
+	mgr.Start(ctx)
+	cfg = map[string]map[string]any{"core": cfg["core"], "sub": {"x": 2}}
+	_, err := mgr.Reload(ctx)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return sub.starts.Load() == 2 }, time.Second, 5*time.Millisecond)
+
+	subs := mgr.EventBus().Subscriptions()
+	require.Len(t, subs, 1, "the subscription made by the first Init is gone")
+	assert.Equal(t, "sub", subs[0].Plugin)
+	mgr.Publish(ctx, InternalEvent{Type: "sub"})
+	require.NoError(t, mgr.WaitForEvents(ctx))
+	assert.Equal(t, int32(1), sub.got.Load())
+
+	mgr.Stop(ctx)
+	assert.Empty(t, mgr.EventBus().Subscriptions())
+}
+
+func TestReload_ConfigSections(t *testing.T) {
+	cfg := map[string]map[string]any{"core": {"plugins_dir": t.TempDir(), "interval": 1}}
+	mgr := newReloadManager(t, &cfg)
+	plug := &countingPlugin{testPlugin: testPlugin{name: "reader"}, sections: []string{"core"}}
+	ui := &countingPlugin{testPlugin: testPlugin{name: "ui"}, sections: []string{}}
+	mgr.Register(plug)
+	mgr.Register(ui)
+
+	ctx := context.Background()
+	require.NoError(t, mgr.Init(ctx))
+
+	cfg = map[string]map[string]any{
+		"core": {"plugins_dir": cfg["core"]["plugins_dir"], "interval": 2},
+		"ui":   {"theme": "dark"},
+	}
+	result, err := mgr.Reload(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"core", "ui"}, result.Changed)
+	assert.Equal(t, []string{"reader"}, result.Restarted)
+	assert.Equal(t, int32(1), ui.inits.Load())
+}
+
+func TestReload_StartsNewBuiltins(t *testing.T) {
+	name := "builtin_a"
+	cfg := map[string]map[string]any{"core": {"plugins_dir": t.TempDir()}}
+	mgr := newReloadManager(t, &cfg)
+	ctx := context.Background()
+	require.NoError(t, mgr.Init(ctx))
+	mgr.Start(ctx)
+	defer mgr.Stop(ctx)
+
+	cfg = map[string]map[string]any{"core": {"plugins_dir": cfg["core"]["plugins_dir"], "builtin_plugins": name}}
+	result, err := mgr.Reload(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{name}, result.Added)
+
+	require.Eventually(t, func() bool {
+		lc, _ := mgr.Lifecycle(name)
+		return lc.State == StateRunning
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestReload_NoLoader(t *testing.T) {
+	mgr := newTestManager(t, nil)
+	_, err := mgr.Reload(context.Background())
+	assert.EqualError(t, err, "config reload is not configured")
+}
+
+func TestReload_LoadErrorKeepsConfig(t *testing.T) {
+	cfg := map[string]map[string]any{"core": {"plugins_dir": t.TempDir()}, "live": {"interval": 1}}
+	mgr := newReloadManager(t, &cfg)
+	mgr.SetConfigLoader(func() (map[string]map[string]any, error) {
+		return map[string]map[string]any{}, errors.New("environment variable TOKEN is not set")
+	})
+
+	_, err := mgr.Reload(context.Background())
+	assert.ErrorContains(t, err, "TOKEN is not set")
+	assert.Equal(t, 1, mgr.GetConfig()["live"]["interval"], "the previous config is kept")
+}
+
+func TestReload_InvalidAuthKeepsConfig(t *testing.T) {
+	cfg := map[string]map[string]any{"core": {"plugins_dir": t.TempDir()}, "live": {"interval": 1}}
+	mgr := newReloadManager(t, &cfg)
+	cfg = map[string]map[string]any{"core": {"auth": "r-token"}, "live": {"interval": 2}}
+
+	_, err := mgr.Reload(context.Background())
+	assert.ErrorContains(t, err, "core.auth: must be a map")
+	assert.Equal(t, 1, mgr.GetConfig()["live"]["interval"], "the previous config is kept")
+}
+
+func TestConfigReloadAPI(t *testing.T) {
+	cfg := map[string]map[string]any{"core": {"plugins_dir": t.TempDir()}, "a": {"k": 1}}
+	mgr := newReloadManager(t, &cfg)
+	cfg = map[string]map[string]any{"core": cfg["core"], "a": {"k": 2}}
+
+	rec := httptest.NewRecorder()
+	mgr.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/config/reload", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = httptest.NewRecorder()
+	mgr.mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/config/reload", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var result ReloadResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, []string{"a"}, result.Changed)
+}
@@ -0,0 +1,201 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// pluginRoutePrefix is the path plugin routes are served under:
+// /plugins/<name>/...
+const pluginRoutePrefix = "/plugins/"
+
+// PluginRouter registers the HTTP routes of one plugin on the core HTTP
+// server. Patterns follow http.ServeMux, including an optional method ("POST
+// /run") and a trailing "/" for a subtree, but are relative to the plugin's
+// Prefix: "/status" is served at /plugins/<name>/status. Handlers see the
+// path with the prefix stripped, as with http.StripPrefix.
+//
+// Registering a pattern again replaces its handler, so plugins can register
+// their routes in Init or Start and still be restarted. Invalid or
+// conflicting patterns are logged instead of panicking.
+type PluginRouter struct {
+	name   string
+	mux    *http.ServeMux
+	logger *slog.Logger
+	// legacyAllowed reports whether the operator opted in to the plugin's
+	// legacy paths (`core.legacy_routes`).
+	legacyAllowed func() bool
+
+	mu         sync.RWMutex
+	handlers   map[string]http.Handler // by relative pattern
+	bases      []string                // prefixes the patterns are served under
+	registered map[string]bool         // full patterns registered on mux
+}
+
+func newPluginRouter(name string, mux *http.ServeMux, logger *slog.Logger, legacyAllowed func() bool) *PluginRouter {
+	return &PluginRouter{
+		name:          name,
+		mux:           mux,
+		logger:        logger,
+		legacyAllowed: legacyAllowed,
+		handlers:      map[string]http.Handler{},
+		bases:         []string{pluginRoutePrefix + name},
+		registered:    map[string]bool{},
+	}
+}
+
+// Prefix returns the path the plugin's routes are served under, without a
+// trailing slash: /plugins/<name>.
+func (r *PluginRouter) Prefix() string {
+	return pluginRoutePrefix + r.name
+}
+
+// Handle registers handler for pattern, relative to Prefix.
+func (r *PluginRouter) Handle(pattern string, handler http.Handler) {
+	method, path, err := splitRoutePattern(pattern)
+	if err != nil {
+		r.logger.Error("Invalid HTTP route", "plugin", r.name, "pattern", pattern, "error", err)
+		return
+	}
+	pattern = joinRoutePattern(method, path)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[pattern] = handler
+	for _, base := range r.bases {
+		r.register(base, method, path)
+	}
+}
+
+// HandleFunc registers handler for pattern, relative to Prefix.
+func (r *PluginRouter) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	r.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// ServeLegacy also serves every route under prefix, the path the plugin
+// used before routes were namespaced: "/mcp" serves "/stacks" at
+// /mcp/stacks, "" serves "/reconcile" at /reconcile. Legacy paths are only
+// served if the operator lists the plugin in `core.legacy_routes`; ServeLegacy
+// reports whether they are.
+func (r *PluginRouter) ServeLegacy(prefix string) bool {
+	if r.legacyAllowed == nil || !r.legacyAllowed() {
+		return false
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		r.logger.Error("Invalid legacy route prefix", "plugin", r.name, "prefix", prefix)
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !slices.Contains(r.bases, prefix) {
+		r.bases = append(r.bases, prefix)
+	}
+	for pattern := range r.handlers {
+		method, path, _ := splitRoutePattern(pattern)
+		r.register(prefix, method, path)
+	}
+	return true
+}
+
+// servedPrefixes returns the prefixes the plugin's routes are served under.
+func (r *PluginRouter) servedPrefixes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.bases)
+}
+
+// register adds base+path to the mux once. The mux handler looks the
+// relative pattern up on every request, so it follows later registrations.
+func (r *PluginRouter) register(base, method, path string) {
+	full := joinRoutePattern(method, base+path)
+	if r.registered[full] {
+		return
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			r.logger.Error("Failed to register HTTP route", "plugin", r.name, "pattern", full, "error", p)
+		}
+	}()
+	r.mux.Handle(full, http.StripPrefix(base, r.route(joinRoutePattern(method, path))))
+	r.registered[full] = true
+}
+
+func (r *PluginRouter) route(pattern string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if info := requestInfoFrom(req.Context()); info != nil {
+			info.plugin = r.name
+		}
+		r.mu.RLock()
+		handler := r.handlers[pattern]
+		r.mu.RUnlock()
+		if handler == nil {
+			http.NotFound(w, req)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// splitRoutePattern splits "METHOD /path" into its method, which may be
+// empty, and path.
+func splitRoutePattern(pattern string) (string, string, error) {
+	method, path, ok := strings.Cut(strings.TrimSpace(pattern), " ")
+	if !ok {
+		method, path = "", method
+	}
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "/") {
+		return "", "", fmt.Errorf("path %q must start with /", path)
+	}
+	return method, path, nil
+}
+
+func joinRoutePattern(method, path string) string {
+	if method == "" {
+		return path
+	}
+	return method + " " + path
+}
+
+// Router returns the router of the named plugin, created on first use. The
+// same router is returned for the lifetime of the manager, so a restarted
+// plugin re-registers its routes on it.
+func (m *ModuleManager) Router(name string) *PluginRouter {
+	m.routersMu.Lock()
+	defer m.routersMu.Unlock()
+	if r, ok := m.routers[name]; ok {
+		return r
+	}
+	r := newPluginRouter(name, m.mux, m.logger, func() bool { return m.legacyRoutesEnabled(name) })
+	m.routers[name] = r
+	return r
+}
+
+// routePrefixes returns the prefixes the named plugin's routes are served
+// under, or nil if it has no router and registers absolute paths.
+func (m *ModuleManager) routePrefixes(name string) []string {
+	m.routersMu.Lock()
+	r, ok := m.routers[name]
+	m.routersMu.Unlock()
+	if !ok {
+		return nil
+	}
+	return r.servedPrefixes()
+}
+
+// legacyRoutesEnabled reports whether `core.legacy_routes` lists the plugin
+// or "*".
+func (m *ModuleManager) legacyRoutesEnabled(name string) bool {
+	for _, entry := range configStringList(m.GetConfig()["core"]["legacy_routes"]) {
+		if entry == name || entry == "*" {
+			return true
+		}
+	}
+	return false
+}
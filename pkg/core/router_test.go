@@ -0,0 +1,106 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scopedRoutePlugin declares scopes relative to its router.
+type scopedRoutePlugin struct {
+	testPlugin
+}
+
+func (p *scopedRoutePlugin) RouteScopes() map[string]Scope {
+	return map[string]Scope{"POST /run": ScopeTrigger}
+}
+
+func echoPath(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body+" "+r.URL.Path)
+	}
+}
+
+func TestPluginRouter_ServesUnderPrefix(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	router := mgr.Router("hooks")
+	assert.Same(t, router, mgr.Router("hooks"))
+	assert.Equal(t, "/plugins/hooks", router.Prefix())
+
+	router.HandleFunc("GET /ping", echoPath("v1"))
+	router.HandleFunc("/files/", echoPath("files"))
+
+	rr := serve(mgr.httpHandler(), http.MethodGet, "/plugins/hooks/ping", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "v1 /ping", rr.Body.String(), "handlers see the path without the prefix")
+	assert.Equal(t, "files /files/a/b", serve(mgr.httpHandler(), http.MethodGet, "/plugins/hooks/files/a/b", "").Body.String())
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(mgr.httpHandler(), http.MethodPost, "/plugins/hooks/ping", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(mgr.httpHandler(), http.MethodGet, "/ping", "").Code)
+
+	// A restarted plugin registers again and replaces its handler.
+	router.HandleFunc("GET /ping", echoPath("v2"))
+	assert.Equal(t, "v2 /ping", serve(mgr.httpHandler(), http.MethodGet, "/plugins/hooks/ping", "").Body.String())
+}
+
+func TestPluginRouter_InvalidAndConflictingRoutes(t *testing.T) {
+	var logs bytes.Buffer
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(&logs, nil)))
+	mgr.SetConfig(map[string]map[string]any{"core": {"legacy_routes": "*"}})
+
+	a, b := mgr.Router("a"), mgr.Router("b")
+	a.HandleFunc("ping", echoPath("a"))
+	assert.Contains(t, logs.String(), "Invalid HTTP route")
+
+	a.HandleFunc("/ping", echoPath("a"))
+	b.HandleFunc("/ping", echoPath("b"))
+	require.True(t, a.ServeLegacy(""))
+	require.NotPanics(t, func() { b.ServeLegacy("") })
+	assert.Contains(t, logs.String(), "Failed to register HTTP route")
+
+	assert.Equal(t, "a /ping", serve(mgr.httpHandler(), http.MethodGet, "/ping", "").Body.String())
+	assert.Equal(t, "b /ping", serve(mgr.httpHandler(), http.MethodGet, "/plugins/b/ping", "").Body.String())
+}
+
+func TestPluginRouter_LegacyRoutes(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	old := mgr.Router("old")
+	old.HandleFunc("/stacks", echoPath("old"))
+	assert.False(t, old.ServeLegacy("/old"), "legacy routes are opt-in")
+	assert.Equal(t, http.StatusNotFound, serve(mgr.httpHandler(), http.MethodGet, "/old/stacks", "").Code)
+
+	mgr.SetConfig(map[string]map[string]any{"core": {"legacy_routes": []any{"old"}}})
+	require.True(t, old.ServeLegacy("/old/"))
+	assert.Equal(t, "old /stacks", serve(mgr.httpHandler(), http.MethodGet, "/old/stacks", "").Body.String())
+
+	// Routes registered after ServeLegacy are served at both paths.
+	old.HandleFunc("/deployments", echoPath("old"))
+	assert.Equal(t, "old /deployments", serve(mgr.httpHandler(), http.MethodGet, "/old/deployments", "").Body.String())
+	assert.Equal(t, "old /deployments", serve(mgr.httpHandler(), http.MethodGet, "/plugins/old/deployments", "").Body.String())
+
+	assert.False(t, mgr.Router("other").ServeLegacy(""))
+}
+
+func TestPluginRouter_RouteScopes(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mgr.SetConfig(map[string]map[string]any{"core": {
+		"legacy_routes": "svc",
+		"auth": map[string]any{"tokens": []any{
+			map[string]any{"name": "viewer", "token": "read-token", "scopes": "read"},
+			map[string]any{"name": "ci", "token": "trigger-token", "scopes": "trigger"},
+		}},
+	}})
+	mgr.Register(&scopedRoutePlugin{testPlugin{name: "svc"}})
+	router := mgr.Router("svc")
+	router.HandleFunc("POST /run", echoPath("run"))
+	router.ServeLegacy("/svc")
+
+	for _, path := range []string{"/plugins/svc/run", "/svc/run"} {
+		assert.Equal(t, http.StatusForbidden, serve(mgr.httpHandler(), http.MethodPost, path, "read-token").Code, path)
+		assert.Equal(t, http.StatusOK, serve(mgr.httpHandler(), http.MethodPost, path, "trigger-token").Code, path)
+	}
+}
@@ -0,0 +1,530 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigType is the type of a config field.
+type ConfigType string
+
+const (
+	ConfigString     ConfigType = "string"
+	ConfigBool       ConfigType = "bool"
+	ConfigInt        ConfigType = "int"
+	ConfigDuration   ConfigType = "duration"
+	ConfigStringList ConfigType = "string_list" // list of strings
+	ConfigCommaList  ConfigType = "comma_list"  // list or comma-separated string
+	ConfigObject     ConfigType = "object"      // nested keys described by Fields
+	ConfigObjectList ConfigType = "object_list" // list of objects described by Fields
+	ConfigMap        ConfigType = "map"         // free-form map; keys are not checked
+	ConfigAny        ConfigType = "any"
+)
+
+// ConfigField describes one key of a config section.
+type ConfigField struct {
+	Name        string        `json:"name"`
+	Aliases     []string      `json:"aliases,omitempty"`
+	Type        ConfigType    `json:"type"`
+	Required    bool          `json:"required,omitempty"`
+	Default     any           `json:"default,omitempty"`
+	Secret      bool          `json:"secret,omitempty"`
+	Enum        []string      `json:"enum,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Fields      []ConfigField `json:"fields,omitempty"`
+}
+
+// ConfigSchema describes the keys a plugin reads from one config section.
+// Several schemas may describe the same section (e.g. core and the
+// reconciler both read `core`); their fields are combined.
+type ConfigSchema struct {
+	Section string        `json:"section"`
+	Fields  []ConfigField `json:"fields"`
+}
+
+// ConfigSchemaProvider is implemented by plugins that describe their config.
+// Sections of plugins without a schema are not validated.
+type ConfigSchemaProvider interface {
+	ConfigSchema() []ConfigSchema
+}
+
+// ConfigProblem is a single validation failure.
+type ConfigProblem struct {
+	Section string `json:"section"`
+	Key     string `json:"key,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p ConfigProblem) String() string {
+	if p.Key == "" {
+		return p.Section + ": " + p.Message
+	}
+	return p.Section + "." + p.Key + ": " + p.Message
+}
+
+// CoreConfigSchema describes the keys core itself reads from `core`.
+func CoreConfigSchema() ConfigSchema {
+	policyFields := []ConfigField{
+		{Name: "mode", Type: ConfigString, Enum: []string{string(RestartNever), string(RestartOnFailure)}, Default: string(DefaultRestartPolicy.Mode)},
+		{Name: "max_restarts", Type: ConfigInt, Default: DefaultRestartPolicy.MaxRestarts},
+		{Name: "backoff", Type: ConfigDuration, Default: DefaultRestartPolicy.Backoff.String()},
+		{Name: "max_backoff", Type: ConfigDuration, Default: DefaultRestartPolicy.MaxBackoff.String()},
+	}
+	return ConfigSchema{
+		Section: "core",
+		Fields: []ConfigField{
+			{Name: "plugins_dir", Type: ConfigString, Default: "plugins", Description: "Directory of .so and process plugins"},
+			{Name: "builtin_plugins", Type: ConfigCommaList, Description: `Built-in plugins to enable; "*" for all`},
+			{Name: "http_addr", Type: ConfigString, Description: `Bind address of the core HTTP API, or "unix:/path" for a Unix socket`},
+			{Name: "http_tls", Type: ConfigObject, Description: "Serve the HTTP API over TLS", Fields: []ConfigField{
+				{Name: "cert_file", Type: ConfigString, Required: true},
+				{Name: "key_file", Type: ConfigString, Required: true},
+				{Name: "client_ca_file", Type: ConfigString, Description: "Require client certificates signed by these CAs"},
+			}},
+			{Name: "legacy_routes", Type: ConfigCommaList, Description: `Plugins whose routes are also served at their pre-/plugins/<name>/ paths; "*" for all`},
+			{Name: "health", Type: ConfigObject, Description: "Readiness of /readyz", Fields: []ConfigField{
+				{Name: "required_plugins", Type: ConfigCommaList, Description: `Plugins that must be HEALTHY and pass their readiness checks; "*" for all. Default: the reconciler`},
+			}},
+			{Name: "events", Type: ConfigObject, Description: "Delivery of events and handling of unregistered event types", Fields: []ConfigField{
+				{Name: "buffer", Type: ConfigInt, Default: DefaultDeliveryPolicy.Buffer, Description: "Events queued per subscriber"},
+				{Name: "workers", Type: ConfigInt, Default: DefaultDeliveryPolicy.Workers, Description: "Listener goroutines per subscriber; above 1, order is kept per repository only"},
+				{Name: "overflow", Type: ConfigString, Enum: []string{string(OverflowBlock), string(OverflowDrop)}, Default: string(OverflowBlock), Description: "What Publish does when a queue is full"},
+				{Name: "block_timeout", Type: ConfigDuration, Default: DefaultDeliveryPolicy.BlockTimeout.String(), Description: "How long Publish blocks on a full queue before dropping the event"},
+				{Name: "unregistered", Type: ConfigString, Enum: []string{string(UnregisteredAllow), string(UnregisteredWarn), string(UnregisteredReject)}, Default: string(DefaultUnregisteredEventPolicy), Description: "What Publish does with events whose type is not registered"},
+			}},
+			{Name: "restart_policy", Type: ConfigObject, Fields: policyFields, Description: "Default restart policy"},
+			{Name: "restart_policies", Type: ConfigMap, Description: "Per-plugin restart policy overrides"},
+			{Name: "auth", Type: ConfigObject, Description: "API tokens of the core HTTP API", Fields: []ConfigField{
+				{Name: "tokens", Type: ConfigObjectList, Fields: []ConfigField{
+					{Name: "name", Type: ConfigString, Required: true},
+					{Name: "token", Type: ConfigString, Required: true, Secret: true},
+					{Name: "scopes", Type: ConfigCommaList, Required: true, Enum: scopeNames()},
+				}},
+			}},
+			{Name: "plugin_verification", Type: ConfigObject, Description: "Plugin file allowlist or signing key", Fields: []ConfigField{
+				{Name: "sha256", Type: ConfigCommaList},
+				{Name: "public_key", Type: ConfigString},
+				{Name: "public_key_file", Type: ConfigString},
+			}},
+		},
+	}
+}
+
+// BuiltinConfigSchemas returns core's schema and the schemas of the named
+// built-in plugins, without initializing them. It is used to validate a
+// config file offline.
+func BuiltinConfigSchemas(names []string) []ConfigSchema {
+	schemas := []ConfigSchema{CoreConfigSchema()}
+	for _, name := range names {
+		plug, ok := NewBuiltin(name)
+		if !ok {
+			continue
+		}
+		if sp, ok := plug.(ConfigSchemaProvider); ok {
+			schemas = append(schemas, sp.ConfigSchema()...)
+		}
+	}
+	return schemas
+}
+
+// ConfigSchemas returns core's schema and those of all registered plugins.
+func (m *ModuleManager) ConfigSchemas() []ConfigSchema {
+	schemas := []ConfigSchema{CoreConfigSchema()}
+	for _, plug := range m.ListPlugins() {
+		if sp, ok := plug.(ConfigSchemaProvider); ok {
+			schemas = append(schemas, sp.ConfigSchema()...)
+		}
+	}
+	return schemas
+}
+
+// ValidateConfig validates the current config against ConfigSchemas. An
+// unusable `core.auth` is always a problem, since it locks the HTTP API.
+func (m *ModuleManager) ValidateConfig() []ConfigProblem {
+	cfg := m.GetConfig()
+	problems := ValidateConfig(cfg, m.ConfigSchemas())
+	if _, err := apiTokens(cfg); err != nil {
+		for _, p := range problems {
+			if p.Section == "core" && (p.Key == "auth" || strings.HasPrefix(p.Key, "auth.")) {
+				return problems
+			}
+		}
+		problems = append(problems, ConfigProblem{Section: "core", Key: "auth", Message: err.Error()})
+		sortProblems(problems)
+	}
+	return problems
+}
+
+// logConfigProblems validates the config and logs every problem. Invalid
+// config does not stop core; plugins fall back to their defaults.
+func (m *ModuleManager) logConfigProblems() []ConfigProblem {
+	problems := m.ValidateConfig()
+	for _, p := range problems {
+		m.logger.Error("Invalid config", "section", p.Section, "key", p.Key, "error", p.Message)
+	}
+	return problems
+}
+
+// ValidateConfig checks every section that has a schema for unknown keys,
+// values of the wrong type and missing required keys. Empty strings count as
+// unset, since the environment defaults every known key to "". Strings are
+// accepted for bool, int, duration and list fields when they parse, because
+// values from the environment are always strings. Required keys are only
+// enforced in sections that set at least one value. Problems are sorted by
+// section and key.
+func ValidateConfig(cfg map[string]map[string]any, schemas []ConfigSchema) []ConfigProblem {
+	bySection := map[string][]ConfigField{}
+	for _, schema := range schemas {
+		bySection[schema.Section] = append(bySection[schema.Section], schema.Fields...)
+	}
+
+	var problems []ConfigProblem
+	for section, fields := range bySection {
+		values := cfg[section]
+		for _, msg := range validateFields(values, fields, "", !isEmptySection(values)) {
+			msg.Section = section
+			problems = append(problems, msg)
+		}
+	}
+	sortProblems(problems)
+	return problems
+}
+
+func sortProblems(problems []ConfigProblem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Section != problems[j].Section {
+			return problems[i].Section < problems[j].Section
+		}
+		return problems[i].Key < problems[j].Key
+	})
+}
+
+// RedactConfig returns a copy of cfg in which every Secret value (such as a
+// resolved ${...} reference) and every value of a field marked Secret in
+// schemas is replaced by its redacted form. Keys no schema describes are
+// redacted when their name looks like a credential (token, password, secret,
+// api_key, private_key).
+func RedactConfig(cfg map[string]map[string]any, schemas []ConfigSchema) map[string]map[string]any {
+	bySection := map[string][]ConfigField{}
+	for _, schema := range schemas {
+		bySection[schema.Section] = append(bySection[schema.Section], schema.Fields...)
+	}
+	out := make(map[string]map[string]any, len(cfg))
+	for section, values := range cfg {
+		out[section] = redactFields(values, bySection[section])
+	}
+	return out
+}
+
+func redactFields(values map[string]any, fields []ConfigField) map[string]any {
+	known := map[string]ConfigField{}
+	for _, field := range fields {
+		known[field.Name] = field
+		for _, alias := range field.Aliases {
+			known[alias] = field
+		}
+	}
+	out := make(map[string]any, len(values))
+	for key, value := range values {
+		field, ok := known[key]
+		switch {
+		case isUnset(value):
+			out[key] = UnwrapSecrets(value)
+		case field.Secret || !ok && looksSecret(key):
+			out[key] = NewSecret(fmt.Sprint(UnwrapSecrets(value))).Redacted()
+		case !ok:
+			out[key] = redactSecrets(value)
+		case field.Type == ConfigObject:
+			if obj, ok := value.(map[string]any); ok {
+				out[key] = redactFields(obj, field.Fields)
+			} else {
+				out[key] = redactSecrets(value)
+			}
+		case field.Type == ConfigObjectList:
+			items, ok := value.([]any)
+			if !ok {
+				out[key] = redactSecrets(value)
+				continue
+			}
+			redacted := make([]any, len(items))
+			for i, item := range items {
+				if obj, ok := item.(map[string]any); ok {
+					redacted[i] = redactFields(obj, field.Fields)
+				} else {
+					redacted[i] = redactSecrets(item)
+				}
+			}
+			out[key] = redacted
+		default:
+			out[key] = redactSecrets(value)
+		}
+	}
+	return out
+}
+
+// redactSecrets returns v with every Secret replaced by its redacted form.
+func redactSecrets(v any) any {
+	switch t := v.(type) {
+	case Secret:
+		return t.Redacted()
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, item := range t {
+			out[k] = redactSecrets(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, item := range t {
+			out[i] = redactSecrets(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func looksSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range []string{"token", "password", "secret", "api_key", "private_key"} {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func validateFields(values map[string]any, fields []ConfigField, prefix string, enforceRequired bool) []ConfigProblem {
+	var problems []ConfigProblem
+	known := map[string]*ConfigField{}
+	var names []string
+	for i := range fields {
+		known[fields[i].Name] = &fields[i]
+		names = append(names, fields[i].Name)
+		for _, alias := range fields[i].Aliases {
+			known[alias] = &fields[i]
+		}
+	}
+
+	for key, value := range values {
+		field, ok := known[key]
+		if !ok && isUnset(value) {
+			continue
+		}
+		if !ok {
+			msg := "unknown key"
+			if s := suggest(key, names); s != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", s)
+			}
+			problems = append(problems, ConfigProblem{Key: prefix + key, Message: msg})
+			continue
+		}
+		problems = append(problems, validateValue(*field, value, prefix+key)...)
+	}
+
+	if enforceRequired {
+		for _, field := range fields {
+			if field.Required && !isSet(values, field) {
+				problems = append(problems, ConfigProblem{Key: prefix + field.Name, Message: "required key is not set"})
+			}
+		}
+	}
+	return problems
+}
+
+func validateValue(field ConfigField, value any, path string) []ConfigProblem {
+	if s, ok := value.(Secret); ok {
+		value = s.Value
+	}
+	if isUnset(value) {
+		return nil
+	}
+	invalid := func(format string, args ...any) []ConfigProblem {
+		return []ConfigProblem{{Key: path, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	switch field.Type {
+	case ConfigString:
+		switch value.(type) {
+		case map[string]any, []any:
+			return invalid("expected a string, got %s", describeValue(value))
+		}
+		if len(field.Enum) > 0 && !containsString(field.Enum, fmt.Sprint(value)) {
+			return invalid("must be one of %s, got %q", strings.Join(field.Enum, ", "), fmt.Sprint(value))
+		}
+	case ConfigBool:
+		switch t := value.(type) {
+		case bool:
+		case string:
+			if _, err := strconv.ParseBool(strings.TrimSpace(t)); err != nil {
+				return invalid("expected a bool, got %q", t)
+			}
+		default:
+			return invalid("expected a bool, got %s", describeValue(value))
+		}
+	case ConfigInt:
+		switch t := value.(type) {
+		case int, int64:
+		case float64:
+			if t != math.Trunc(t) {
+				return invalid("expected an integer, got %v", t)
+			}
+		case string:
+			if _, err := strconv.Atoi(strings.TrimSpace(t)); err != nil {
+				return invalid("expected an integer, got %q", t)
+			}
+		default:
+			return invalid("expected an integer, got %s", describeValue(value))
+		}
+	case ConfigDuration:
+		switch t := value.(type) {
+		case int, int64, float64, time.Duration:
+		case string:
+			if _, err := time.ParseDuration(strings.TrimSpace(t)); err != nil {
+				return invalid("expected a duration like 30s or 5m, got %q", t)
+			}
+		default:
+			return invalid("expected a duration, got %s", describeValue(value))
+		}
+	case ConfigStringList, ConfigCommaList:
+		switch t := value.(type) {
+		case string:
+			if field.Type == ConfigStringList {
+				return invalid("expected a list, got a string")
+			}
+		case []any:
+			for i, item := range t {
+				switch item.(type) {
+				case map[string]any, []any:
+					return invalid("item %d: expected a string, got %s", i, describeValue(item))
+				}
+			}
+		default:
+			return invalid("expected a list, got %s", describeValue(value))
+		}
+		if len(field.Enum) > 0 {
+			for _, item := range configStringList(value) {
+				if !containsString(field.Enum, item) {
+					return invalid("items must be one of %s, got %q", strings.Join(field.Enum, ", "), item)
+				}
+			}
+		}
+	case ConfigObject:
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return invalid("expected a map, got %s", describeValue(value))
+		}
+		return validateFields(obj, field.Fields, path+".", true)
+	case ConfigObjectList:
+		items, ok := value.([]any)
+		if !ok {
+			return invalid("expected a list, got %s", describeValue(value))
+		}
+		var problems []ConfigProblem
+		for i, item := range items {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			obj, ok := item.(map[string]any)
+			if !ok {
+				problems = append(problems, ConfigProblem{Key: itemPath, Message: "expected a map, got " + describeValue(item)})
+				continue
+			}
+			problems = append(problems, validateFields(obj, field.Fields, itemPath+".", true)...)
+		}
+		return problems
+	case ConfigMap:
+		if _, ok := value.(map[string]any); !ok {
+			return invalid("expected a map, got %s", describeValue(value))
+		}
+	}
+	return nil
+}
+
+func isSet(values map[string]any, field ConfigField) bool {
+	for _, key := range append([]string{field.Name}, field.Aliases...) {
+		if v, ok := values[key]; ok && !isUnset(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func isUnset(v any) bool {
+	if v == nil {
+		return true
+	}
+	if secret, ok := v.(Secret); ok {
+		v = secret.Value
+	}
+	s, ok := v.(string)
+	return ok && strings.TrimSpace(s) == ""
+}
+
+func isEmptySection(values map[string]any) bool {
+	for _, v := range values {
+		if !isUnset(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func describeValue(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "a map"
+	case []any:
+		return "a list"
+	case string:
+		return "a string"
+	case bool:
+		return "a bool"
+	case int, int64, float64:
+		return "a number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// suggest returns the known name closest to key if it is a likely typo.
+func suggest(key string, names []string) string {
+	best, bestDist := "", 3
+	for _, name := range names {
+		if d := editDistance(key, name); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
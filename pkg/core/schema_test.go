@@ -0,0 +1,102 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testSchema = ConfigSchema{
+	Section: "notifier",
+	Fields: []ConfigField{
+		{Name: "url", Type: ConfigString, Required: true},
+		{Name: "token", Aliases: []string{"api_token"}, Type: ConfigString, Secret: true},
+		{Name: "enabled", Type: ConfigBool},
+		{Name: "retries", Type: ConfigInt},
+		{Name: "timeout", Type: ConfigDuration},
+		{Name: "subscribe", Type: ConfigCommaList},
+		{Name: "headers", Type: ConfigStringList},
+		{Name: "level", Type: ConfigString, Enum: []string{"info", "error"}},
+		{Name: "targets", Type: ConfigObjectList, Fields: []ConfigField{
+			{Name: "name", Type: ConfigString, Required: true},
+		}},
+	},
+}
+
+func TestValidateConfig_Valid(t *testing.T) {
+	cfg := map[string]map[string]any{
+		"notifier": {
+			"url":       "https://example.com",
+			"api_token": "abc",
+			"enabled":   "true", // env values are strings
+			"retries":   "3",
+			"timeout":   "30s",
+			"subscribe": "notify_*,deploy_*",
+			"headers":   []any{"X-A"},
+			"level":     "info",
+			"targets":   []any{map[string]any{"name": "a"}},
+		},
+		"other": {"anything": 1},
+	}
+	assert.Empty(t, ValidateConfig(cfg, []ConfigSchema{testSchema}))
+}
+
+func TestValidateConfig_Problems(t *testing.T) {
+	cfg := map[string]map[string]any{
+		"notifier": {
+			"subscibe": []any{"notify_*"},
+			"enabled":  "yes please",
+			"retries":  1.5,
+			"timeout":  "5 minutes",
+			"headers":  "X-A",
+			"level":    "debug",
+			"targets":  []any{map[string]any{"nme": "a"}, "b"},
+		},
+	}
+	var got []string
+	for _, p := range ValidateConfig(cfg, []ConfigSchema{testSchema}) {
+		got = append(got, p.String())
+	}
+	assert.Equal(t, []string{
+		`notifier.enabled: expected a bool, got "yes please"`,
+		`notifier.headers: expected a list, got a string`,
+		`notifier.level: must be one of info, error, got "debug"`,
+		`notifier.retries: expected an integer, got 1.5`,
+		`notifier.subscibe: unknown key (did you mean "subscribe"?)`,
+		`notifier.targets[0].name: required key is not set`,
+		`notifier.targets[0].nme: unknown key (did you mean "name"?)`,
+		`notifier.targets[1]: expected a map, got a string`,
+		`notifier.timeout: expected a duration like 30s or 5m, got "5 minutes"`,
+		`notifier.url: required key is not set`,
+	}, got)
+}
+
+func TestValidateConfig_EmptySection(t *testing.T) {
+	// Sections filled with empty env defaults are not in use, so required
+	// keys are not enforced and unknown empty keys are ignored.
+	cfg := map[string]map[string]any{"notifier": {"url": "", "legacy": ""}}
+	assert.Empty(t, ValidateConfig(cfg, []ConfigSchema{testSchema}))
+}
+
+func TestValidateConfig_MergesSchemasPerSection(t *testing.T) {
+	cfg := map[string]map[string]any{"core": {"plugins_dir": "plugins", "token": "abc"}}
+	assert.Len(t, ValidateConfig(cfg, []ConfigSchema{CoreConfigSchema()}), 1, "token is unknown to core alone")
+
+	reader := ConfigSchema{Section: "core", Fields: []ConfigField{{Name: "token", Type: ConfigString}}}
+	assert.Empty(t, ValidateConfig(cfg, []ConfigSchema{CoreConfigSchema(), reader}))
+}
+
+func TestRedactConfig(t *testing.T) {
+	cfg := map[string]map[string]any{
+		"notifier": {"url": "https://example.com", "api_token": "abc", "token": ""},
+		"other":    {"password": "hunter2", "user": "me"},
+	}
+	redacted := RedactConfig(cfg, []ConfigSchema{testSchema})
+
+	assert.Equal(t, "REDACTED", redacted["notifier"]["api_token"])
+	assert.Equal(t, "", redacted["notifier"]["token"])
+	assert.Equal(t, "https://example.com", redacted["notifier"]["url"])
+	assert.Equal(t, "REDACTED", redacted["other"]["password"])
+	assert.Equal(t, "me", redacted["other"]["user"])
+	assert.Equal(t, "abc", cfg["notifier"]["api_token"], "input must not be modified")
+}
@@ -1,6 +1,10 @@
 package core
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+)
 
 // Secret represents sensitive values that should be redacted in UI/API output.
 type Secret struct {
@@ -29,3 +33,94 @@ func (s Secret) MarshalJSON() ([]byte, error) {
 func (s Secret) String() string {
 	return s.Redacted()
 }
+
+// MarshalYAML ensures secrets are never serialized in cleartext.
+func (s Secret) MarshalYAML() (any, error) {
+	return s.Redacted(), nil
+}
+
+// UnmarshalYAML decodes a plain string, so config structs can declare
+// Secret fields.
+func (s *Secret) UnmarshalYAML(node *yaml.Node) error {
+	return node.Decode(&s.Value)
+}
+
+// UnwrapSecrets returns a deep copy of v in which every Secret is replaced by
+// its value. Config values resolved from ${...} references are Secrets; use
+// this before handing config to code that expects plain values.
+func UnwrapSecrets(v any) any {
+	switch t := v.(type) {
+	case Secret:
+		return t.Value
+	case *Secret:
+		if t == nil {
+			return ""
+		}
+		return t.Value
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, item := range t {
+			out[k] = UnwrapSecrets(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, item := range t {
+			out[i] = UnwrapSecrets(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// UnwrapConfig applies UnwrapSecrets to every section of cfg.
+func UnwrapConfig(cfg map[string]map[string]any) map[string]map[string]any {
+	out := make(map[string]map[string]any, len(cfg))
+	for section, values := range cfg {
+		out[section], _ = UnwrapSecrets(values).(map[string]any)
+	}
+	return out
+}
+
+// collectSecretValues adds the non-empty values of all Secrets in v to out.
+func collectSecretValues(v any, out map[string]bool) {
+	switch t := v.(type) {
+	case Secret:
+		if t.Value != "" {
+			out[t.Value] = true
+		}
+	case map[string]any:
+		for _, item := range t {
+			collectSecretValues(item, out)
+		}
+	case []any:
+		for _, item := range t {
+			collectSecretValues(item, out)
+		}
+	}
+}
+
+// redactStrings replaces every string in a decoded JSON value that is one of
+// secrets.
+func redactStrings(v any, secrets map[string]bool) any {
+	switch t := v.(type) {
+	case string:
+		if secrets[t] {
+			return NewSecret(t).Redacted()
+		}
+		return t
+	case map[string]any:
+		for k, item := range t {
+			t[k] = redactStrings(item, secrets)
+		}
+		return t
+	case []any:
+		for i, item := range t {
+			t[i] = redactStrings(item, secrets)
+		}
+		return t
+	default:
+		return v
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
 func TestSecretMarshalJSON(t *testing.T) {
@@ -18,3 +19,35 @@ func TestSecretMarshalJSON(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "\"\"", string(data))
 }
+
+func TestSecretYAML(t *testing.T) {
+	data, err := yaml.Marshal(map[string]any{"token": NewSecret("supersecret")})
+	assert.NoError(t, err)
+	assert.Equal(t, "token: REDACTED\n", string(data))
+
+	var out struct {
+		Token Secret `yaml:"token"`
+		User  string `yaml:"user"`
+	}
+	err = DecodeConfigSection(map[string]any{"token": NewSecret("supersecret"), "user": NewSecret("me")}, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, "supersecret", out.Token.Value)
+	assert.Equal(t, "me", out.User)
+}
+
+func TestUnwrapConfig(t *testing.T) {
+	cfg := map[string]map[string]any{
+		"core": {
+			"token": NewSecret("abc"),
+			"nested": map[string]any{
+				"list": []any{NewSecret("x"), "y"},
+			},
+			"port": 8080,
+		},
+	}
+	out := UnwrapConfig(cfg)
+	assert.Equal(t, "abc", out["core"]["token"])
+	assert.Equal(t, []any{"x", "y"}, out["core"]["nested"].(map[string]any)["list"])
+	assert.Equal(t, 8080, out["core"]["port"])
+	assert.IsType(t, Secret{}, cfg["core"]["token"], "input must not be modified")
+}
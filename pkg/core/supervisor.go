@@ -0,0 +1,269 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// ModuleState is the lifecycle state of a module as tracked by the manager.
+type ModuleState string
+
+const (
+	StateInitializing ModuleState = "initializing"
+	StateRunning      ModuleState = "running"
+	StateFailed       ModuleState = "failed"
+	StateStopped      ModuleState = "stopped"
+)
+
+// ModuleLifecycle is a snapshot of a module's supervised lifecycle.
+// LastError is the error of the current state and is cleared once the module
+// runs again; LastFailure keeps the most recent error across restarts.
+type ModuleLifecycle struct {
+	State       ModuleState `json:"state"`
+	LastError   string      `json:"last_error,omitempty"`
+	LastFailure string      `json:"last_failure,omitempty"`
+	Restarts    int         `json:"restarts,omitempty"`
+	Since       time.Time   `json:"since"`
+}
+
+// RestartMode controls whether the supervisor restarts a failed module.
+type RestartMode string
+
+const (
+	RestartNever     RestartMode = "never"
+	RestartOnFailure RestartMode = "on-failure"
+)
+
+// RestartPolicy configures how a failed module is restarted. A module fails
+// when Start returns an error or panics; it is restarted by calling Start
+// again after an exponential backoff.
+type RestartPolicy struct {
+	Mode RestartMode
+	// MaxRestarts is the number of consecutive restarts before the module is
+	// left failed. Zero means unlimited.
+	MaxRestarts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultRestartPolicy is used when core.restart_policy is not configured.
+var DefaultRestartPolicy = RestartPolicy{
+	Mode:        RestartOnFailure,
+	MaxRestarts: 5,
+	Backoff:     time.Second,
+	MaxBackoff:  time.Minute,
+}
+
+// delay returns the backoff before the given restart attempt (1-based).
+func (p RestartPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// restartPolicy returns the policy for a module. `core.restart_policy` sets
+// the default and `core.restart_policies.<name>` overrides individual keys
+// for one module:
+//
+//	core:
+//	  restart_policy:
+//	    mode: on-failure
+//	    max_restarts: 5
+//	    backoff: 1s
+//	    max_backoff: 1m
+//	  restart_policies:
+//	    reconciler:
+//	      mode: never
+func (m *ModuleManager) restartPolicy(name string) RestartPolicy {
+	policy := DefaultRestartPolicy
+	coreCfg := m.GetConfig()["core"]
+	applyRestartPolicy(&policy, coreCfg["restart_policy"])
+	if overrides, ok := coreCfg["restart_policies"].(map[string]any); ok {
+		applyRestartPolicy(&policy, overrides[name])
+	}
+	return policy
+}
+
+func applyRestartPolicy(policy *RestartPolicy, raw any) {
+	values, ok := raw.(map[string]any)
+	if !ok {
+		return
+	}
+	if v, ok := values["mode"]; ok {
+		policy.Mode = RestartMode(strings.ToLower(configString(v)))
+	}
+	if v, ok := values["max_restarts"]; ok {
+		var n int
+		if _, err := fmt.Sscan(configString(v), &n); err == nil {
+			policy.MaxRestarts = n
+		}
+	}
+	if d, ok := configDuration(values["backoff"]); ok {
+		policy.Backoff = d
+	}
+	if d, ok := configDuration(values["max_backoff"]); ok {
+		policy.MaxBackoff = d
+	}
+}
+
+func configDuration(v any) (time.Duration, bool) {
+	switch t := UnwrapSecrets(v).(type) {
+	case nil:
+		return 0, false
+	case time.Duration:
+		return t, true
+	case int:
+		return time.Duration(t) * time.Second, true
+	case float64:
+		return time.Duration(t * float64(time.Second)), true
+	default:
+		d, err := time.ParseDuration(strings.TrimSpace(fmt.Sprint(t)))
+		return d, err == nil
+	}
+}
+
+// Lifecycle returns the supervised lifecycle of the named module.
+func (m *ModuleManager) Lifecycle(name string) (ModuleLifecycle, bool) {
+	m.lifecycleMu.RLock()
+	defer m.lifecycleMu.RUnlock()
+	lc, ok := m.lifecycle[name]
+	if !ok {
+		return ModuleLifecycle{}, false
+	}
+	return *lc, true
+}
+
+func (m *ModuleManager) setState(name string, state ModuleState, err error) {
+	m.lifecycleMu.Lock()
+	defer m.lifecycleMu.Unlock()
+	lc, ok := m.lifecycle[name]
+	if !ok {
+		lc = &ModuleLifecycle{}
+		m.lifecycle[name] = lc
+	}
+	lc.State = state
+	lc.Since = time.Now()
+	switch {
+	case err != nil:
+		lc.LastError = err.Error()
+		lc.LastFailure = lc.LastError
+	case state == StateRunning:
+		lc.LastError = ""
+	}
+}
+
+func (m *ModuleManager) countRestart(name string) {
+	m.lifecycleMu.Lock()
+	defer m.lifecycleMu.Unlock()
+	if lc, ok := m.lifecycle[name]; ok {
+		lc.Restarts++
+	}
+}
+
+// safeCall runs a module lifecycle method, converting a panic into an error.
+func (m *ModuleManager) safeCall(mod Module, phase string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.logger.Error("Module panicked", "module", mod.Name(), "phase", phase, "panic", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("panic during %s: %v", phase, r)
+		}
+	}()
+	return fn()
+}
+
+// supervise starts a module and restarts it according to its policy until
+// it starts cleanly, the policy gives up, or the manager is stopped. A
+// process plugin is supervised for as long as its process runs: if it exits
+// on its own, it is relaunched and initialized again before it is restarted.
+func (m *ModuleManager) supervise(ctx context.Context, mod Module) {
+	name := mod.Name()
+	policy := m.restartPolicy(name)
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			delay := policy.delay(attempt)
+			m.logger.Info("Restarting module", "module", name, "attempt", attempt, "backoff", delay)
+			select {
+			case <-time.After(delay):
+			case <-m.stopCh:
+				return
+			case <-ctx.Done():
+				return
+			}
+			m.countRestart(name)
+		}
+
+		err := m.relaunchProcess(ctx, mod)
+		if err == nil {
+			m.logger.Info("Starting module", "module", name)
+			m.setState(name, StateRunning, nil)
+			err = m.safeCall(mod, "start", func() error { return mod.Start(ctx) })
+		}
+		if err == nil {
+			err = m.waitProcess(ctx, mod)
+		}
+		if err == nil {
+			return
+		}
+
+		select {
+		case <-m.stopCh:
+			return
+		default:
+		}
+		m.setState(name, StateFailed, err)
+		m.logger.Error("Module failed", "module", name, "error", err)
+
+		if policy.Mode != RestartOnFailure {
+			return
+		}
+		if policy.MaxRestarts > 0 && attempt >= policy.MaxRestarts {
+			m.logger.Error("Module exceeded restart limit, giving up", "module", name, "max_restarts", policy.MaxRestarts)
+			return
+		}
+	}
+}
+
+// relaunchProcess initializes a process plugin whose process has exited
+// again, which relaunches it (describe) and sends init. The subscriptions of
+// the old process are dropped first.
+func (m *ModuleManager) relaunchProcess(ctx context.Context, mod Module) error {
+	pp, ok := mod.(*processPlugin)
+	if !ok || !pp.exited() {
+		return nil
+	}
+	m.dropSubscriptions(mod.Name())
+	return m.initModule(ctx, mod)
+}
+
+// waitProcess blocks while a started process plugin's process runs. It
+// returns the exit error if the process exits on its own, and nil if it is
+// stopped, the manager stops or mod is not a process plugin.
+func (m *ModuleManager) waitProcess(ctx context.Context, mod Module) error {
+	pp, ok := mod.(*processPlugin)
+	if !ok {
+		return nil
+	}
+	proc := pp.current()
+	select {
+	case <-proc.exited:
+	case <-m.stopCh:
+		return nil
+	case <-ctx.Done():
+		return nil
+	}
+	if proc.stopped.Load() {
+		return nil
+	}
+	if proc.exitErr != nil {
+		return proc.exitErr
+	}
+	return fmt.Errorf("process exited")
+}
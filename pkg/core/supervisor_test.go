@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyPlugin fails its first `failures` Start calls, alternating between
+// returning an error and panicking.
+type flakyPlugin struct {
+	testPlugin
+	failures int32
+	starts   atomic.Int32
+}
+
+func (p *flakyPlugin) Start(ctx context.Context) error {
+	n := p.starts.Add(1)
+	if n > p.failures {
+		return nil
+	}
+	if n%2 == 0 {
+		panic("start exploded")
+	}
+	return errors.New("start failed")
+}
+
+func newTestManager(t *testing.T, policy map[string]any) *ModuleManager {
+	t.Helper()
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mgr.SetConfig(map[string]map[string]any{"core": {"restart_policy": policy}})
+	return mgr
+}
+
+func TestSupervisor_RestartsUntilRunning(t *testing.T) {
+	mgr := newTestManager(t, map[string]any{"mode": "on-failure", "backoff": "1ms", "max_restarts": 5})
+	plug := &flakyPlugin{testPlugin: testPlugin{name: "flaky"}, failures: 2}
+	mgr.Register(plug)
+
+	ctx := context.Background()
+	require.NoError(t, mgr.Init(ctx))
+	mgr.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		lc, _ := mgr.Lifecycle("flaky")
+		return lc.State == StateRunning && plug.starts.Load() == 3
+	}, 2*time.Second, 5*time.Millisecond)
+
+	lc, _ := mgr.Lifecycle("flaky")
+	assert.Equal(t, 2, lc.Restarts)
+	assert.Empty(t, lc.LastError, "cleared once the module runs again")
+	assert.Contains(t, lc.LastFailure, "panic during start: start exploded")
+
+	mgr.Stop(ctx)
+	lc, _ = mgr.Lifecycle("flaky")
+	assert.Equal(t, StateStopped, lc.State)
+}
+
+func TestSupervisor_GivesUpAfterMaxRestarts(t *testing.T) {
+	mgr := newTestManager(t, map[string]any{"backoff": "1ms", "max_restarts": 2})
+	plug := &flakyPlugin{testPlugin: testPlugin{name: "flaky"}, failures: 100}
+	mgr.Register(plug)
+
+	ctx := context.Background()
+	require.NoError(t, mgr.Init(ctx))
+	mgr.Start(ctx)
+
+	require.Eventually(t, func() bool { return plug.starts.Load() == 3 }, 2*time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(3), plug.starts.Load())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/plugins/flaky", nil)
+	rr := httptest.NewRecorder()
+	mgr.GetMuxServer().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var info pluginInfo
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &info))
+	assert.Equal(t, StatusUnhealthy, info.Status)
+	require.NotNil(t, info.Lifecycle)
+	assert.Equal(t, StateFailed, info.Lifecycle.State)
+	assert.Equal(t, 2, info.Lifecycle.Restarts)
+	assert.Equal(t, "start failed", info.Lifecycle.LastError)
+}
+
+func TestSupervisor_PerPluginOverride(t *testing.T) {
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mgr.SetConfig(map[string]map[string]any{"core": {
+		"restart_policy":   map[string]any{"backoff": "1ms"},
+		"restart_policies": map[string]any{"flaky": map[string]any{"mode": "never"}},
+	}})
+	plug := &flakyPlugin{testPlugin: testPlugin{name: "flaky"}, failures: 1}
+	mgr.Register(plug)
+
+	ctx := context.Background()
+	require.NoError(t, mgr.Init(ctx))
+	mgr.Start(ctx)
+
+	require.Eventually(t, func() bool {
+		lc, _ := mgr.Lifecycle("flaky")
+		return lc.State == StateFailed
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(1), plug.starts.Load())
+}
+
+func TestRestartPolicy_Delay(t *testing.T) {
+	p := RestartPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, p.delay(1))
+	assert.Equal(t, 2*time.Second, p.delay(2))
+	assert.Equal(t, 4*time.Second, p.delay(3))
+	assert.Equal(t, 5*time.Second, p.delay(4))
+}
+
+func TestStartModules_SkipsAndReportsFailures(t *testing.T) {
+	mgr := newTestManager(t, nil)
+	ok := &MockModule{name: "ok"}
+	skipped := &MockModule{name: "skipped"}
+	failing := &flakyPlugin{testPlugin: testPlugin{name: "failing"}, failures: 1}
+	mgr.Register(ok)
+	mgr.Register(skipped)
+	mgr.Register(failing)
+
+	ctx := context.Background()
+	require.NoError(t, mgr.Init(ctx))
+	err := mgr.StartModules(ctx, "skipped")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "start failing")
+
+	assert.True(t, ok.startCalled)
+	assert.False(t, skipped.startCalled)
+	assert.Equal(t, int32(1), failing.starts.Load(), "one-shot start must not restart")
+	lc, _ := mgr.Lifecycle("failing")
+	assert.Equal(t, StateFailed, lc.State)
+}
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// signatureSuffix is appended to a plugin's file name to find its detached
+// ed25519 signature.
+const signatureSuffix = ".sig"
+
+// RejectedPlugin is a plugin file that LoadPlugins refused to open because it
+// failed verification.
+type RejectedPlugin struct {
+	Path   string    `json:"path"`
+	SHA256 string    `json:"sha256,omitempty"`
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+// pluginVerifier checks plugin files against `core.plugin_verification`
+// before they are opened or executed. A file is accepted if its SHA-256
+// digest is allowlisted or if it has a valid signature by the public key.
+type pluginVerifier struct {
+	digests   map[string]bool
+	publicKey ed25519.PublicKey
+}
+
+// pluginVerifier returns the verifier configured by
+// `core.plugin_verification`, or nil if verification is disabled:
+//
+//	core:
+//	  plugin_verification:
+//	    sha256:                 # allowlisted digests (sha256sum output works)
+//	      - 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+//	    public_key: |           # ed25519, PEM or base64; or public_key_file
+//	      -----BEGIN PUBLIC KEY-----
+//	      ...
+func (m *ModuleManager) pluginVerifier() (*pluginVerifier, error) {
+	raw, ok := m.GetConfig()["core"]["plugin_verification"].(map[string]any)
+	if !ok {
+		return nil, nil
+	}
+
+	v := &pluginVerifier{digests: map[string]bool{}}
+	for _, entry := range configStringList(raw["sha256"]) {
+		digest := strings.ToLower(strings.Fields(entry)[0])
+		if decoded, err := hex.DecodeString(digest); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("plugin_verification: invalid sha256 digest %q", entry)
+		}
+		v.digests[digest] = true
+	}
+
+	keyText := configString(raw["public_key"])
+	if path := configString(raw["public_key_file"]); keyText == "" && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("plugin_verification: read public key: %w", err)
+		}
+		keyText = strings.TrimSpace(string(data))
+	}
+	if keyText != "" {
+		key, err := parseEd25519PublicKey(keyText)
+		if err != nil {
+			return nil, fmt.Errorf("plugin_verification: %w", err)
+		}
+		v.publicKey = key
+	}
+
+	if len(v.digests) == 0 && v.publicKey == nil {
+		return nil, nil
+	}
+	return v, nil
+}
+
+// verify reads the plugin file and returns its hex SHA-256 digest, or an
+// error explaining why the file is not trusted.
+func (v *pluginVerifier) verify(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read plugin: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	if v.digests[digest] {
+		return digest, nil
+	}
+	if v.publicKey == nil {
+		return digest, fmt.Errorf("sha256 %s is not in the allowlist", digest)
+	}
+
+	sig, err := readSignature(path + signatureSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return digest, fmt.Errorf("sha256 %s is not in the allowlist and %s does not exist", digest, path+signatureSuffix)
+	}
+	if err != nil {
+		return digest, err
+	}
+	if !ed25519.Verify(v.publicKey, data, sig) {
+		return digest, fmt.Errorf("signature %s does not match the plugin", path+signatureSuffix)
+	}
+	return digest, nil
+}
+
+// readSignature accepts a raw 64-byte signature (as written by
+// `openssl pkeyutl -sign -rawin`) or its base64 encoding.
+func readSignature(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == ed25519.SignatureSize {
+		return data, nil
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("signature %s is not a raw or base64 ed25519 signature", path)
+	}
+	return sig, nil
+}
+
+func parseEd25519PublicKey(text string) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode([]byte(text)); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key: %w", err)
+		}
+		edKey, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key is %T, want ed25519", key)
+		}
+		return edKey, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(text)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be PEM or base64 of %d bytes", ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+func configString(v any) string {
+	if v == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(UnwrapSecrets(v)))
+}
+
+func (m *ModuleManager) rejectPlugin(path, digest string, err error) {
+	m.logger.Error("Plugin failed verification, refusing to load", "path", path, "sha256", digest, "error", err)
+	m.modulesMu.Lock()
+	defer m.modulesMu.Unlock()
+	m.rejected[path] = RejectedPlugin{Path: path, SHA256: digest, Reason: err.Error(), Time: time.Now()}
+}
+
+// RejectedPlugins returns the plugin files that failed verification, sorted
+// by path. A file is removed from the list once it loads.
+func (m *ModuleManager) RejectedPlugins() []RejectedPlugin {
+	m.modulesMu.RLock()
+	defer m.modulesMu.RUnlock()
+	out := make([]RejectedPlugin, 0, len(m.rejected))
+	for _, r := range m.rejected {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+func (m *ModuleManager) handleRejectedPlugins(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, m.RejectedPlugins())
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newVerifyManager(t *testing.T, verification map[string]any) *ModuleManager {
+	t.Helper()
+	mgr := NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mgr.SetConfig(map[string]map[string]any{"core": {"plugin_verification": verification}})
+	return mgr
+}
+
+func writePluginFile(t *testing.T, dir, name, content string) (string, string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	sum := sha256.Sum256([]byte(content))
+	return path, hex.EncodeToString(sum[:])
+}
+
+func TestPluginVerifier_Allowlist(t *testing.T) {
+	dir := t.TempDir()
+	good, goodDigest := writePluginFile(t, dir, "good.so", "trusted")
+	bad, badDigest := writePluginFile(t, dir, "bad.so", "untrusted")
+
+	mgr := newVerifyManager(t, map[string]any{"sha256": []any{goodDigest + "  good.so"}})
+	v, err := mgr.pluginVerifier()
+	require.NoError(t, err)
+	require.NotNil(t, v)
+
+	digest, err := v.verify(good)
+	require.NoError(t, err)
+	assert.Equal(t, goodDigest, digest)
+
+	digest, err = v.verify(bad)
+	assert.EqualError(t, err, "sha256 "+badDigest+" is not in the allowlist")
+	assert.Equal(t, badDigest, digest)
+}
+
+func TestPluginVerifier_Signature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	pemKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	dir := t.TempDir()
+	raw, _ := writePluginFile(t, dir, "raw.so", "raw plugin")
+	require.NoError(t, os.WriteFile(raw+".sig", ed25519.Sign(priv, []byte("raw plugin")), 0o644))
+	encoded, _ := writePluginFile(t, dir, "encoded.so", "encoded plugin")
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte("encoded plugin")))
+	require.NoError(t, os.WriteFile(encoded+".sig", []byte(sig+"\n"), 0o644))
+	tampered, _ := writePluginFile(t, dir, "tampered.so", "tampered plugin")
+	require.NoError(t, os.WriteFile(tampered+".sig", ed25519.Sign(priv, []byte("original plugin")), 0o644))
+	unsigned, _ := writePluginFile(t, dir, "unsigned.so", "unsigned plugin")
+
+	for _, key := range []string{pemKey, base64.StdEncoding.EncodeToString(pub)} {
+		mgr := newVerifyManager(t, map[string]any{"public_key": key})
+		v, err := mgr.pluginVerifier()
+		require.NoError(t, err)
+
+		_, err = v.verify(raw)
+		assert.NoError(t, err)
+		_, err = v.verify(encoded)
+		assert.NoError(t, err)
+		_, err = v.verify(tampered)
+		assert.ErrorContains(t, err, "does not match the plugin")
+		_, err = v.verify(unsigned)
+		assert.ErrorContains(t, err, "unsigned.so.sig does not exist")
+	}
+}
+
+func TestPluginVerifier_Config(t *testing.T) {
+	v, err := newVerifyManager(t, nil).pluginVerifier()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	v, err = newVerifyManager(t, map[string]any{"sha256": "", "public_key_file": ""}).pluginVerifier()
+	assert.NoError(t, err)
+	assert.Nil(t, v, "empty settings leave verification disabled")
+
+	_, err = newVerifyManager(t, map[string]any{"sha256": "abc"}).pluginVerifier()
+	assert.EqualError(t, err, `plugin_verification: invalid sha256 digest "abc"`)
+
+	_, err = newVerifyManager(t, map[string]any{"public_key": "not a key"}).pluginVerifier()
+	assert.ErrorContains(t, err, "public key must be PEM or base64")
+}
+
+func TestLoadPlugins_RefusesUnverified(t *testing.T) {
+	dir := t.TempDir()
+	writePluginFile(t, dir, "evil.so", "evil")
+	_, digest := writePluginFile(t, dir, "evil.so.sig", "not a plugin")
+
+	mgr := newVerifyManager(t, map[string]any{"sha256": digest})
+	require.NoError(t, mgr.LoadPlugins(dir))
+	assert.Empty(t, mgr.ListPlugins())
+
+	rejected := mgr.RejectedPlugins()
+	require.Len(t, rejected, 1)
+	assert.Equal(t, filepath.Join(dir, "evil.so"), rejected[0].Path)
+	assert.Contains(t, rejected[0].Reason, "is not in the allowlist")
+
+	rr := httptest.NewRecorder()
+	mgr.mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/rejected_plugins", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var out []RejectedPlugin
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&out))
+	assert.Equal(t, rejected[0].SHA256, out[0].SHA256)
+}
+
+func TestLoadPlugins_InvalidVerificationConfig(t *testing.T) {
+	mgr := newVerifyManager(t, map[string]any{"sha256": "xyz"})
+	assert.ErrorContains(t, mgr.LoadPlugins(t.TempDir()), "invalid sha256 digest")
+}
@@ -0,0 +1,294 @@
+// Package metrics is a small registry of counters, gauges and histograms
+// exposed in the Prometheus text format.
+package metrics
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets are the default histogram buckets, in seconds, for latencies
+// from milliseconds to about a minute.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+var (
+	nameRe  = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+var discardLogger = slog.New(slog.DiscardHandler)
+
+type kind string
+
+const (
+	kindCounter   kind = "counter"
+	kindGauge     kind = "gauge"
+	kindHistogram kind = "histogram"
+)
+
+// Registry holds metric families. It is safe for concurrent use.
+//
+// Registering a family again with the same kind, labels and buckets returns
+// the existing one, so plugins can register their metrics in Init and still
+// be restarted or reloaded. An invalid or conflicting registration is logged
+// and returns a working metric that is not exposed, as does registering on a
+// nil Registry.
+type Registry struct {
+	logger *slog.Logger
+
+	mu       sync.RWMutex
+	families map[string]*family
+}
+
+// NewRegistry returns an empty registry; a nil logger discards registration
+// errors.
+func NewRegistry(logger *slog.Logger) *Registry {
+	if logger == nil {
+		logger = discardLogger
+	}
+	return &Registry{logger: logger, families: map[string]*family{}}
+}
+
+type family struct {
+	logger  *slog.Logger
+	name    string
+	help    string
+	kind    kind
+	labels  []string
+	buckets []float64
+	fn      func() float64 // GaugeFunc
+
+	mu     sync.RWMutex
+	series map[string]*series // by joined label values
+}
+
+type series struct {
+	values []string
+	value  atomicFloat // counter and gauge
+
+	mu      sync.Mutex // histogram
+	counts  []uint64   // per bucket, not cumulative
+	sum     float64
+	samples uint64
+}
+
+// Counter registers a counter, a value that only goes up.
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{r.register(name, help, kindCounter, labels, nil, nil)}
+}
+
+// Gauge registers a gauge, a value that goes up and down.
+func (r *Registry) Gauge(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{r.register(name, help, kindGauge, labels, nil, nil)}
+}
+
+// GaugeFunc registers a gauge without labels whose value is fn, called on
+// every scrape. Registering it again replaces fn.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64) {
+	r.register(name, help, kindGauge, nil, nil, fn)
+}
+
+// Histogram registers a histogram with the given upper bucket bounds;
+// DefBuckets if nil.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	buckets = slices.Clone(buckets)
+	sort.Float64s(buckets)
+	buckets = slices.Compact(buckets)
+	return &HistogramVec{r.register(name, help, kindHistogram, labels, buckets, nil)}
+}
+
+func (r *Registry) register(name, help string, k kind, labels []string, buckets []float64, fn func() float64) *family {
+	logger := discardLogger
+	if r != nil {
+		logger = r.logger
+	}
+	f := &family{logger: logger, name: name, help: help, kind: k, labels: slices.Clone(labels), buckets: buckets, fn: fn, series: map[string]*series{}}
+	if err := f.validate(); err != nil {
+		logger.Error("Invalid metric", "metric", name, "error", err)
+		return f
+	}
+	if r == nil {
+		return f
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, ok := r.families[name]
+	if !ok {
+		r.families[name] = f
+		return f
+	}
+	if existing.kind != k || !slices.Equal(existing.labels, f.labels) || !slices.Equal(existing.buckets, buckets) ||
+		(existing.fn == nil) != (fn == nil) {
+		r.logger.Error("Metric already registered differently", "metric", name, "kind", existing.kind, "labels", existing.labels)
+		return f
+	}
+	if fn != nil {
+		existing.mu.Lock()
+		existing.fn = fn
+		existing.mu.Unlock()
+	}
+	return existing
+}
+
+func (f *family) validate() error {
+	if !nameRe.MatchString(f.name) {
+		return fmt.Errorf("invalid metric name %q", f.name)
+	}
+	seen := map[string]bool{}
+	for _, label := range f.labels {
+		if !labelRe.MatchString(label) || strings.HasPrefix(label, "__") {
+			return fmt.Errorf("invalid label name %q", label)
+		}
+		if label == "le" && f.kind == kindHistogram {
+			return fmt.Errorf("label %q is reserved for histograms", label)
+		}
+		if seen[label] {
+			return fmt.Errorf("duplicate label %q", label)
+		}
+		seen[label] = true
+	}
+	return nil
+}
+
+// with returns the series for the label values, created on first use. A
+// wrong number of values is logged and gets a series that is not exposed.
+func (f *family) with(values []string) *series {
+	if len(values) != len(f.labels) {
+		f.logger.Error("Wrong number of metric label values", "metric", f.name, "labels", f.labels, "values", values)
+		return f.newSeries(values)
+	}
+	key := strings.Join(values, "\xff")
+	f.mu.RLock()
+	s, ok := f.series[key]
+	f.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.series[key]; ok {
+		return s
+	}
+	s = f.newSeries(values)
+	f.series[key] = s
+	return s
+}
+
+// remove drops the series of the label values.
+func (f *family) remove(values []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.series, strings.Join(values, "\xff"))
+}
+
+func (f *family) newSeries(values []string) *series {
+	s := &series{values: slices.Clone(values)}
+	if f.kind == kindHistogram {
+		s.counts = make([]uint64, len(f.buckets))
+	}
+	return s
+}
+
+// CounterVec is a counter family, partitioned by its labels.
+type CounterVec struct{ f *family }
+
+// With returns the counter for the label values, in registration order.
+func (v *CounterVec) With(values ...string) *Counter {
+	return &Counter{v.f.with(values)}
+}
+
+// Counter is one counter series.
+type Counter struct{ s *series }
+
+// Inc adds 1.
+func (c *Counter) Inc() { c.s.value.add(1) }
+
+// Add adds delta; negative deltas are ignored.
+func (c *Counter) Add(delta float64) {
+	if delta > 0 {
+		c.s.value.add(delta)
+	}
+}
+
+// GaugeVec is a gauge family, partitioned by its labels.
+type GaugeVec struct{ f *family }
+
+// With returns the gauge for the label values, in registration order.
+func (v *GaugeVec) With(values ...string) *Gauge {
+	return &Gauge{v.f.with(values)}
+}
+
+// Delete removes the gauge for the label values, e.g. of an object that no
+// longer exists, so that it is no longer exposed.
+func (v *GaugeVec) Delete(values ...string) {
+	v.f.remove(values)
+}
+
+// Gauge is one gauge series.
+type Gauge struct{ s *series }
+
+// Set sets the value.
+func (g *Gauge) Set(value float64) { g.s.value.set(value) }
+
+// Inc adds 1.
+func (g *Gauge) Inc() { g.s.value.add(1) }
+
+// Dec subtracts 1.
+func (g *Gauge) Dec() { g.s.value.add(-1) }
+
+// Add adds delta, which may be negative.
+func (g *Gauge) Add(delta float64) { g.s.value.add(delta) }
+
+// HistogramVec is a histogram family, partitioned by its labels.
+type HistogramVec struct{ f *family }
+
+// With returns the histogram for the label values, in registration order.
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return &Histogram{s: v.f.with(values), buckets: v.f.buckets}
+}
+
+// Histogram is one histogram series.
+type Histogram struct {
+	s       *series
+	buckets []float64
+}
+
+// Observe records a sample, e.g. a duration in seconds.
+func (h *Histogram) Observe(value float64) {
+	i := sort.SearchFloat64s(h.buckets, value)
+	h.s.mu.Lock()
+	defer h.s.mu.Unlock()
+	if i < len(h.s.counts) {
+		h.s.counts[i]++
+	}
+	h.s.sum += value
+	h.s.samples++
+}
+
+// atomicFloat is a float64 updated atomically.
+type atomicFloat struct{ bits atomic.Uint64 }
+
+func (a *atomicFloat) load() float64 { return math.Float64frombits(a.bits.Load()) }
+
+func (a *atomicFloat) set(v float64) { a.bits.Store(math.Float64bits(v)) }
+
+func (a *atomicFloat) add(delta float64) {
+	for {
+		old := a.bits.Load()
+		if a.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
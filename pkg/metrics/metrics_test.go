@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func text(t *testing.T, registries ...*Registry) string {
+	t.Helper()
+	var b strings.Builder
+	require.NoError(t, WriteText(&b, registries...))
+	return b.String()
+}
+
+func TestCounterAndGauge(t *testing.T) {
+	r := NewRegistry(nil)
+	c := r.Counter("test_runs_total", "Runs.", "result")
+	c.With("ok").Inc()
+	c.With("ok").Add(2)
+	c.With("ok").Add(-5) // ignored
+	c.With("failed").Inc()
+
+	g := r.Gauge("test_in_flight", "In flight.")
+	g.With().Inc()
+	g.With().Inc()
+	g.With().Dec()
+
+	assert.Equal(t, `# HELP test_in_flight In flight.
+# TYPE test_in_flight gauge
+test_in_flight 1
+# HELP test_runs_total Runs.
+# TYPE test_runs_total counter
+test_runs_total{result="failed"} 1
+test_runs_total{result="ok"} 3
+`, text(t, r))
+}
+
+func TestGaugeDelete(t *testing.T) {
+	r := NewRegistry(nil)
+	g := r.Gauge("test_queue_depth", "Depth.", "queue")
+	g.With("a").Set(2)
+	g.With("b").Set(3)
+	g.Delete("a")
+	g.Delete("missing")
+
+	assert.Equal(t, `# HELP test_queue_depth Depth.
+# TYPE test_queue_depth gauge
+test_queue_depth{queue="b"} 3
+`, text(t, r))
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry(nil)
+	h := r.Histogram("test_duration_seconds", "Duration.", []float64{1, 0.1}, "stack")
+	h.With("web").Observe(0.05)
+	h.With("web").Observe(0.5)
+	h.With("web").Observe(3)
+
+	assert.Equal(t, `# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{stack="web",le="0.1"} 1
+test_duration_seconds_bucket{stack="web",le="1"} 2
+test_duration_seconds_bucket{stack="web",le="+Inf"} 3
+test_duration_seconds_sum{stack="web"} 3.55
+test_duration_seconds_count{stack="web"} 3
+`, text(t, r))
+}
+
+func TestReregistration(t *testing.T) {
+	r := NewRegistry(nil)
+	r.Counter("test_total", "", "a").With("x").Inc()
+	r.Counter("test_total", "", "a").With("x").Inc()
+	assert.Contains(t, text(t, r), `test_total{a="x"} 2`)
+
+	// Conflicting registrations and label mismatches work but are not exposed.
+	r.Gauge("test_total", "", "a").With("x").Set(10)
+	r.Counter("test_total", "", "b").With("x").Inc()
+	r.Counter("test_total", "", "a").With("x", "y").Inc()
+	r.Counter("bad-name", "").With().Inc()
+	var nilRegistry *Registry
+	nilRegistry.Counter("test_total", "", "a").With("x").Inc()
+	assert.Equal(t, "# TYPE test_total counter\ntest_total{a=\"x\"} 2\n", text(t, r))
+}
+
+func TestGaugeFuncAndEscaping(t *testing.T) {
+	r := NewRegistry(nil)
+	r.GaugeFunc("test_value", "A \\ help\nline", func() float64 { return 1 })
+	r.GaugeFunc("test_value", "A \\ help\nline", func() float64 { return 2 })
+	r.Counter("test_labels_total", "", "v").With("a\"b\\c\nd").Inc()
+
+	out := text(t, r)
+	assert.Contains(t, out, "# HELP test_value A \\\\ help\\nline\n# TYPE test_value gauge\ntest_value 2\n")
+	assert.Contains(t, out, `test_labels_total{v="a\"b\\c\nd"} 1`)
+}
+
+func TestHandlerMergesRegistries(t *testing.T) {
+	a, b := NewRegistry(nil), NewRegistry(nil)
+	a.Counter("test_a_total", "").With().Inc()
+	b.Counter("test_b_total", "").With().Add(2)
+	b.Counter("test_a_total", "").With().Add(5) // shadowed by a
+
+	rec := httptest.NewRecorder()
+	Handler(a, b).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "# TYPE test_a_total counter\ntest_a_total 1\n# TYPE test_b_total counter\ntest_b_total 2\n", rec.Body.String())
+}
+
+func TestConcurrentUpdates(t *testing.T) {
+	r := NewRegistry(nil)
+	c := r.Counter("test_total", "", "n")
+	h := r.Histogram("test_seconds", "", nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.With("x").Inc()
+				h.With().Observe(0.01)
+			}
+		}()
+	}
+	wg.Wait()
+	out := text(t, r)
+	assert.Contains(t, out, `test_total{n="x"} 8000`)
+	assert.Contains(t, out, "test_seconds_count 8000")
+}
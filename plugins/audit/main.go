@@ -1,25 +1,50 @@
-package main
+package audit
 
 import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 
 	"github.com/mywio/git-ops/pkg/core"
+	"github.com/mywio/git-ops/pkg/metrics"
 )
 
 type AuditPlugin struct {
-	logger         *slog.Logger
-	registry       core.PluginRegistry
+	logger   *slog.Logger
+	registry core.PluginRegistry
+	recorded *metrics.CounterVec
+	events   core.Subscription // set by Start
+
+	mu             sync.RWMutex // guards the fields below
 	store          AuditStore
+	storeKey       string // storage type and path the store was opened with
 	retentionCount int
 }
 
-// Plugin is the exported symbol for dynamic loading
-var Plugin = &AuditPlugin{}
+// Manifest of the audit plugin, which records every event.
+var Manifest = core.PluginManifest{
+	Name:         "audit",
+	Version:      core.Version,
+	APIVersion:   core.APIVersion,
+	Capabilities: []core.Capability{core.CapabilityAudit},
+}
+
+func init() {
+	core.RegisterBuiltin(Manifest.Name, New)
+}
+
+func New() core.Plugin {
+	return &AuditPlugin{}
+}
 
 func (p *AuditPlugin) Name() string {
-	return "audit"
+	return Manifest.Name
+}
+
+// Manifest implements core.ManifestProvider.
+func (p *AuditPlugin) Manifest() core.PluginManifest {
+	return Manifest
 }
 
 func (p *AuditPlugin) Description() string {
@@ -27,7 +52,7 @@ func (p *AuditPlugin) Description() string {
 }
 
 func (p *AuditPlugin) Capabilities() []core.Capability {
-	return []core.Capability{core.CapabilityAudit}
+	return Manifest.Capabilities
 }
 
 func (p *AuditPlugin) Status() core.ServiceStatus {
@@ -37,9 +62,33 @@ func (p *AuditPlugin) Status() core.ServiceStatus {
 func (p *AuditPlugin) Init(ctx context.Context, logger *slog.Logger, registry core.PluginRegistry) error {
 	p.logger = logger
 	p.registry = registry
+	p.recorded = registry.Metrics().Counter("gitops_audit_events_total",
+		"Events handled by the audit plugin, by result (saved or failed).", "result")
+	return p.applyConfig(registry.GetConfig())
+}
+
+// Reconfigure applies a changed audit section. The store is only reopened
+// when the storage type or database path changes; events recorded in a
+// memory store are lost in that case.
+func (p *AuditPlugin) Reconfigure(ctx context.Context, cfg map[string]map[string]any) error {
+	return p.applyConfig(cfg)
+}
 
-	config := registry.GetConfig()
+// ConfigSchema implements core.ConfigSchemaProvider.
+func (p *AuditPlugin) ConfigSchema() []core.ConfigSchema {
+	return []core.ConfigSchema{{
+		Section: Manifest.Name,
+		Fields: []core.ConfigField{
+			{Name: "storage", Type: core.ConfigString, Enum: []string{"memory", "sqlite"}, Default: "memory"},
+			{Name: "db_path", Type: core.ConfigString, Default: "data/audit.db", Description: "SQLite database path"},
+			{Name: "retention_count", Type: core.ConfigInt, Default: 1000, Description: "Number of events to keep"},
+		},
+	}}
+}
+
+func (p *AuditPlugin) applyConfig(config map[string]map[string]any) error {
 	auditCfg, ok := config["audit"]
+	auditCfg, _ = core.UnwrapSecrets(auditCfg).(map[string]any)
 
 	storageType := "memory"
 	dbPath := "data/audit.db"
@@ -52,49 +101,86 @@ func (p *AuditPlugin) Init(ctx context.Context, logger *slog.Logger, registry co
 		if d, ok := auditCfg["db_path"].(string); ok && d != "" {
 			dbPath = d
 		}
-		if r, ok := auditCfg["retention_count"].(int); ok {
-			retentionCount = r
-		} else if r, ok := auditCfg["retention_count"].(float64); ok {
-			retentionCount = int(r)
+		// A number from a ${...} reference arrives as a string.
+		if r, ok := auditCfg["retention_count"]; ok {
+			if _, err := fmt.Sscan(fmt.Sprint(r), &retentionCount); err != nil {
+				p.logger.Warn("Invalid audit.retention_count, keeping the default", "value", r, "default", 1000)
+				retentionCount = 1000
+			}
 		}
 	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	p.retentionCount = retentionCount
 
+	storeKey := storageType
+	if storageType == "sqlite" {
+		storeKey += ":" + dbPath
+	}
+	if p.store != nil && storeKey == p.storeKey {
+		return nil
+	}
+
+	var store AuditStore
 	if storageType == "sqlite" {
 		p.logger.Info("Initializing sqlite audit store", "db_path", dbPath)
 		s, err := newSQLiteStore(dbPath)
 		if err != nil {
 			return fmt.Errorf("failed to initialize sqlite store: %w", err)
 		}
-		p.store = s
+		store = s
 	} else {
 		p.logger.Info("Initializing memory audit store")
-		p.store = newMemoryStore()
+		store = newMemoryStore()
 	}
 
+	if p.store != nil {
+		if err := p.store.Close(); err != nil {
+			p.logger.Warn("Failed to close previous audit store", "error", err)
+		}
+	}
+	p.store = store
+	p.storeKey = storeKey
 	return nil
 }
 
 func (p *AuditPlugin) Start(ctx context.Context) error {
 	p.logger.Info("Starting audit plugin and subscribing to all events")
-	p.registry.Subscribe("*", p.handleEvent)
+	if p.events != nil {
+		p.events.Unsubscribe() // started again after a failure
+	}
+	p.events = p.registry.Subscribe("*", p.handleEvent)
 	return nil
 }
 
 func (p *AuditPlugin) Stop(ctx context.Context) error {
 	p.logger.Info("Stopping audit plugin")
-	if p.store != nil {
-		return p.store.Close()
+	if p.events != nil {
+		p.events.Unsubscribe()
+		p.events = nil
 	}
-	return nil
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.store == nil {
+		return nil
+	}
+	store := p.store
+	p.store = nil
+	return store.Close()
 }
 
 func (p *AuditPlugin) handleEvent(ctx context.Context, event core.InternalEvent) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	if p.store == nil {
 		return
 	}
 	if err := p.store.Save(event); err != nil {
+		p.recorded.With("failed").Inc()
 		p.logger.Error("Failed to save audit event", "error", err)
+	} else {
+		p.recorded.With("saved").Inc()
 	}
 
 	if p.retentionCount > 0 {
@@ -104,6 +190,22 @@ func (p *AuditPlugin) handleEvent(ctx context.Context, event core.InternalEvent)
 	}
 }
 
+// Actions implements core.ActionProvider.
+func (p *AuditPlugin) Actions() []core.ActionDesc {
+	return []core.ActionDesc{
+		{Name: "last_events", Description: "Recorded events, newest first by default", Scope: core.ScopeRead, Params: []core.ConfigField{
+			{Name: "limit", Type: core.ConfigInt, Default: 100},
+			{Name: "offset", Type: core.ConfigInt, Default: 0},
+			{Name: "order", Type: core.ConfigString, Enum: []string{"asc", "desc"}, Default: "desc"},
+			{Name: "filter", Type: core.ConfigObject, Fields: []core.ConfigField{
+				{Name: "type", Type: core.ConfigString},
+				{Name: "source", Type: core.ConfigString},
+				{Name: "repo", Type: core.ConfigString},
+			}},
+		}},
+	}
+}
+
 func (p *AuditPlugin) Execute(ctx context.Context, action string, params map[string]interface{}) (interface{}, error) {
 	if action != "last_events" {
 		return nil, fmt.Errorf("unknown action: %s", action)
@@ -136,6 +238,8 @@ func (p *AuditPlugin) Execute(ctx context.Context, action string, params map[str
 		}
 	}
 
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	events, err := p.store.GetLastEvents(filter, limit, offset, order)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get events: %w", err)
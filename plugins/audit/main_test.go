@@ -1,4 +1,4 @@
-package main
+package audit
 
 import (
 	"context"
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/mywio/git-ops/pkg/core"
+	"github.com/mywio/git-ops/pkg/metrics"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -96,17 +97,29 @@ func (m *mockRegistry) GetPluginsWithCapability(cap core.Capability) []core.Plug
 func (m *mockRegistry) ListPlugins() []core.Plugin                                 { return nil }
 func (m *mockRegistry) RegisterEventType(desc core.EventTypeDesc) error            { return nil }
 func (m *mockRegistry) GetMuxServer() *http.ServeMux                               { return nil }
-func (m *mockRegistry) Subscribe(pattern string, handler core.Listener) {
+func (m *mockRegistry) Router(name string) *core.PluginRouter                      { return nil }
+func (m *mockRegistry) Metrics() *metrics.Registry                                 { return nil }
+func (m *mockRegistry) Subscribe(pattern string, handler core.Listener) core.Subscription {
 	if m.subs == nil {
 		m.subs = make(map[string]core.Listener)
 	}
 	m.subs[pattern] = handler
+	return mockSubscription{registry: m, pattern: pattern}
 }
-func (m *mockRegistry) GetHTTPClient() *http.Client { return nil }
+func (m *mockRegistry) Publish(ctx context.Context, event core.InternalEvent) {}
+func (m *mockRegistry) GetHTTPClient() *http.Client                           { return nil }
 func (m *mockRegistry) GetConfig() map[string]map[string]any {
 	return m.config
 }
 
+type mockSubscription struct {
+	registry *mockRegistry
+	pattern  string
+}
+
+func (s mockSubscription) Pattern() string { return s.pattern }
+func (s mockSubscription) Unsubscribe()    { delete(s.registry.subs, s.pattern) }
+
 func TestAuditPlugin(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 	registry := &mockRegistry{
@@ -146,4 +159,18 @@ func TestAuditPlugin(t *testing.T) {
 	// e1 should be deleted.
 	assert.Equal(t, core.EventTypeName("e3"), events[0].Type)
 	assert.Equal(t, core.EventTypeName("e2"), events[1].Type)
+
+	require.NoError(t, p.Stop(context.Background()))
+	assert.Nil(t, registry.subs["*"], "Stop unsubscribes")
+}
+
+func TestAuditPlugin_RetentionFromReference(t *testing.T) {
+	p := &AuditPlugin{logger: slog.New(slog.NewTextHandler(os.Stdout, nil))}
+	require.NoError(t, p.applyConfig(map[string]map[string]any{
+		"audit": {"storage": "memory", "retention_count": core.NewSecret("5")},
+	}))
+	assert.Equal(t, 5, p.retentionCount)
+
+	require.NoError(t, p.applyConfig(map[string]map[string]any{"audit": {"retention_count": "many"}}))
+	assert.Equal(t, 1000, p.retentionCount)
 }
@@ -0,0 +1,17 @@
+// Package builtin links every bundled plugin into the importing binary.
+// Importing it for side effects registers the plugins with core; which of
+// them are enabled is decided by `core.builtin_plugins`.
+package builtin
+
+import (
+	_ "github.com/mywio/git-ops/plugins/audit"
+	_ "github.com/mywio/git-ops/plugins/env_forwarder"
+	_ "github.com/mywio/git-ops/plugins/file_forwarder"
+	_ "github.com/mywio/git-ops/plugins/google_secret_manager"
+	_ "github.com/mywio/git-ops/plugins/mcp"
+	_ "github.com/mywio/git-ops/plugins/notifier_pushover"
+	_ "github.com/mywio/git-ops/plugins/notifier_webhook"
+	_ "github.com/mywio/git-ops/plugins/reconciler"
+	_ "github.com/mywio/git-ops/plugins/ui"
+	_ "github.com/mywio/git-ops/plugins/webhook_trigger"
+)
@@ -1,4 +1,4 @@
-package main
+package envforwarder
 
 import (
 	"context"
@@ -14,6 +14,9 @@ import (
 
 type EnvForwarderPlugin struct {
 	logger   *slog.Logger
+	registry core.PluginRegistry
+
+	cfgMu    sync.RWMutex // guards keys, prefixes and enabled
 	keys     []string
 	prefixes []string
 	enabled  bool
@@ -27,6 +30,17 @@ type envForwarderConfig struct {
 	Prefixes []string `yaml:"prefixes"`
 }
 
+// ConfigSchema implements core.ConfigSchemaProvider.
+func (p *EnvForwarderPlugin) ConfigSchema() []core.ConfigSchema {
+	return []core.ConfigSchema{{
+		Section: Manifest.Name,
+		Fields: []core.ConfigField{
+			{Name: "keys", Type: core.ConfigStringList, Description: "Environment variables to forward"},
+			{Name: "prefixes", Type: core.ConfigStringList, Description: "Forward every variable with one of these prefixes"},
+		},
+	}}
+}
+
 type envForwarderStats struct {
 	ConfiguredKeys        int
 	ConfiguredPrefixes    int
@@ -51,10 +65,30 @@ type envForwarderStatsView struct {
 	LastUpdated           string         `json:"last_updated,omitempty"`
 }
 
-var Plugin core.Plugin = &EnvForwarderPlugin{}
+// Manifest of the env_forwarder plugin, which passes allowlisted host
+// environment variables to docker compose.
+var Manifest = core.PluginManifest{
+	Name:         "env_forwarder",
+	Version:      core.Version,
+	APIVersion:   core.APIVersion,
+	Capabilities: []core.Capability{core.CapabilitySecrets},
+}
+
+func init() {
+	core.RegisterBuiltin(Manifest.Name, New)
+}
+
+func New() core.Plugin {
+	return &EnvForwarderPlugin{}
+}
 
 func (p *EnvForwarderPlugin) Name() string {
-	return "env_forwarder"
+	return Manifest.Name
+}
+
+// Manifest implements core.ManifestProvider.
+func (p *EnvForwarderPlugin) Manifest() core.PluginManifest {
+	return Manifest
 }
 
 func (p *EnvForwarderPlugin) Description() string {
@@ -63,17 +97,41 @@ func (p *EnvForwarderPlugin) Description() string {
 
 func (p *EnvForwarderPlugin) Init(ctx context.Context, logger *slog.Logger, registry core.PluginRegistry) error {
 	p.logger = logger
+	p.registry = registry
 
+	var cfg map[string]map[string]any
 	if registry != nil {
-		cfg := registry.GetConfig()
-		if section, ok := cfg["env_forwarder"]; ok {
-			var ecfg envForwarderConfig
-			if err := core.DecodeConfigSection(section, &ecfg); err != nil {
-				p.logger.WarnContext(ctx, "Invalid env_forwarder config", "error", err)
-			}
-			p.keys = normalizeList(ecfg.Keys)
-			p.prefixes = normalizeList(ecfg.Prefixes)
+		cfg = registry.GetConfig()
+		registry.RegisterEventType(core.EventTypeDesc{
+			Name:        "notify_env_forwarder_missing",
+			Description: "A configured environment variable is not set",
+			PayloadSpec: map[string]core.PayloadField{
+				"key": {Type: "string", Description: "Variable name", Required: true},
+			},
+		})
+	}
+	p.applyConfig(ctx, cfg)
+	return nil
+}
+
+// Reconfigure applies a changed env_forwarder section in place.
+func (p *EnvForwarderPlugin) Reconfigure(ctx context.Context, cfg map[string]map[string]any) error {
+	p.applyConfig(ctx, cfg)
+	return nil
+}
+
+func (p *EnvForwarderPlugin) applyConfig(ctx context.Context, cfg map[string]map[string]any) {
+	p.cfgMu.Lock()
+	defer p.cfgMu.Unlock()
+
+	p.keys, p.prefixes = nil, nil
+	if section, ok := cfg["env_forwarder"]; ok {
+		var ecfg envForwarderConfig
+		if err := core.DecodeConfigSection(section, &ecfg); err != nil {
+			p.logger.WarnContext(ctx, "Invalid env_forwarder config", "error", err)
 		}
+		p.keys = normalizeList(ecfg.Keys)
+		p.prefixes = normalizeList(ecfg.Prefixes)
 	}
 
 	if len(p.keys) == 0 && len(p.prefixes) == 0 {
@@ -83,7 +141,7 @@ func (p *EnvForwarderPlugin) Init(ctx context.Context, logger *slog.Logger, regi
 			ConfiguredKeys:     len(p.keys),
 			ConfiguredPrefixes: len(p.prefixes),
 		})
-		return nil
+		return
 	}
 
 	p.enabled = true
@@ -92,7 +150,6 @@ func (p *EnvForwarderPlugin) Init(ctx context.Context, logger *slog.Logger, regi
 		ConfiguredPrefixes: len(p.prefixes),
 	})
 	p.logger.InfoContext(ctx, "env_forwarder initialized", "keys", len(p.keys), "prefixes", len(p.prefixes))
-	return nil
 }
 
 func (p *EnvForwarderPlugin) Start(ctx context.Context) error {
@@ -104,10 +161,12 @@ func (p *EnvForwarderPlugin) Stop(ctx context.Context) error {
 }
 
 func (p *EnvForwarderPlugin) Capabilities() []core.Capability {
-	return []core.Capability{core.CapabilitySecrets}
+	return Manifest.Capabilities
 }
 
 func (p *EnvForwarderPlugin) Status() core.ServiceStatus {
+	p.cfgMu.RLock()
+	defer p.cfgMu.RUnlock()
 	if p.enabled {
 		return core.StatusHealthy
 	}
@@ -130,6 +189,15 @@ func (p *EnvForwarderPlugin) Execute(ctx context.Context, action string, params
 	}
 }
 
+// Actions implements core.ActionProvider. get_secrets returns secret values
+// and is only available to other plugins.
+func (p *EnvForwarderPlugin) Actions() []core.ActionDesc {
+	return []core.ActionDesc{
+		{Name: "get_secrets", Description: "Forwarded environment variables", Internal: true},
+		{Name: "get_stats", Description: "Forwarding statistics", Scope: core.ScopeRead},
+	}
+}
+
 type envForwarderConfigView struct {
 	Keys     []string              `json:"keys,omitempty"`
 	Prefixes []string              `json:"prefixes,omitempty"`
@@ -138,6 +206,8 @@ type envForwarderConfigView struct {
 }
 
 func (p *EnvForwarderPlugin) Config() any {
+	p.cfgMu.RLock()
+	defer p.cfgMu.RUnlock()
 	return envForwarderConfigView{
 		Keys:     append([]string(nil), p.keys...),
 		Prefixes: append([]string(nil), p.prefixes...),
@@ -147,6 +217,9 @@ func (p *EnvForwarderPlugin) Config() any {
 }
 
 func (p *EnvForwarderPlugin) collectSecrets(ctx context.Context) (map[string]string, envForwarderStats) {
+	p.cfgMu.RLock()
+	defer p.cfgMu.RUnlock()
+
 	stats := envForwarderStats{
 		ConfiguredKeys:     len(p.keys),
 		ConfiguredPrefixes: len(p.prefixes),
@@ -181,7 +254,7 @@ func (p *EnvForwarderPlugin) collectSecrets(ctx context.Context) (map[string]str
 		value, ok := envMap[key]
 		if !ok {
 			p.logger.Warn("Env var not set", "key", key)
-			core.Publish(ctx, core.InternalEvent{
+			p.publish(ctx, core.InternalEvent{
 				Type:   "notify_env_forwarder_missing",
 				Source: "env_forwarder",
 				String: fmt.Sprintf("Env var %s not set", key),
@@ -274,3 +347,11 @@ func normalizeList(values []string) []string {
 	}
 	return out
 }
+
+// publish sends event on the registry's bus, if the plugin was initialized
+// with one.
+func (p *EnvForwarderPlugin) publish(ctx context.Context, event core.InternalEvent) {
+	if p.registry != nil {
+		p.registry.Publish(ctx, event)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package envforwarder
 
 import (
 	"context"
@@ -76,3 +76,10 @@ func TestEnvForwarderPlugin_UnknownAction(t *testing.T) {
 	_, err = p.Execute(context.Background(), "nope", map[string]interface{}{})
 	assert.Error(t, err)
 }
+
+func TestEnvForwarderPlugin_SecretsNotInvokableOverHTTP(t *testing.T) {
+	var p core.ActionProvider = &EnvForwarderPlugin{}
+	for _, action := range p.Actions() {
+		assert.Equal(t, action.Name == "get_secrets", action.Internal, action.Name)
+	}
+}
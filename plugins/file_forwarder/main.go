@@ -1,4 +1,4 @@
-package main
+package fileforwarder
 
 import (
 	"context"
@@ -15,7 +15,9 @@ import (
 )
 
 type FileForwarderPlugin struct {
-	logger  *slog.Logger
+	logger *slog.Logger
+
+	cfgMu   sync.RWMutex // guards files and enabled
 	files   []forwardFileSpec
 	enabled bool
 
@@ -35,6 +37,22 @@ type forwardFileSpec struct {
 	Required *bool  `yaml:"required"`
 }
 
+// ConfigSchema implements core.ConfigSchemaProvider.
+func (p *FileForwarderPlugin) ConfigSchema() []core.ConfigSchema {
+	return []core.ConfigSchema{{
+		Section: Manifest.Name,
+		Fields: []core.ConfigField{
+			{Name: "files", Type: core.ConfigObjectList, Description: "Host files to forward", Fields: []core.ConfigField{
+				{Name: "env", Type: core.ConfigString, Required: true, Description: "Variable that receives the file path"},
+				{Name: "path", Type: core.ConfigString, Required: true, Description: "Host file to forward"},
+				{Name: "filename", Type: core.ConfigString},
+				{Name: "mode", Type: core.ConfigString, Default: "0600"},
+				{Name: "required", Type: core.ConfigBool, Default: true},
+			}},
+		},
+	}}
+}
+
 type fileForwarderStats struct {
 	ConfiguredFiles int
 	ForwardedFiles  int
@@ -63,10 +81,30 @@ type forwardFileSpecView struct {
 	Required bool   `json:"required"`
 }
 
-var Plugin core.Plugin = &FileForwarderPlugin{}
+// Manifest of the file_forwarder plugin, which hands allowlisted host files
+// to docker compose.
+var Manifest = core.PluginManifest{
+	Name:         "file_forwarder",
+	Version:      core.Version,
+	APIVersion:   core.APIVersion,
+	Capabilities: []core.Capability{core.CapabilityRuntimeFiles},
+}
+
+func init() {
+	core.RegisterBuiltin(Manifest.Name, New)
+}
+
+func New() core.Plugin {
+	return &FileForwarderPlugin{}
+}
 
 func (p *FileForwarderPlugin) Name() string {
-	return "file_forwarder"
+	return Manifest.Name
+}
+
+// Manifest implements core.ManifestProvider.
+func (p *FileForwarderPlugin) Manifest() core.PluginManifest {
+	return Manifest
 }
 
 func (p *FileForwarderPlugin) Description() string {
@@ -76,28 +114,43 @@ func (p *FileForwarderPlugin) Description() string {
 func (p *FileForwarderPlugin) Init(ctx context.Context, logger *slog.Logger, registry core.PluginRegistry) error {
 	p.logger = logger
 
+	var cfg map[string]map[string]any
 	if registry != nil {
-		cfg := registry.GetConfig()
-		if section, ok := cfg["file_forwarder"]; ok {
-			var fcfg fileForwarderConfig
-			if err := core.DecodeConfigSection(section, &fcfg); err != nil {
-				p.logger.WarnContext(ctx, "Invalid file_forwarder config", "error", err)
-			}
-			p.files = normalizeFileSpecs(fcfg.Files)
+		cfg = registry.GetConfig()
+	}
+	p.applyConfig(ctx, cfg)
+	return nil
+}
+
+// Reconfigure applies a changed file_forwarder section in place.
+func (p *FileForwarderPlugin) Reconfigure(ctx context.Context, cfg map[string]map[string]any) error {
+	p.applyConfig(ctx, cfg)
+	return nil
+}
+
+func (p *FileForwarderPlugin) applyConfig(ctx context.Context, cfg map[string]map[string]any) {
+	p.cfgMu.Lock()
+	defer p.cfgMu.Unlock()
+
+	p.files = nil
+	if section, ok := cfg["file_forwarder"]; ok {
+		var fcfg fileForwarderConfig
+		if err := core.DecodeConfigSection(section, &fcfg); err != nil {
+			p.logger.WarnContext(ctx, "Invalid file_forwarder config", "error", err)
 		}
+		p.files = normalizeFileSpecs(fcfg.Files)
 	}
 
 	if len(p.files) == 0 {
 		p.enabled = false
 		p.logger.WarnContext(ctx, "file_forwarder has no files configured, disabled")
 		p.setStats(fileForwarderStats{ConfiguredFiles: 0})
-		return nil
+		return
 	}
 
 	p.enabled = true
 	p.setStats(fileForwarderStats{ConfiguredFiles: len(p.files)})
 	p.logger.InfoContext(ctx, "file_forwarder initialized", "files", len(p.files))
-	return nil
 }
 
 func (p *FileForwarderPlugin) Start(ctx context.Context) error { return nil }
@@ -105,10 +158,12 @@ func (p *FileForwarderPlugin) Start(ctx context.Context) error { return nil }
 func (p *FileForwarderPlugin) Stop(ctx context.Context) error { return nil }
 
 func (p *FileForwarderPlugin) Capabilities() []core.Capability {
-	return []core.Capability{core.CapabilityRuntimeFiles}
+	return Manifest.Capabilities
 }
 
 func (p *FileForwarderPlugin) Status() core.ServiceStatus {
+	p.cfgMu.RLock()
+	defer p.cfgMu.RUnlock()
 	if p.enabled {
 		return core.StatusHealthy
 	}
@@ -128,7 +183,18 @@ func (p *FileForwarderPlugin) Execute(ctx context.Context, action string, params
 	}
 }
 
+// Actions implements core.ActionProvider. get_runtime_files returns file
+// contents and is only available to other plugins.
+func (p *FileForwarderPlugin) Actions() []core.ActionDesc {
+	return []core.ActionDesc{
+		{Name: "get_runtime_files", Description: "Files to mount into stacks, with their contents", Internal: true},
+		{Name: "get_stats", Description: "Forwarding statistics", Scope: core.ScopeRead},
+	}
+}
+
 func (p *FileForwarderPlugin) Config() any {
+	p.cfgMu.RLock()
+	defer p.cfgMu.RUnlock()
 	viewFiles := make([]forwardFileSpecView, 0, len(p.files))
 	for _, file := range p.files {
 		viewFiles = append(viewFiles, forwardFileSpecView{
@@ -147,6 +213,9 @@ func (p *FileForwarderPlugin) Config() any {
 }
 
 func (p *FileForwarderPlugin) collectRuntimeFiles() ([]core.RuntimeFile, fileForwarderStats, error) {
+	p.cfgMu.RLock()
+	defer p.cfgMu.RUnlock()
+
 	stats := fileForwarderStats{
 		ConfiguredFiles: len(p.files),
 		MissingFiles:    []string{},
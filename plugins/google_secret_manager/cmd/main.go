@@ -0,0 +1,26 @@
+// Command google_secret_manager builds the google_secret_manager plugin as a standalone artifact: a `.so`
+// (go build -buildmode=plugin) or an executable process plugin.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mywio/git-ops/pkg/core"
+	googlesecretmanager "github.com/mywio/git-ops/plugins/google_secret_manager"
+)
+
+// Plugin is the symbol core looks up when this package is loaded as a `.so`.
+var Plugin core.Plugin = googlesecretmanager.New()
+
+// Manifest is checked by core before the `Plugin` symbol is used.
+var Manifest = googlesecretmanager.Manifest
+
+// main runs the plugin out-of-process when it is launched by git-ops as an
+// executable from plugins_dir.
+func main() {
+	if err := core.ServeProcessPlugin(Plugin); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
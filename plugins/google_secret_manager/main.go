@@ -1,4 +1,4 @@
-package main
+package googlesecretmanager
 
 import (
 	"context"
@@ -23,10 +23,40 @@ type secretManagerConfig struct {
 	Project   string `yaml:"project"`
 }
 
-var Plugin = &SecretManagerPlugin{}
+// ConfigSchema implements core.ConfigSchemaProvider.
+func (p *SecretManagerPlugin) ConfigSchema() []core.ConfigSchema {
+	return []core.ConfigSchema{{
+		Section: Manifest.Name,
+		Fields: []core.ConfigField{
+			{Name: "project_id", Aliases: []string{"project"}, Type: core.ConfigString, Description: "Google Cloud project; defaults to GOOGLE_CLOUD_PROJECT"},
+		},
+	}}
+}
+
+// Manifest of the google_secret_manager plugin, which reads stack secrets
+// from Google Secret Manager.
+var Manifest = core.PluginManifest{
+	Name:         "google_secret_manager",
+	Version:      core.Version,
+	APIVersion:   core.APIVersion,
+	Capabilities: []core.Capability{core.CapabilitySecrets},
+}
+
+func init() {
+	core.RegisterBuiltin(Manifest.Name, New)
+}
+
+func New() core.Plugin {
+	return &SecretManagerPlugin{}
+}
 
 func (p *SecretManagerPlugin) Name() string {
-	return "google_secret_manager"
+	return Manifest.Name
+}
+
+// Manifest implements core.ManifestProvider.
+func (p *SecretManagerPlugin) Manifest() core.PluginManifest {
+	return Manifest
 }
 
 func (p *SecretManagerPlugin) Description() string {
@@ -75,7 +105,7 @@ func (p *SecretManagerPlugin) Stop(ctx context.Context) error {
 }
 
 func (p *SecretManagerPlugin) Capabilities() []core.Capability {
-	return []core.Capability{core.CapabilitySecrets}
+	return Manifest.Capabilities
 }
 
 func (p *SecretManagerPlugin) Status() core.ServiceStatus {
@@ -85,6 +115,14 @@ func (p *SecretManagerPlugin) Status() core.ServiceStatus {
 	return core.StatusHealthy
 }
 
+// Actions implements core.ActionProvider. get_secrets returns secret values
+// and is only available to other plugins.
+func (p *SecretManagerPlugin) Actions() []core.ActionDesc {
+	return []core.ActionDesc{
+		{Name: "get_secrets", Description: "Secrets of a stack", Internal: true},
+	}
+}
+
 func (p *SecretManagerPlugin) Execute(ctx context.Context, action string, params map[string]interface{}) (interface{}, error) {
 	if action != "get_secrets" {
 		return nil, fmt.Errorf("unknown action: %s", action)
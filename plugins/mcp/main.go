@@ -1,4 +1,4 @@
-package main
+package mcp
 
 import (
 	"context"
@@ -26,12 +26,14 @@ var docsFS embed.FS
 
 // MCPPlugin struct implements core.Plugin
 type MCPPlugin struct {
-	logger    *slog.Logger
-	port      string
+	logger *slog.Logger
+	port   string
+	router *core.PluginRouter
+	wg     *sync.WaitGroup
+
+	cfgMu     sync.RWMutex // guards targetDir and apiKey
 	targetDir string
 	apiKey    string
-	mux       *http.ServeMux
-	wg        *sync.WaitGroup
 
 	deployMu    sync.RWMutex
 	deployments map[string]deploymentInfo
@@ -42,6 +44,17 @@ type mcpConfig struct {
 	APIKey    string `yaml:"api_key"`
 }
 
+// ConfigSchema implements core.ConfigSchemaProvider.
+func (p *MCPPlugin) ConfigSchema() []core.ConfigSchema {
+	return []core.ConfigSchema{{
+		Section: Manifest.Name,
+		Fields: []core.ConfigField{
+			{Name: "target_dir", Type: core.ConfigString, Description: "Stacks directory"},
+			{Name: "api_key", Type: core.ConfigString, Secret: true, Description: "Key required on MCP requests"},
+		},
+	}}
+}
+
 type deploymentInfo struct {
 	FullName  string    `json:"full_name"`
 	Owner     string    `json:"owner"`
@@ -52,12 +65,31 @@ type deploymentInfo struct {
 	Duration  string    `json:"duration,omitempty"`
 	Source    string    `json:"source,omitempty"`
 }
-// Exported for plugin loading (core loads symbol "MCPPlugin" or similar)
-var Plugin = &MCPPlugin{}
+
+// Manifest of the mcp plugin, which serves the Model Context Protocol API.
+var Manifest = core.PluginManifest{
+	Name:         "mcp",
+	Version:      core.Version,
+	APIVersion:   core.APIVersion,
+	Capabilities: []core.Capability{core.CapabilityMCP, core.CapabilityAPI},
+}
+
+func init() {
+	core.RegisterBuiltin(Manifest.Name, New)
+}
+
+func New() core.Plugin {
+	return &MCPPlugin{}
+}
 
 // Name returns the plugin name
 func (p *MCPPlugin) Name() string {
-	return "mcp"
+	return Manifest.Name
+}
+
+// Manifest implements core.ManifestProvider.
+func (p *MCPPlugin) Manifest() core.PluginManifest {
+	return Manifest
 }
 
 // Init initializes the plugin with context, logger, and registry
@@ -71,45 +103,77 @@ func (p *MCPPlugin) Init(ctx context.Context, logger *slog.Logger, registry core
 	}
 
 	if registry != nil {
-		cfg := registry.GetConfig()
-		if section, ok := cfg["mcp"]; ok {
-			var mcfg mcpConfig
-			if err := core.DecodeConfigSection(section, &mcfg); err != nil {
-				p.logger.Warn("Invalid mcp config", "error", err)
-			}
-			p.targetDir = mcfg.TargetDir
-			p.apiKey = mcfg.APIKey
-		}
-		p.mux = registry.GetMuxServer()
+		p.applyConfig(registry.GetConfig())
+		p.router = registry.Router(p.Name())
 		registry.Subscribe("deploy_*", p.handleDeployEvent)
 	} else {
-		p.mux = http.NewServeMux()
+		p.applyConfig(nil)
+	}
+	return nil
+}
+
+// Reconfigure applies a changed mcp section; routes stay registered and
+// check the new API key.
+func (p *MCPPlugin) Reconfigure(ctx context.Context, cfg map[string]map[string]any) error {
+	p.applyConfig(cfg)
+	return nil
+}
+
+func (p *MCPPlugin) applyConfig(cfg map[string]map[string]any) {
+	p.cfgMu.Lock()
+	defer p.cfgMu.Unlock()
+
+	p.targetDir, p.apiKey = "", ""
+	if section, ok := cfg["mcp"]; ok {
+		var mcfg mcpConfig
+		if err := core.DecodeConfigSection(section, &mcfg); err != nil {
+			p.logger.Warn("Invalid mcp config", "error", err)
+		}
+		p.targetDir = mcfg.TargetDir
+		p.apiKey = mcfg.APIKey
 	}
 	if p.targetDir == "" {
 		p.targetDir = "/opt/stacks"
 	}
 
 	p.logger.Info("MCP Plugin Initialized", "Port", p.port, "TargetDir", p.targetDir, "Auth", p.apiKey != "")
-	return nil
+}
+
+func (p *MCPPlugin) stacksDir() string {
+	p.cfgMu.RLock()
+	defer p.cfgMu.RUnlock()
+	return p.targetDir
+}
+
+func (p *MCPPlugin) currentAPIKey() string {
+	p.cfgMu.RLock()
+	defer p.cfgMu.RUnlock()
+	return p.apiKey
 }
 
 // Start starts the plugin services
 func (p *MCPPlugin) Start(ctx context.Context) error {
-	//mux := http.NewServeMux()
-	p.mux.HandleFunc("/mcp/setup", authMiddleware(p.apiKey, p.handleSetup))
-	p.mux.HandleFunc("/mcp/stacks", authMiddleware(p.apiKey, p.handleStacks))
-	p.mux.HandleFunc("/mcp/deployments", authMiddleware(p.apiKey, p.handleDeployments))
-	p.mux.HandleFunc("/mcp/services/", authMiddleware(p.apiKey, p.handleServices)) // /mcp/services/:repo
-	p.mux.HandleFunc("/mcp/logs/", authMiddleware(p.apiKey, p.handleLogs))         // /mcp/logs/:repo/:service?lines=100&since=1h
-	p.mux.HandleFunc("/mcp/health/", authMiddleware(p.apiKey, p.handleHealth))     // /mcp/health/:repo/:service
+	if p.router == nil {
+		return nil
+	}
+	p.router.HandleFunc("/setup", authMiddleware(p.currentAPIKey, p.handleSetup))
+	p.router.HandleFunc("/stacks", authMiddleware(p.currentAPIKey, p.handleStacks))
+	p.router.HandleFunc("/deployments", authMiddleware(p.currentAPIKey, p.handleDeployments))
+	p.router.HandleFunc("/services/", authMiddleware(p.currentAPIKey, p.handleServices)) // /services/:repo
+	p.router.HandleFunc("/logs/", authMiddleware(p.currentAPIKey, p.handleLogs))         // /logs/:repo/:service?lines=100&since=1h
+	p.router.HandleFunc("/health/", authMiddleware(p.currentAPIKey, p.handleHealth))     // /health/:repo/:service
+	p.router.ServeLegacy("/mcp")
 
 	if docsSub, err := fs.Sub(docsFS, "docs"); err == nil {
 		fileServer := http.FileServer(http.FS(docsSub))
-		p.mux.HandleFunc("/mcp/docs", authMiddleware(p.apiKey, func(w http.ResponseWriter, r *http.Request) {
-			http.Redirect(w, r, "/mcp/docs/", http.StatusMovedPermanently)
+		// The router strips the prefix, so redirect relative to the request
+		// path instead of with http.Redirect.
+		p.router.HandleFunc("/docs", authMiddleware(p.currentAPIKey, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Location", "docs/")
+			w.WriteHeader(http.StatusMovedPermanently)
 		}))
-		p.mux.HandleFunc("/mcp/docs/", authMiddleware(p.apiKey, func(w http.ResponseWriter, r *http.Request) {
-			http.StripPrefix("/mcp/docs/", fileServer).ServeHTTP(w, r)
+		p.router.HandleFunc("/docs/", authMiddleware(p.currentAPIKey, func(w http.ResponseWriter, r *http.Request) {
+			http.StripPrefix("/docs/", fileServer).ServeHTTP(w, r)
 		}))
 	} else {
 		p.logger.Warn("MCP docs not available", "error", err)
@@ -131,8 +195,7 @@ func (p *MCPPlugin) Description() string {
 
 // Capabilities returns the capabilities of the plugin
 func (p *MCPPlugin) Capabilities() []core.Capability {
-	// Assuming core.Capability is defined; return empty or specific if known
-	return []core.Capability{core.CapabilityMCP, core.CapabilityAPI}
+	return Manifest.Capabilities
 }
 
 // Status returns the current status of the plugin
@@ -154,6 +217,8 @@ type mcpConfigView struct {
 }
 
 func (p *MCPPlugin) Config() any {
+	p.cfgMu.RLock()
+	defer p.cfgMu.RUnlock()
 	return mcpConfigView{
 		TargetDir:   p.targetDir,
 		APIKey:      core.NewSecret(p.apiKey),
@@ -161,10 +226,20 @@ func (p *MCPPlugin) Config() any {
 	}
 }
 
-// Auth middleware
-func authMiddleware(key string, next http.HandlerFunc) http.HandlerFunc {
+// RouteScopes implements core.RouteScopeProvider: every MCP route only
+// reads.
+func (p *MCPPlugin) RouteScopes() map[string]core.Scope {
+	return map[string]core.Scope{"/": core.ScopeRead}
+}
+
+// Auth middleware; requests core already authenticated skip the API key.
+func authMiddleware(apiKey func() string, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if key != "" && r.Header.Get("X-API-Key") != key {
+		if _, ok := core.AuthenticatedToken(r.Context()); ok {
+			next(w, r)
+			return
+		}
+		if key := apiKey(); key != "" && r.Header.Get("X-API-Key") != key {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
@@ -190,14 +265,14 @@ func (p *MCPPlugin) handleStacks(w http.ResponseWriter, r *http.Request) {
 	p.wg.Add(1)
 	defer p.wg.Done()
 
-	repos, err := listDirs(p.targetDir)
+	repos, err := listDirs(p.stacksDir())
 	if err != nil {
 		jsonError(w, err)
 		return
 	}
 	stacks := []map[string]interface{}{}
 	for _, repo := range repos {
-		lastSync, _ := os.Stat(filepath.Join(p.targetDir, repo)) // Approx last reconcile
+		lastSync, _ := os.Stat(filepath.Join(p.stacksDir(), repo)) // Approx last reconcile
 		entry := map[string]interface{}{
 			"repo":     repo,
 			"lastSync": lastSync.ModTime().Format(time.RFC3339),
@@ -235,12 +310,12 @@ func (p *MCPPlugin) handleServices(w http.ResponseWriter, r *http.Request) {
 	p.wg.Add(1)
 	defer p.wg.Done()
 
-	repo := strings.TrimPrefix(r.URL.Path, "/mcp/services/")
+	repo := strings.TrimPrefix(r.URL.Path, "/services/")
 	if repo == "" {
 		jsonError(w, errors.New("repo required"))
 		return
 	}
-	output, err := dockerComposeExec(p.targetDir, repo, "ps", "--format", "json")
+	output, err := dockerComposeExec(p.stacksDir(), repo, "ps", "--format", "json")
 	if err != nil {
 		jsonError(w, err)
 		return
@@ -259,7 +334,7 @@ func (p *MCPPlugin) handleLogs(w http.ResponseWriter, r *http.Request) {
 	p.wg.Add(1)
 	defer p.wg.Done()
 
-	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/mcp/logs/"), "/", 2)
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/logs/"), "/", 2)
 	if len(parts) != 2 {
 		jsonError(w, errors.New("format: /logs/:repo/:service"))
 		return
@@ -275,7 +350,7 @@ func (p *MCPPlugin) handleLogs(w http.ResponseWriter, r *http.Request) {
 		args = append(args, "--since", since)
 	}
 	args = append(args, service)
-	output, err := dockerComposeExec(p.targetDir, repo, args...)
+	output, err := dockerComposeExec(p.stacksDir(), repo, args...)
 	if err != nil {
 		jsonError(w, err)
 		return
@@ -288,7 +363,7 @@ func (p *MCPPlugin) handleHealth(w http.ResponseWriter, r *http.Request) {
 	p.wg.Add(1)
 	defer p.wg.Done()
 
-	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/mcp/health/"), "/", 2)
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/health/"), "/", 2)
 	if len(parts) != 2 {
 		jsonError(w, errors.New("format: /health/:repo/:service"))
 		return
@@ -387,13 +462,3 @@ func dockerComposeExec(targetDir, repo string, args ...string) (string, error) {
 	}
 	return string(output), nil
 }
-
-// Main (for standalone testing; ignored in plugin mode)
-func main() {
-	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	p := &MCPPlugin{}
-	ctx := context.Background()
-	p.Init(ctx, logger, nil) // nil registry for testing
-	p.Start(ctx)
-	select {} // Block for testing
-}
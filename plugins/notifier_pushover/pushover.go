@@ -1,5 +1,5 @@
 // plugins/notifier_pushover/pushover.go
-package main
+package notifierpushover
 
 import (
 	"bytes"
@@ -8,18 +8,26 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/mywio/git-ops/pkg/core"
+	"github.com/mywio/git-ops/pkg/metrics"
 )
 
 type PushoverNotifier struct {
-	logger  *slog.Logger
-	client  *http.Client
-	token   core.Secret
-	user    string
-	enabled bool
+	logger   *slog.Logger
+	client   *http.Client
+	registry core.PluginRegistry
+	sent     *metrics.CounterVec
+
+	mu            sync.RWMutex // guards the fields below
+	token         core.Secret
+	user          string
+	enabled       bool
 	subscriptions []string
+	subscribed    map[string]core.Subscription // by pattern
 }
 
 type pushoverConfig struct {
@@ -27,55 +35,115 @@ type pushoverConfig struct {
 	User  string `yaml:"user"`
 }
 
+// ConfigSchema implements core.ConfigSchemaProvider.
+func (n *PushoverNotifier) ConfigSchema() []core.ConfigSchema {
+	return []core.ConfigSchema{{
+		Section: Manifest.Name,
+		Fields: []core.ConfigField{
+			{Name: "token", Type: core.ConfigString, Secret: true, Description: "Pushover application token"},
+			{Name: "user", Type: core.ConfigString, Description: "Pushover user key"},
+			{Name: "subscribe", Type: core.ConfigCommaList, Default: []string{"notify_*"}, Description: "Event patterns to notify on"},
+		},
+	}}
+}
+
 func (n *PushoverNotifier) Name() string {
-	return "pushover"
+	return Manifest.Name
+}
+
+// Manifest implements core.ManifestProvider.
+func (n *PushoverNotifier) Manifest() core.PluginManifest {
+	return Manifest
 }
 
 func (n *PushoverNotifier) Init(ctx context.Context, logger *slog.Logger, registry core.PluginRegistry) error {
 	n.logger = logger
-	var subscribeProvided bool
-	var subscribePatterns []string
+	n.registry = registry
+	var cfg map[string]map[string]any
+	var reg *metrics.Registry
 	if registry != nil {
 		n.client = registry.GetHTTPClient()
-		cfg := registry.GetConfig()
-		if section, ok := cfg["pushover"]; ok {
-			if _, okSub := section["subscribe"]; okSub {
-				subscribeProvided = true
-			}
-			var pushoverCfg pushoverConfig
-			if err := core.DecodeConfigSection(section, &pushoverCfg); err != nil {
-				n.logger.WarnContext(ctx, "Invalid pushover config", "error", err)
-			}
-			n.token = core.NewSecret(pushoverCfg.Token)
-			n.user = pushoverCfg.User
-			subscribePatterns = parseSubscribePatterns(section)
-		}
+		cfg = registry.GetConfig()
+		reg = registry.Metrics()
 	}
+	n.sent = core.NotificationsCounter(reg)
 	if n.client == nil {
 		n.client = http.DefaultClient
 	}
+	n.applyConfig(ctx, cfg)
+	return nil
+}
+
+// Reconfigure applies a changed pushover section in place. Patterns removed
+// from subscribe stop delivering; new patterns are subscribed.
+func (n *PushoverNotifier) Reconfigure(ctx context.Context, cfg map[string]map[string]any) error {
+	n.applyConfig(ctx, cfg)
+	return nil
+}
+
+func (n *PushoverNotifier) applyConfig(ctx context.Context, cfg map[string]map[string]any) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var subscribeProvided bool
+	var subscribePatterns []string
+	n.token, n.user, n.subscriptions = core.Secret{}, "", nil
+	if section, ok := cfg["pushover"]; ok {
+		if _, okSub := section["subscribe"]; okSub {
+			subscribeProvided = true
+		}
+		var pushoverCfg pushoverConfig
+		if err := core.DecodeConfigSection(section, &pushoverCfg); err != nil {
+			n.logger.WarnContext(ctx, "Invalid pushover config", "error", err)
+		}
+		n.token = core.NewSecret(pushoverCfg.Token)
+		n.user = pushoverCfg.User
+		subscribePatterns = parseSubscribePatterns(section)
+	}
 	if n.token.Value == "" || n.user == "" {
 		n.logger.WarnContext(ctx, "Pushover token or user not set, notifications disabled")
 		n.enabled = false
-		return nil
+		n.unsubscribe(nil)
+		return
 	}
 	n.enabled = true
 	n.logger.InfoContext(ctx, "Pushover Notifier Initialized")
 
-	if registry != nil {
+	if n.registry != nil {
 		if !subscribeProvided {
 			subscribePatterns = []string{"notify_*"}
 		}
 		n.subscriptions = append([]string(nil), subscribePatterns...)
+		n.unsubscribe(subscribePatterns)
 		for _, pattern := range subscribePatterns {
-			registry.Subscribe(pattern, n.process)
+			n.subscribe(pattern)
 		}
 		if len(subscribePatterns) == 0 {
 			n.logger.InfoContext(ctx, "Pushover notifier has no subscriptions configured; skipping event registration")
 		}
 	}
+}
 
-	return nil
+// subscribe registers pattern with the event bus once. Callers hold n.mu.
+func (n *PushoverNotifier) subscribe(pattern string) {
+	if _, ok := n.subscribed[pattern]; ok {
+		return
+	}
+	if n.subscribed == nil {
+		n.subscribed = map[string]core.Subscription{}
+	}
+	n.subscribed[pattern] = n.registry.Subscribe(pattern, n.process)
+}
+
+// unsubscribe drops the subscriptions whose pattern is not in keep. Callers
+// hold n.mu.
+func (n *PushoverNotifier) unsubscribe(keep []string) {
+	for pattern, sub := range n.subscribed {
+		if !slices.Contains(keep, pattern) {
+			sub.Unsubscribe()
+			delete(n.subscribed, pattern)
+		}
+	}
 }
 
 func (n *PushoverNotifier) Start(ctx context.Context) error {
@@ -84,7 +152,9 @@ func (n *PushoverNotifier) Start(ctx context.Context) error {
 }
 
 func (n *PushoverNotifier) Stop(ctx context.Context) error {
-	// No services to stop
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.unsubscribe(nil)
 	return nil
 }
 
@@ -93,10 +163,12 @@ func (n *PushoverNotifier) Description() string {
 }
 
 func (n *PushoverNotifier) Capabilities() []core.Capability {
-	return []core.Capability{core.CapabilityNotifier}
+	return Manifest.Capabilities
 }
 
 func (n *PushoverNotifier) Status() core.ServiceStatus {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
 	if n.enabled && n.token.Value != "" && n.user != "" {
 		return core.StatusHealthy
 	}
@@ -104,12 +176,18 @@ func (n *PushoverNotifier) Status() core.ServiceStatus {
 }
 
 func (n *PushoverNotifier) process(ctx context.Context, event core.InternalEvent) {
-	if !n.enabled || n.token.Value == "" || n.user == "" {
+	n.mu.RLock()
+	enabled := n.enabled && n.token.Value != "" && n.user != ""
+	n.mu.RUnlock()
+	if !enabled {
 		return
 	}
 	if err := n.send(ctx, event); err != nil {
+		n.sent.With(Manifest.Name, "failed").Inc()
 		n.logger.ErrorContext(ctx, "Failed to send Pushover notification", "error", err)
+		return
 	}
+	n.sent.With(Manifest.Name, "sent").Inc()
 }
 
 func (n *PushoverNotifier) Execute(ctx context.Context, action string, params map[string]interface{}) (interface{}, error) {
@@ -169,8 +247,21 @@ func (n *PushoverNotifier) Execute(ctx context.Context, action string, params ma
 	return nil, nil
 }
 
-// Exported symbol that core looks up
-var Plugin core.Plugin = &PushoverNotifier{}
+// Manifest of the pushover notifier.
+var Manifest = core.PluginManifest{
+	Name:         "pushover",
+	Version:      core.Version,
+	APIVersion:   core.APIVersion,
+	Capabilities: []core.Capability{core.CapabilityNotifier},
+}
+
+func init() {
+	core.RegisterBuiltin(Manifest.Name, New)
+}
+
+func New() core.Plugin {
+	return &PushoverNotifier{}
+}
 
 type pushoverConfigView struct {
 	Token     core.Secret `json:"token"`
@@ -180,6 +271,8 @@ type pushoverConfigView struct {
 }
 
 func (n *PushoverNotifier) Config() any {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
 	return pushoverConfigView{
 		Token:     n.token,
 		User:      n.user,
@@ -197,9 +290,13 @@ func (n *PushoverNotifier) send(ctx context.Context, event core.InternalEvent) e
 		return err
 	}
 
+	n.mu.RLock()
+	token, user := n.token.Value, n.user
+	n.mu.RUnlock()
+
 	payload := map[string]interface{}{
-		"token":   n.token.Value,
-		"user":    n.user,
+		"token":   token,
+		"user":    user,
 		"message": fmt.Sprintf("[%s] %s\nRepo: %s/%s\n%s", event.Type, event.String, event.Source, event.Repo, string(details)),
 		"title":   "git-ops Notification",
 		// TODO: Have a priority map in config. That will map notification types to priority levels.
@@ -253,7 +350,8 @@ func parseSubscribePatterns(section map[string]any) []string {
 	if !ok {
 		return nil
 	}
-	switch v := raw.(type) {
+	// A value from a ${...} reference is a core.Secret.
+	switch v := core.UnwrapSecrets(raw).(type) {
 	case []string:
 		return normalizePatterns(v)
 	case []any:
@@ -269,37 +367,3 @@ func parseSubscribePatterns(section map[string]any) []string {
 		return normalizePatterns([]string{fmt.Sprint(v)})
 	}
 }
-
-// TODO: fix me after the refactor
-// Main for standalone testing
-//func main() {
-//	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-//	n := &PushoverNotifier{}
-//	ctx := context.Background()
-//	if err := n.Init(ctx, logger, nil); err != nil { // nil registry for testing
-//		logger.Error("Init failed", "error", err)
-//		return
-//	}
-//	if err := n.Start(ctx); err != nil {
-//		logger.Error("Start failed", "error", err)
-//		return
-//	}
-//
-//	// Test Execute
-//	//event := core.Event{
-//	//	Type:    "test",
-//	//	Owner:   "owner",
-//	//	Repo:    "repo",
-//	//	Message: "message",
-//	//	Details: map[string]interface{}{"key": "value"},
-//	//}
-//	params := map[string]interface{}{"event": event}
-//	result, err := n.Execute(ctx, "notify", params)
-//	if err != nil {
-//		logger.Error("Execute failed", "error", err)
-//	} else {
-//		logger.Info("Execute result", "result", result)
-//	}
-//
-//	n.Stop(ctx)
-//}
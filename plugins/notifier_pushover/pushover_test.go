@@ -1,8 +1,9 @@
-package main
+package notifierpushover
 
 import (
 	"testing"
 
+	"github.com/mywio/git-ops/pkg/core"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -44,6 +45,20 @@ func TestParseSubscribePatternsPushover(t *testing.T) {
 			},
 			want: []string{"notify_*", "deploy_*"},
 		},
+		{
+			name: "reference",
+			section: map[string]any{
+				"subscribe": core.NewSecret("notify_*,deploy_*"),
+			},
+			want: []string{"notify_*", "deploy_*"},
+		},
+		{
+			name: "reference_in_list",
+			section: map[string]any{
+				"subscribe": []any{core.NewSecret("notify_*"), "deploy_*"},
+			},
+			want: []string{"notify_*", "deploy_*"},
+		},
 		{
 			name: "scalar",
 			section: map[string]any{
@@ -0,0 +1,26 @@
+// Command notifier_webhook builds the webhook plugin as a standalone artifact: a `.so`
+// (go build -buildmode=plugin) or an executable process plugin.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mywio/git-ops/pkg/core"
+	notifierwebhook "github.com/mywio/git-ops/plugins/notifier_webhook"
+)
+
+// Plugin is the symbol core looks up when this package is loaded as a `.so`.
+var Plugin core.Plugin = notifierwebhook.New()
+
+// Manifest is checked by core before the `Plugin` symbol is used.
+var Manifest = notifierwebhook.Manifest
+
+// main runs the plugin out-of-process when it is launched by git-ops as an
+// executable from plugins_dir.
+func main() {
+	if err := core.ServeProcessPlugin(Plugin); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package notifierwebhook
 
 import (
 	"bytes"
@@ -7,71 +7,144 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"os"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/mywio/git-ops/pkg/core"
+	"github.com/mywio/git-ops/pkg/metrics"
 )
 
 type WebhookPlugin struct {
-	logger *slog.Logger
-	url    string
-	client *http.Client
-	enabled bool
+	logger   *slog.Logger
+	client   *http.Client
+	registry core.PluginRegistry
+	sent     *metrics.CounterVec
+
+	mu            sync.RWMutex // guards the fields below
+	url           string
+	enabled       bool
 	subscriptions []string
+	subscribed    map[string]core.Subscription // by pattern
 }
 
 type webhookConfig struct {
 	URL string `yaml:"url"`
 }
 
+// ConfigSchema implements core.ConfigSchemaProvider.
+func (p *WebhookPlugin) ConfigSchema() []core.ConfigSchema {
+	return []core.ConfigSchema{{
+		Section: Manifest.Name,
+		Fields: []core.ConfigField{
+			{Name: "url", Type: core.ConfigString, Description: "URL events are posted to"},
+			{Name: "subscribe", Type: core.ConfigCommaList, Default: []string{"notify_*"}, Description: "Event patterns to post"},
+		},
+	}}
+}
+
 func (p *WebhookPlugin) Name() string {
-	return "webhook"
+	return Manifest.Name
+}
+
+// Manifest implements core.ManifestProvider.
+func (p *WebhookPlugin) Manifest() core.PluginManifest {
+	return Manifest
 }
 
 func (p *WebhookPlugin) Init(ctx context.Context, logger *slog.Logger, registry core.PluginRegistry) error {
 	p.logger = logger
-	var subscribeProvided bool
-	var subscribePatterns []string
+	p.registry = registry
+	var cfg map[string]map[string]any
+	var reg *metrics.Registry
 	if registry != nil {
-		cfg := registry.GetConfig()
-		if section, ok := cfg["webhook"]; ok {
-			if _, ok := section["subscribe"]; ok {
-				subscribeProvided = true
-			}
-			var wcfg webhookConfig
-			if err := core.DecodeConfigSection(section, &wcfg); err != nil {
-				p.logger.Warn("Invalid webhook config", "error", err)
-			}
-			p.url = wcfg.URL
-			subscribePatterns = parseSubscribePatterns(section)
-		}
+		cfg = registry.GetConfig()
 		p.client = registry.GetHTTPClient()
+		reg = registry.Metrics()
 	}
+	p.sent = core.NotificationsCounter(reg)
 	if p.client == nil {
 		p.client = http.DefaultClient
 	}
+	p.applyConfig(ctx, cfg)
+	return nil
+}
+
+// Reconfigure applies a changed webhook section in place. Patterns removed
+// from subscribe stop delivering; new patterns are subscribed.
+func (p *WebhookPlugin) Reconfigure(ctx context.Context, cfg map[string]map[string]any) error {
+	p.applyConfig(ctx, cfg)
+	return nil
+}
+
+func (p *WebhookPlugin) applyConfig(ctx context.Context, cfg map[string]map[string]any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var subscribeProvided bool
+	var subscribePatterns []string
+	p.url, p.subscriptions = "", nil
+	if section, ok := cfg["webhook"]; ok {
+		if _, ok := section["subscribe"]; ok {
+			subscribeProvided = true
+		}
+		var wcfg webhookConfig
+		if err := core.DecodeConfigSection(section, &wcfg); err != nil {
+			p.logger.Warn("Invalid webhook config", "error", err)
+		}
+		p.url = wcfg.URL
+		subscribePatterns = parseSubscribePatterns(section)
+	}
 	if p.url == "" {
 		p.logger.Warn("NOTIFY_WEBHOOK_URL not set, webhook notifications disabled")
 		p.enabled = false
-		return nil
+		p.unsubscribe(nil)
+		return
 	}
 
 	p.enabled = true
 	p.logger.Info("Webhook Plugin Initialized", "url", p.url)
-	if registry != nil {
+	if p.registry != nil {
 		if !subscribeProvided {
 			subscribePatterns = []string{"notify_*"}
 		}
 		p.subscriptions = append([]string(nil), subscribePatterns...)
+		p.unsubscribe(subscribePatterns)
 		for _, pattern := range subscribePatterns {
-			registry.Subscribe(pattern, p.process)
+			p.subscribe(pattern)
 		}
 		if len(subscribePatterns) == 0 {
 			p.logger.InfoContext(ctx, "Webhook notifier has no subscriptions configured; skipping event registration")
 		}
 	}
-	return nil
+}
+
+// subscribe registers pattern with the event bus once. Callers hold p.mu.
+func (p *WebhookPlugin) subscribe(pattern string) {
+	if _, ok := p.subscribed[pattern]; ok {
+		return
+	}
+	if p.subscribed == nil {
+		p.subscribed = map[string]core.Subscription{}
+	}
+	p.subscribed[pattern] = p.registry.Subscribe(pattern, p.process)
+}
+
+// unsubscribe drops the subscriptions whose pattern is not in keep. Callers
+// hold p.mu.
+func (p *WebhookPlugin) unsubscribe(keep []string) {
+	for pattern, sub := range p.subscribed {
+		if !slices.Contains(keep, pattern) {
+			sub.Unsubscribe()
+			delete(p.subscribed, pattern)
+		}
+	}
+}
+
+func (p *WebhookPlugin) targetURL() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.url
 }
 
 func (p *WebhookPlugin) Start(ctx context.Context) error {
@@ -80,17 +153,21 @@ func (p *WebhookPlugin) Start(ctx context.Context) error {
 }
 
 func (p *WebhookPlugin) Stop(ctx context.Context) error {
-	// No services to stop
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unsubscribe(nil)
 	return nil
 }
 
 func (p *WebhookPlugin) Description() string { return "Generic webhook notifier" }
 
 func (p *WebhookPlugin) Capabilities() []core.Capability {
-	return []core.Capability{core.CapabilityNotifier}
+	return Manifest.Capabilities
 }
 
 func (p *WebhookPlugin) Status() core.ServiceStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	if p.enabled && p.url != "" {
 		return core.StatusHealthy
 	}
@@ -98,7 +175,7 @@ func (p *WebhookPlugin) Status() core.ServiceStatus {
 }
 
 func (p *WebhookPlugin) Execute(ctx context.Context, action string, params map[string]interface{}) (interface{}, error) {
-	if p.url == "" {
+	if p.targetURL() == "" {
 		p.logger.Debug("Webhook URL not set, skipping notification")
 		return nil, nil // silent skip if not set
 	}
@@ -123,7 +200,21 @@ func (p *WebhookPlugin) Execute(ctx context.Context, action string, params map[s
 	return map[string]string{"status": "delivered"}, nil
 }
 
-var Plugin core.Plugin = &WebhookPlugin{}
+// Manifest of the generic webhook notifier, registered as "webhook".
+var Manifest = core.PluginManifest{
+	Name:         "webhook",
+	Version:      core.Version,
+	APIVersion:   core.APIVersion,
+	Capabilities: []core.Capability{core.CapabilityNotifier},
+}
+
+func init() {
+	core.RegisterBuiltin(Manifest.Name, New)
+}
+
+func New() core.Plugin {
+	return &WebhookPlugin{}
+}
 
 type webhookConfigView struct {
 	URL       core.Secret `json:"url"`
@@ -132,6 +223,8 @@ type webhookConfigView struct {
 }
 
 func (p *WebhookPlugin) Config() any {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	return webhookConfigView{
 		URL:       core.NewSecret(p.url),
 		Subscribe: append([]string(nil), p.subscriptions...),
@@ -140,12 +233,18 @@ func (p *WebhookPlugin) Config() any {
 }
 
 func (p *WebhookPlugin) process(ctx context.Context, event core.InternalEvent) {
-	if !p.enabled || p.url == "" {
+	p.mu.RLock()
+	enabled := p.enabled && p.url != ""
+	p.mu.RUnlock()
+	if !enabled {
 		return
 	}
 	if err := p.send(ctx, event); err != nil {
+		p.sent.With(Manifest.Name, "failed").Inc()
 		p.logger.ErrorContext(ctx, "Webhook notification failed", "error", err)
+		return
 	}
+	p.sent.With(Manifest.Name, "sent").Inc()
 }
 
 func (p *WebhookPlugin) send(ctx context.Context, event core.InternalEvent) error {
@@ -165,7 +264,7 @@ func (p *WebhookPlugin) send(ctx context.Context, event core.InternalEvent) erro
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewBuffer(data))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.targetURL(), bytes.NewBuffer(data))
 	if err != nil {
 		return err
 	}
@@ -207,7 +306,8 @@ func parseSubscribePatterns(section map[string]any) []string {
 	if !ok {
 		return nil
 	}
-	switch v := raw.(type) {
+	// A value from a ${...} reference is a core.Secret.
+	switch v := core.UnwrapSecrets(raw).(type) {
 	case []string:
 		return normalizePatterns(v)
 	case []any:
@@ -223,36 +323,3 @@ func parseSubscribePatterns(section map[string]any) []string {
 		return normalizePatterns([]string{fmt.Sprint(v)})
 	}
 }
-
-// Main for standalone testing
-func main() {
-	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	p := &WebhookPlugin{}
-	ctx := context.Background()
-	if err := p.Init(ctx, logger, nil); err != nil { // nil registry for testing
-		logger.Error("Init failed", "error", err)
-		return
-	}
-	if err := p.Start(ctx); err != nil {
-		logger.Error("Start failed", "error", err)
-		return
-	}
-
-	// Test Execute
-	//event := core.Event{
-	//	Type:    "test",
-	//	Owner:   "owner",
-	//	Repo:    "repo",
-	//	Message: "message",
-	//	Details: map[string]interface{}{"key": "value"},
-	//}
-	//params := map[string]interface{}{"event": event}
-	//result, err := p.Execute(ctx, "notify", params)
-	//if err != nil {
-	//	logger.Error("Execute failed", "error", err)
-	//} else {
-	//	logger.Info("Execute result", "result", result)
-	//}
-
-	p.Stop(ctx)
-}
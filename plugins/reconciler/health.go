@@ -0,0 +1,86 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/mywio/git-ops/pkg/core"
+)
+
+// githubProbeTTL is how long a GitHub reachability probe is reused, so
+// frequent /readyz polls do not each call the API.
+const githubProbeTTL = 30 * time.Second
+
+// githubProbe is the result of the last reachability probe with client.
+type githubProbe struct {
+	client *github.Client
+	at     time.Time
+	err    error
+}
+
+// recordPass remembers a completed full reconciliation for readiness.
+func (r *Reconciler) recordPass(summary *ReconcileSummary) {
+	r.healthMu.Lock()
+	defer r.healthMu.Unlock()
+	r.lastPass = time.Now()
+	r.lastSummary = summary
+}
+
+// StatusReport implements core.StatusReporter.
+func (r *Reconciler) StatusReport() core.StatusReport {
+	if !r.started {
+		return core.StatusReport{Status: core.StatusDegraded, Reason: "not started"}
+	}
+	r.healthMu.Lock()
+	defer r.healthMu.Unlock()
+	if r.lastSummary == nil {
+		return core.StatusReport{Status: core.StatusHealthy, Reason: "waiting for the first reconciliation"}
+	}
+	s := r.lastSummary
+	return core.StatusReport{Status: core.StatusHealthy, Reason: fmt.Sprintf("last reconciliation at %s: %d deployed, %d failed, %d errors",
+		r.lastPass.Format(time.RFC3339), len(s.Deployed), len(s.Failed), len(s.Errors))}
+}
+
+// ReadinessChecks implements core.ReadinessChecker: the reconciler is ready
+// once a full reconciliation completed and GitHub answers.
+func (r *Reconciler) ReadinessChecks(ctx context.Context) []core.ReadinessCheck {
+	r.healthMu.Lock()
+	lastPass := r.lastPass
+	r.healthMu.Unlock()
+
+	first := core.ReadinessCheck{Name: "first_reconciliation", OK: !lastPass.IsZero()}
+	if first.OK {
+		first.Reason = "completed at " + lastPass.Format(time.RFC3339)
+	} else {
+		first.Reason = "no reconciliation has completed yet"
+	}
+
+	reachable := core.ReadinessCheck{Name: "github", OK: true, Reason: "reachable"}
+	if err := r.probeGitHub(ctx); err != nil {
+		reachable.OK, reachable.Reason = false, "unreachable: "+err.Error()
+	}
+	return []core.ReadinessCheck{first, reachable}
+}
+
+// probeGitHub checks that the GitHub API answers, reusing a recent result.
+// It queries the rate limit, which does not count against it.
+func (r *Reconciler) probeGitHub(ctx context.Context) error {
+	client := r.githubClient()
+	if client == nil {
+		return fmt.Errorf("no GitHub client")
+	}
+	r.healthMu.Lock()
+	probe := r.githubProbe
+	r.healthMu.Unlock()
+	if probe.client == client && time.Since(probe.at) < githubProbeTTL {
+		return probe.err
+	}
+
+	_, _, err := client.RateLimit.Get(ctx)
+	r.healthMu.Lock()
+	r.githubProbe = githubProbe{client: client, at: time.Now(), err: err}
+	r.healthMu.Unlock()
+	return err
+}
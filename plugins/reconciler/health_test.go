@@ -0,0 +1,61 @@
+package reconciler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/mywio/git-ops/pkg/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fakeGitHub(t *testing.T, status int) (*github.Client, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		assert.Equal(t, "/rate_limit", r.URL.Path)
+		w.WriteHeader(status)
+		w.Write([]byte(`{"resources":{}}`))
+	}))
+	t.Cleanup(srv.Close)
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	return client, &calls
+}
+
+func TestReadinessChecks(t *testing.T) {
+	client, calls := fakeGitHub(t, http.StatusOK)
+	r := &Reconciler{client: client, started: true}
+
+	checks := r.ReadinessChecks(context.Background())
+	require.Len(t, checks, 2)
+	assert.Equal(t, "first_reconciliation", checks[0].Name)
+	assert.False(t, checks[0].OK)
+	assert.Equal(t, core.ReadinessCheck{Name: "github", OK: true, Reason: "reachable"}, checks[1])
+	assert.Equal(t, "waiting for the first reconciliation", r.StatusReport().Reason)
+
+	r.recordPass(&ReconcileSummary{Deployed: []string{"me/web"}})
+	checks = r.ReadinessChecks(context.Background())
+	assert.True(t, checks[0].OK)
+	assert.Equal(t, int32(1), calls.Load(), "the GitHub probe is cached")
+	assert.Contains(t, r.StatusReport().Reason, "1 deployed, 0 failed")
+
+	// A new client, e.g. after a token change, is probed again.
+	r.client, calls = fakeGitHub(t, http.StatusUnauthorized)
+	checks = r.ReadinessChecks(context.Background())
+	assert.False(t, checks[1].OK)
+	assert.Contains(t, checks[1].Reason, "unreachable: ")
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestStatusReport_NotStarted(t *testing.T) {
+	r := &Reconciler{}
+	assert.Equal(t, core.StatusReport{Status: core.StatusDegraded, Reason: "not started"}, r.StatusReport())
+	assert.Equal(t, r.Status(), r.StatusReport().Status)
+}
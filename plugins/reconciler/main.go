@@ -1,4 +1,4 @@
-package main
+package reconciler
 
 import (
 	"context"
@@ -15,11 +15,13 @@ import (
 	"github.com/google/go-github/v57/github"
 	"github.com/mywio/git-ops/pkg/config"
 	"github.com/mywio/git-ops/pkg/core"
+	"github.com/mywio/git-ops/pkg/metrics"
 	"github.com/mywio/git-ops/pkg/utils"
 	"golang.org/x/oauth2"
 )
 
 type Reconciler struct {
+	mu       sync.RWMutex // guards cfg, client and ticker
 	cfg      config.Config
 	client   *github.Client
 	logger   *slog.Logger
@@ -28,14 +30,38 @@ type Reconciler struct {
 	wg       sync.WaitGroup
 	ticker   *time.Ticker
 	started  bool
+
+	healthMu    sync.Mutex // guards lastPass, lastSummary and githubProbe
+	lastPass    time.Time
+	lastSummary *ReconcileSummary
+	githubProbe githubProbe
+
+	metrics *reconcilerMetrics
+}
+
+// Manifest of the reconciler. It declares no capabilities; core finds it by
+// name.
+var Manifest = core.PluginManifest{
+	Name:       "reconciler",
+	Version:    core.Version,
+	APIVersion: core.APIVersion,
+}
+
+func init() {
+	core.RegisterBuiltin(Manifest.Name, New)
 }
 
-var Plugin core.Plugin = &Reconciler{
-	stopCh: make(chan struct{}),
+func New() core.Plugin {
+	return &Reconciler{}
 }
 
 func (r *Reconciler) Name() string {
-	return "reconciler"
+	return Manifest.Name
+}
+
+// Manifest implements core.ManifestProvider.
+func (r *Reconciler) Manifest() core.PluginManifest {
+	return Manifest
 }
 
 func (r *Reconciler) Description() string {
@@ -43,7 +69,16 @@ func (r *Reconciler) Description() string {
 }
 
 func (r *Reconciler) Capabilities() []core.Capability {
-	return []core.Capability{}
+	return Manifest.Capabilities
+}
+
+// Dependencies orders secret and runtime file providers before the
+// reconciler so they are ready when the first sync runs.
+func (r *Reconciler) Dependencies() []core.Dependency {
+	return []core.Dependency{
+		{Capability: core.CapabilitySecrets, Optional: true},
+		{Capability: core.CapabilityRuntimeFiles, Optional: true},
+	}
 }
 
 func (r *Reconciler) Status() core.ServiceStatus {
@@ -53,8 +88,21 @@ func (r *Reconciler) Status() core.ServiceStatus {
 	return core.StatusDegraded
 }
 
+// Execute runs reconciler actions:
+//
+//   - reconcile: a full reconciliation; it returns the *ReconcileSummary
+//     when the run is done.
+//   - reconcile_stack: reconcile one stack (owner, repo, optional force_type).
+//     It runs in the background unless wait is true, in which case it
+//     returns the *ReconcileSummary when the stack is deployed and fails if
+//     it cannot be found.
 func (r *Reconciler) Execute(ctx context.Context, action string, params map[string]interface{}) (interface{}, error) {
 	switch action {
+	case "reconcile":
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		return r.runReconcile(ctx), nil
 	case "reconcile_stack":
 		owner, okOwner := params["owner"].(string)
 		repo, okRepo := params["repo"].(string)
@@ -69,6 +117,13 @@ func (r *Reconciler) Execute(ctx context.Context, action string, params map[stri
 			triggerCtx = ctx
 		}
 
+		if wait, _ := params["wait"].(bool); wait {
+			summary, err := r.runReconcileStack(triggerCtx, owner, repo, forceType)
+			if err != nil {
+				return nil, err
+			}
+			return summary, nil
+		}
 		go r.runReconcileStack(triggerCtx, owner, repo, forceType)
 		return true, nil
 	default:
@@ -76,10 +131,98 @@ func (r *Reconciler) Execute(ctx context.Context, action string, params map[stri
 	}
 }
 
+// Actions implements core.ActionProvider.
+func (r *Reconciler) Actions() []core.ActionDesc {
+	return []core.ActionDesc{
+		{Name: "reconcile", Description: "Run a full reconciliation and return its summary", Scope: core.ScopeTrigger},
+		{Name: "reconcile_stack", Description: "Reconcile one stack", Scope: core.ScopeTrigger, Params: []core.ConfigField{
+			{Name: "owner", Type: core.ConfigString, Required: true},
+			{Name: "repo", Type: core.ConfigString, Required: true},
+			{Name: "force_type", Type: core.ConfigString, Enum: []string{"bypass_check", "clean_local_state", "remove_images", "restart_only"}},
+			{Name: "wait", Type: core.ConfigBool, Description: "Return the summary once the stack is deployed"},
+		}},
+	}
+}
+
 func (r *Reconciler) Config() any {
+	return r.config()
+}
+
+// ConfigSections reports that the reconciler is configured from the core section.
+func (r *Reconciler) ConfigSections() []string {
+	return []string{"core"}
+}
+
+func (r *Reconciler) config() config.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	return r.cfg
 }
 
+func (r *Reconciler) githubClient() *github.Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.client
+}
+
+// ConfigSchema implements core.ConfigSchemaProvider. The reconciler reads
+// its settings from the core section.
+func (r *Reconciler) ConfigSchema() []core.ConfigSchema {
+	return []core.ConfigSchema{{
+		Section: "core",
+		Fields: []core.ConfigField{
+			{Name: "token", Aliases: []string{"github_token"}, Type: core.ConfigString, Required: true, Secret: true, Description: "GitHub token with repo scope"},
+			{Name: "users", Aliases: []string{"github_users"}, Type: core.ConfigCommaList, Description: "Users and orgs to scan"},
+			{Name: "topic", Aliases: []string{"topic_filter"}, Type: core.ConfigString, Description: "Repository topic to deploy"},
+			{Name: "target_dir", Type: core.ConfigString, Default: "./stacks", Description: "Local path to store stacks"},
+			{Name: "interval", Aliases: []string{"sync_interval"}, Type: core.ConfigDuration, Default: "5m", Description: "Reconcile interval"},
+			{Name: "dry_run", Type: core.ConfigBool, Default: false},
+			{Name: "global_hooks_dir", Type: core.ConfigString},
+			{Name: "secrets_dir", Type: core.ConfigString},
+			{Name: "secret_precedence", Type: core.ConfigCommaList, Description: "Secret plugins, highest precedence first"},
+		},
+	}}
+}
+
+// loadConfig builds the reconciler config from the core section of the
+// merged config (environment, file and overrides; see config.LoadCoreConfig).
+func loadConfig(cfgMap map[string]map[string]any) (config.Config, error) {
+	cfg, err := config.LoadCoreConfig(cfgMap, nil)
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.Token == "" {
+		return cfg, fmt.Errorf("missing GITHUB_TOKEN")
+	}
+	return cfg, nil
+}
+
+// Reconfigure applies a new core config without interrupting a running
+// reconciliation; the next run picks up the new settings.
+func (r *Reconciler) Reconfigure(ctx context.Context, cfgMap map[string]map[string]any) error {
+	cfg, err := loadConfig(cfgMap)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cfg.Token != r.cfg.Token {
+		r.client = newGitHubClient(ctx, cfg.Token)
+	}
+	if r.ticker != nil && cfg.Interval != r.cfg.Interval {
+		r.ticker.Reset(cfg.Interval)
+	}
+	r.cfg = cfg
+	r.logger.Info("Reconciler reconfigured", "users", cfg.Users, "topic", cfg.Topic, "interval", cfg.Interval)
+	return nil
+}
+
+func newGitHubClient(ctx context.Context, token string) *github.Client {
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	return github.NewClient(oauth2.NewClient(ctx, ts))
+}
+
 func (r *Reconciler) handleReconcileNowEvent(ctx context.Context, event core.InternalEvent) {
 	r.logger.Info("Received reconcile_now event, triggering reconciliation", "source", event.Source)
 	go r.runReconcile(ctx)
@@ -102,17 +245,16 @@ func (r *Reconciler) Init(ctx context.Context, logger *slog.Logger, registry cor
 	r.logger = logger
 	r.registry = registry
 
+	var cfgMap map[string]map[string]any
+	var reg *metrics.Registry
 	if registry != nil {
-		cfgMap := registry.GetConfig()
-		if coreSection, ok := cfgMap["core"]; ok {
-			r.cfg = config.LoadConfigFromMap(coreSection)
-		}
+		cfgMap = registry.GetConfig()
+		reg = registry.Metrics()
 	}
-	envCfg := config.LoadConfig()
-	r.cfg = config.MergeConfig(r.cfg, envCfg)
-
-	if r.cfg.Token == "" {
-		return fmt.Errorf("missing GITHUB_TOKEN")
+	r.metrics = newReconcilerMetrics(reg)
+	cfg, err := loadConfig(cfgMap)
+	if err != nil {
+		return err
 	}
 
 	// Register Events
@@ -165,13 +307,10 @@ func (r *Reconciler) Init(ctx context.Context, logger *slog.Logger, registry cor
 		registry.Subscribe("reconcile_stack", r.handleReconcileStackEvent)
 	}
 
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: r.cfg.Token})
-	client := github.NewClient(oauth2.NewClient(ctx, ts))
-	r.client = client
-
-	if r.cfg.TargetDir == "" {
-		r.cfg.TargetDir = "./stacks"
-	}
+	r.mu.Lock()
+	r.cfg = cfg
+	r.client = newGitHubClient(ctx, cfg.Token)
+	r.mu.Unlock()
 
 	return nil
 }
@@ -181,9 +320,16 @@ func (r *Reconciler) Start(ctx context.Context) error {
 		return nil
 	}
 	r.started = true
+	r.stopCh = make(chan struct{})
+	stopCh := r.stopCh
 
-	r.logger.Info("Starting Reconciler", "users", r.cfg.Users, "topic", r.cfg.Topic)
-	r.ticker = time.NewTicker(r.cfg.Interval)
+	r.mu.Lock()
+	cfg := r.cfg
+	r.ticker = time.NewTicker(cfg.Interval)
+	ticker := r.ticker
+	r.mu.Unlock()
+
+	r.logger.Info("Starting Reconciler", "users", cfg.Users, "topic", cfg.Topic)
 
 	go func() {
 		// Run once immediately
@@ -191,13 +337,13 @@ func (r *Reconciler) Start(ctx context.Context) error {
 
 		for {
 			select {
-			case <-r.ticker.C:
+			case <-ticker.C:
 				r.runReconcile(ctx)
-			case <-r.stopCh:
-				r.ticker.Stop()
+			case <-stopCh:
+				ticker.Stop()
 				return
 			case <-ctx.Done():
-				r.ticker.Stop()
+				ticker.Stop()
 				return
 			}
 		}
@@ -210,6 +356,7 @@ func (r *Reconciler) Stop(ctx context.Context) error {
 	if !r.started {
 		return nil
 	}
+	r.started = false
 	close(r.stopCh)
 	r.logger.Info("Waiting for reconciliation to finish...")
 
@@ -231,13 +378,28 @@ func (r *Reconciler) Stop(ctx context.Context) error {
 	return nil
 }
 
-func (r *Reconciler) runReconcile(ctx context.Context) {
+// runReconcile runs a full reconciliation and logs its summary.
+func (r *Reconciler) runReconcile(ctx context.Context) *ReconcileSummary {
 	r.wg.Add(1)
 	defer r.wg.Done()
-	r.reconcile(ctx)
+	start := time.Now()
+	summary := r.reconcile(ctx)
+	r.recordPass(summary)
+	r.metrics.observeRun(summary, time.Since(start))
+	if summary.OK() {
+		r.logger.Info("Reconciliation complete", summary.logArgs()...)
+	} else {
+		r.logger.Error("Reconciliation completed with failures", append(summary.logArgs(), "failures", summary.Failed, "errors_detail", summary.Errors)...)
+	}
+	return summary
 }
 
-func (r *Reconciler) reconcile(ctx context.Context) {
+func (r *Reconciler) reconcile(ctx context.Context) *ReconcileSummary {
+	cfg := r.config()
+	start := time.Now()
+	summary := newSummary(cfg.DryRun)
+	defer summary.finish(start)
+
 	// 1. Build Desired State (What should exist)
 	// Map Key: "Owner/RepoName"
 	desiredState := make(map[string]*github.Repository)
@@ -245,29 +407,35 @@ func (r *Reconciler) reconcile(ctx context.Context) {
 	// 2. Build Removal State (What should be explicitly removed)
 	removalState := make(map[string]bool)
 
-	for _, user := range r.cfg.Users {
+	for _, user := range cfg.Users {
 		if user == "" {
 			continue
 		}
 
 		// Query 1: Desired State (user:NAME topic:TAG archived:false)
-		queryDesired := fmt.Sprintf("user:%s topic:%s archived:false", user, r.cfg.Topic)
-		r.fetchReposInto(ctx, queryDesired, desiredState)
+		queryDesired := fmt.Sprintf("user:%s topic:%s archived:false", user, cfg.Topic)
+		if err := r.fetchReposInto(ctx, queryDesired, desiredState); err != nil {
+			summary.addError(err)
+		}
 
 		// Query 2: Removal Candidates - Topic "git-ops-remove"
 		queryRemoveTopic := fmt.Sprintf("user:%s topic:git-ops-remove", user)
-		r.fetchRemovalInto(ctx, queryRemoveTopic, removalState)
+		if err := r.fetchRemovalInto(ctx, queryRemoveTopic, removalState); err != nil {
+			summary.addError(err)
+		}
 
 		// Query 3: Removal Candidates - Archived but with main Topic
 		// Note: searching for archived:true explicitly
-		queryArchived := fmt.Sprintf("user:%s topic:%s archived:true", user, r.cfg.Topic)
-		r.fetchRemovalInto(ctx, queryArchived, removalState)
+		queryArchived := fmt.Sprintf("user:%s topic:%s archived:true", user, cfg.Topic)
+		if err := r.fetchRemovalInto(ctx, queryArchived, removalState); err != nil {
+			summary.addError(err)
+		}
 	}
 
 	r.logger.Info("State calculated", "desired", len(desiredState), "removal", len(removalState))
 
 	// 3. Process Local State (The "Kill Switch" Logic)
-	r.processLocalState(desiredState, removalState)
+	r.processLocalState(desiredState, removalState, summary)
 
 	// 4. Deploy Phase (Update/Create what should exist)
 	for fullName, repo := range desiredState {
@@ -279,67 +447,87 @@ func (r *Reconciler) reconcile(ctx context.Context) {
 		// Let's assume Removal trumps Desired.
 		if removalState[fullName] {
 			r.logger.Warn("Repo found in both Desired and Removal state, skipping deploy", "repo", fullName)
+			summary.record(fullName, outcomeSkipped, nil)
 			continue
 		}
-		r.deployRepo(ctx, fullName, repo, "")
+		o, err := r.deployRepo(ctx, fullName, repo, "")
+		summary.record(fullName, o, err)
 	}
+	return summary
 }
 
-func (r *Reconciler) runReconcileStack(ctx context.Context, owner, repo, forceType string) {
+// runReconcileStack deploys one stack. It fails if the stack cannot be
+// found; deploy failures are reported in the summary.
+func (r *Reconciler) runReconcileStack(ctx context.Context, owner, repo, forceType string) (*ReconcileSummary, error) {
+	cfg := r.config()
 	r.wg.Add(1)
 	defer r.wg.Done()
 
+	start := time.Now()
+	summary := newSummary(cfg.DryRun)
+	defer summary.finish(start)
 	fullName := fmt.Sprintf("%s/%s", owner, repo)
 
 	// Query to check if the specific repo is marked for gitops
-	queryDesired := fmt.Sprintf("repo:%s topic:%s archived:false", fullName, r.cfg.Topic)
+	queryDesired := fmt.Sprintf("repo:%s topic:%s archived:false", fullName, cfg.Topic)
 	desiredState := make(map[string]*github.Repository)
-	r.fetchReposInto(ctx, queryDesired, desiredState)
+	if err := r.fetchReposInto(ctx, queryDesired, desiredState); err != nil {
+		return nil, err
+	}
 
 	if len(desiredState) == 0 {
 		r.logger.Warn("Stack not found or not tagged for git-ops, cannot reconcile", "owner", owner, "repo", repo)
-		return
+		return nil, fmt.Errorf("stack %s not found or not tagged with topic %q", fullName, cfg.Topic)
 	}
 
 	repository := desiredState[fullName]
 	if repository == nil {
 		r.logger.Warn("Stack not found in query results", "owner", owner, "repo", repo)
-		return
+		return nil, fmt.Errorf("stack %s not found in query results", fullName)
 	}
 
 	r.logger.Info("Targeted stack reconciliation initiated", "service", fullName, "force_type", forceType)
-	r.deployRepo(ctx, fullName, repository, forceType)
+	o, err := r.deployRepo(ctx, fullName, repository, forceType)
+	summary.record(fullName, o, err)
+	return summary, nil
 }
 
-func (r *Reconciler) fetchReposInto(ctx context.Context, query string, target map[string]*github.Repository) {
+func (r *Reconciler) fetchReposInto(ctx context.Context, query string, target map[string]*github.Repository) error {
+	client := r.githubClient()
 	opts := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 100}}
-	repos, _, err := r.client.Search.Repositories(ctx, query, opts)
+	repos, _, err := client.Search.Repositories(ctx, query, opts)
 	if err != nil {
+		r.metrics.searchErrors.With().Inc()
 		r.logger.Error("Search failed", "query", query, "error", err)
-		return
+		return fmt.Errorf("search %q: %w", query, err)
 	}
 	for _, repo := range repos.Repositories {
 		fullName := fmt.Sprintf("%s/%s", *repo.Owner.Login, *repo.Name)
 		target[fullName] = repo
 	}
+	return nil
 }
 
-func (r *Reconciler) fetchRemovalInto(ctx context.Context, query string, target map[string]bool) {
+func (r *Reconciler) fetchRemovalInto(ctx context.Context, query string, target map[string]bool) error {
+	client := r.githubClient()
 	opts := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 100}}
-	repos, _, err := r.client.Search.Repositories(ctx, query, opts)
+	repos, _, err := client.Search.Repositories(ctx, query, opts)
 	if err != nil {
+		r.metrics.searchErrors.With().Inc()
 		r.logger.Error("Search failed", "query", query, "error", err)
-		return
+		return fmt.Errorf("search %q: %w", query, err)
 	}
 	for _, repo := range repos.Repositories {
 		fullName := fmt.Sprintf("%s/%s", *repo.Owner.Login, *repo.Name)
 		target[fullName] = true
 	}
+	return nil
 }
 
-func (r *Reconciler) processLocalState(desiredState map[string]*github.Repository, removalState map[string]bool) {
+func (r *Reconciler) processLocalState(desiredState map[string]*github.Repository, removalState map[string]bool, summary *ReconcileSummary) {
+	cfg := r.config()
 	// Walk TARGET_DIR/OWNER/REPO
-	entries, err := os.ReadDir(r.cfg.TargetDir)
+	entries, err := os.ReadDir(cfg.TargetDir)
 	if os.IsNotExist(err) {
 		return
 	}
@@ -349,7 +537,7 @@ func (r *Reconciler) processLocalState(desiredState map[string]*github.Repositor
 			continue
 		}
 
-		userPath := filepath.Join(r.cfg.TargetDir, userDir.Name())
+		userPath := filepath.Join(cfg.TargetDir, userDir.Name())
 		repos, _ := os.ReadDir(userPath)
 
 		for _, repoDir := range repos {
@@ -366,20 +554,26 @@ func (r *Reconciler) processLocalState(desiredState map[string]*github.Repositor
 
 			if isRemoval {
 				r.logger.Info("Explicit removal detected", "service", currentKey)
-				r.pruneService(fullPath)
+				if err := r.pruneService(fullPath); err != nil {
+					summary.record(currentKey, outcomeFailed, err)
+				} else {
+					summary.removed(currentKey)
+				}
 			} else if !isDesired {
 				// Exists locally, but NOT in Desired, and NOT in Removal.
 				// This is the "Safety Warning" - Do NOT Delete.
 				r.logger.Warn("Sync Divergence: Local service exists but not found in Desired State. Skipping removal.", "service", currentKey)
+				summary.diverged(currentKey)
 			}
 		}
 	}
 }
 
-func (r *Reconciler) pruneService(path string) {
-	if r.cfg.DryRun {
+func (r *Reconciler) pruneService(path string) error {
+	cfg := r.config()
+	if cfg.DryRun {
 		r.logger.Info("DryRun: Would remove service", "path", path)
-		return
+		return nil
 	}
 
 	// Docker Down
@@ -390,41 +584,47 @@ func (r *Reconciler) pruneService(path string) {
 	// Delete Folder
 	if err := os.RemoveAll(path); err != nil {
 		r.logger.Error("Failed to remove service folder", "path", path, "error", err)
+		return fmt.Errorf("remove %s: %w", path, err)
 	}
+	return nil
 }
 
-func (r *Reconciler) deployRepo(ctx context.Context, fullName string, repo *github.Repository, forceType string) {
+// deployRepo syncs one stack and reports whether it was deployed, unchanged
+// or skipped; a failed deploy returns outcomeFailed and the error.
+func (r *Reconciler) deployRepo(ctx context.Context, fullName string, repo *github.Repository, forceType string) (outcome, error) {
+	cfg := r.config()
+	client := r.githubClient()
 	logger := r.logger.With("service", fullName)
 
 	// Fetch docker-compose.yml
-	fileContent, _, _, err := r.client.Repositories.GetContents(ctx, *repo.Owner.Login, *repo.Name, "docker-compose.yml", nil)
+	fileContent, _, _, err := client.Repositories.GetContents(ctx, *repo.Owner.Login, *repo.Name, "docker-compose.yml", nil)
 	if err != nil {
 		if strings.Contains(err.Error(), "404") {
 			logger.Debug("No docker-compose.yml found, skipping")
-		} else {
-			logger.Error("Failed to fetch file", "error", err)
+			return outcomeSkipped, nil
 		}
-		return
+		logger.Error("Failed to fetch file", "error", err)
+		return outcomeFailed, fmt.Errorf("fetch docker-compose.yml: %w", err)
 	}
 
 	content, err := fileContent.GetContent()
 	if err != nil {
-		return
+		return outcomeFailed, fmt.Errorf("decode docker-compose.yml: %w", err)
 	}
 
 	// Structure: TARGET_DIR / OWNER / REPO / docker-compose.yml
-	repoLocalPath := filepath.Join(r.cfg.TargetDir, *repo.Owner.Login, *repo.Name)
+	repoLocalPath := filepath.Join(cfg.TargetDir, *repo.Owner.Login, *repo.Name)
 	filePath := filepath.Join(repoLocalPath, "docker-compose.yml")
 
 	if forceType == "clean_local_state" {
 		logger.Info("Cleaning local state before deploy", "force_type", forceType)
-		if !r.cfg.DryRun {
+		if !cfg.DryRun {
 			os.Remove(filePath)
 			os.RemoveAll(filepath.Join(repoLocalPath, ".deploy"))
 		}
 	} else if forceType == "remove_images" {
 		logger.Info("Removing local images before deploy", "force_type", forceType)
-		if !r.cfg.DryRun {
+		if !cfg.DryRun {
 			// Try to bring it down and remove images
 			cmd := exec.Command("docker", "compose", "down", "--rmi", "all", "--remove-orphans")
 			cmd.Dir = repoLocalPath
@@ -432,17 +632,18 @@ func (r *Reconciler) deployRepo(ctx context.Context, fullName string, repo *gith
 		}
 	} else if forceType == "restart_only" {
 		logger.Info("Restarting stack containers", "force_type", forceType)
-		if !r.cfg.DryRun {
+		if !cfg.DryRun {
 			cmd := exec.Command("docker", "compose", "restart")
 			cmd.Dir = repoLocalPath
 			if err := cmd.Run(); err != nil {
 				logger.Error("Restart failed", "error", err)
+				return outcomeFailed, fmt.Errorf("docker compose restart: %w", err)
 			}
 		}
-		return // Do not process file changes
+		return outcomeDeployed, nil // Do not process file changes
 	}
 
-	if !r.cfg.DryRun {
+	if !cfg.DryRun {
 		os.MkdirAll(repoLocalPath, 0755)
 	}
 
@@ -450,7 +651,7 @@ func (r *Reconciler) deployRepo(ctx context.Context, fullName string, repo *gith
 	existing, _ := os.ReadFile(filePath)
 	if string(existing) == content && forceType == "" {
 		// No force type specified and no changes detected
-		return
+		return outcomeUnchanged, nil
 	}
 
 	if forceType != "" {
@@ -459,8 +660,8 @@ func (r *Reconciler) deployRepo(ctx context.Context, fullName string, repo *gith
 
 	logger.Info("Updating deployment")
 
-	if r.cfg.DryRun {
-		return
+	if cfg.DryRun {
+		return outcomeDeployed, nil
 	}
 
 	deployStart := time.Now()
@@ -469,7 +670,7 @@ func (r *Reconciler) deployRepo(ctx context.Context, fullName string, repo *gith
 	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
 		logger.Error("Failed to write docker-compose.yml", "error", err)
 		r.publishDeployEvent(ctx, "deploy_failed", repo, "failed", err.Error(), "", deployStart)
-		return
+		return outcomeFailed, err
 	}
 
 	// Fetch Repo Hooks (Pre & Post)
@@ -477,17 +678,17 @@ func (r *Reconciler) deployRepo(ctx context.Context, fullName string, repo *gith
 	if err != nil {
 		logger.Error("Global Fetch Pre-Hook failed, aborting deploy", "error", err)
 		r.publishDeployEvent(ctx, "deploy_failed", repo, "failed", err.Error(), "", deployStart)
-		return
+		return outcomeFailed, err
 	}
 	err = r.fetchRepoHooks(ctx, *repo.Owner.Login, *repo.Name, "post", repoLocalPath)
 	if err != nil {
 		logger.Error("Global Fetch Post-Hook failed, aborting deploy", "error", err)
 		r.publishDeployEvent(ctx, "deploy_failed", repo, "failed", err.Error(), "", deployStart)
-		return
+		return outcomeFailed, err
 	}
 
 	// Collect Secrets from Plugins
-	secretPlugins := r.registry.GetPluginsWithCapability(core.CapabilitySecrets)
+	secretPlugins := core.OrderByPrecedence(r.registry.GetPluginsWithCapability(core.CapabilitySecrets), cfg.SecretPrecedence)
 	secretEnv := []string{}
 	secretValues := make(map[string]string)
 	secretSources := make(map[string]string)
@@ -500,7 +701,7 @@ func (r *Reconciler) deployRepo(ctx context.Context, fullName string, repo *gith
 		if err != nil {
 			logger.Error("Failed to fetch secrets from plugin, aborting deploy", "plugin", p.Name(), "error", err)
 			r.publishDeployEvent(ctx, "deploy_failed", repo, "failed", err.Error(), "", deployStart)
-			return
+			return outcomeFailed, err
 		}
 
 		if secrets, ok := res.(map[string]string); ok {
@@ -508,7 +709,7 @@ func (r *Reconciler) deployRepo(ctx context.Context, fullName string, repo *gith
 				if _, exists := secretValues[k]; exists {
 					winner := secretSources[k]
 					logger.Warn("Duplicate secret key, skipping", "key", k, "winner", winner, "skipped", p.Name())
-					core.Publish(ctx, core.InternalEvent{
+					r.registry.Publish(ctx, core.InternalEvent{
 						Type:   "notify_secret_conflict",
 						Source: "reconciler",
 						String: fmt.Sprintf("Secret %s already provided by %s; skipping %s", k, winner, p.Name()),
@@ -540,7 +741,7 @@ func (r *Reconciler) deployRepo(ctx context.Context, fullName string, repo *gith
 	if err != nil {
 		logger.Error("Failed to collect runtime files from plugin, aborting deploy", "error", err)
 		r.publishDeployEvent(ctx, "deploy_failed", repo, "failed", err.Error(), "", deployStart)
-		return
+		return outcomeFailed, err
 	}
 
 	runtimeFileEnv := []string{}
@@ -550,7 +751,7 @@ func (r *Reconciler) deployRepo(ctx context.Context, fullName string, repo *gith
 		if err != nil {
 			logger.Error("Failed to materialize runtime files, aborting deploy", "error", err)
 			r.publishDeployEvent(ctx, "deploy_failed", repo, "failed", err.Error(), "", deployStart)
-			return
+			return outcomeFailed, err
 		}
 		defer cleanupRuntimeFiles()
 	}
@@ -570,19 +771,21 @@ func (r *Reconciler) deployRepo(ctx context.Context, fullName string, repo *gith
 	// Okay, strictly docker compose process.
 
 	// Run Global PRE Hooks
-	if r.cfg.GlobalHooksDir != "" {
-		if err := utils.ExecuteHooks(filepath.Join(r.cfg.GlobalHooksDir, "pre"), hookEnv, logger); err != nil {
+	if cfg.GlobalHooksDir != "" {
+		if err := utils.ExecuteHooks(filepath.Join(cfg.GlobalHooksDir, "pre"), hookEnv, logger); err != nil {
+			r.metrics.hookFailures.With(fullName, "global_pre").Inc()
 			logger.Error("Global Pre-hook failed, aborting deploy", "error", err)
 			r.publishDeployEvent(ctx, "deploy_failed", repo, "failed", err.Error(), "", deployStart)
-			return
+			return outcomeFailed, err
 		}
 	}
 
 	// Run Repo PRE Hooks
 	if err := utils.ExecuteHooks(filepath.Join(repoLocalPath, ".deploy", "pre"), hookEnv, logger); err != nil {
+		r.metrics.hookFailures.With(fullName, "repo_pre").Inc()
 		logger.Error("Repo Pre-hook failed, aborting deploy", "error", err)
 		r.publishDeployEvent(ctx, "deploy_failed", repo, "failed", err.Error(), "", deployStart)
-		return
+		return outcomeFailed, err
 	}
 
 	// Docker Compose Up
@@ -597,29 +800,33 @@ func (r *Reconciler) deployRepo(ctx context.Context, fullName string, repo *gith
 	if err := cmd.Run(); err != nil {
 		logger.Error("Deploy failed", "error", err)
 		r.publishDeployEvent(ctx, "deploy_failed", repo, "failed", err.Error(), "", deployStart)
-		return
+		return outcomeFailed, err
 	}
 
 	// Run Repo POST Hooks
 	if err := utils.ExecuteHooks(filepath.Join(repoLocalPath, ".deploy", "post"), hookEnv, logger); err != nil {
+		r.metrics.hookFailures.With(fullName, "repo_post").Inc()
 		logger.Error("Repo Post-hook failed", "error", err)
 	}
 
 	// Run Global POST Hooks
-	if r.cfg.GlobalHooksDir != "" {
-		if err = utils.ExecuteHooks(filepath.Join(r.cfg.GlobalHooksDir, "post"), hookEnv, logger); err != nil {
+	if cfg.GlobalHooksDir != "" {
+		if err = utils.ExecuteHooks(filepath.Join(cfg.GlobalHooksDir, "post"), hookEnv, logger); err != nil {
+			r.metrics.hookFailures.With(fullName, "global_post").Inc()
 			logger.Error("Repo Post-hook execution failed", "error", err)
 			r.publishDeployEvent(ctx, "deploy_failed", repo, "failed", err.Error(), "", deployStart)
-			return
+			return outcomeFailed, err
 		}
 	}
 
 	logger.Info("Deploy sequence complete")
 	r.publishDeployEvent(ctx, "deploy_success", repo, "success", "", time.Since(deployStart).String(), deployStart)
+	return outcomeDeployed, nil
 }
 
 func (r *Reconciler) collectRuntimeFiles(ctx context.Context, owner, repo string, logger *slog.Logger, existingSources map[string]string) ([]core.RuntimeFile, error) {
-	runtimePlugins := r.registry.GetPluginsWithCapability(core.CapabilityRuntimeFiles)
+	cfg := r.config()
+	runtimePlugins := core.OrderByPrecedence(r.registry.GetPluginsWithCapability(core.CapabilityRuntimeFiles), cfg.SecretPrecedence)
 	files := make([]core.RuntimeFile, 0)
 	runtimeSources := make(map[string]string)
 
@@ -727,7 +934,10 @@ func (r *Reconciler) publishDeployEvent(ctx context.Context, eventType string, r
 	if repo == nil || repo.Owner == nil || repo.Name == nil {
 		return
 	}
-	core.Publish(ctx, core.InternalEvent{
+	if eventType != "deploy_start" {
+		r.metrics.observeDeploy(fmt.Sprintf("%s/%s", *repo.Owner.Login, *repo.Name), eventType, start)
+	}
+	r.registry.Publish(ctx, core.InternalEvent{
 		Type:   core.EventTypeName(eventType),
 		Source: "reconciler",
 		Repo:   *repo.Name,
@@ -745,8 +955,9 @@ func (r *Reconciler) publishDeployEvent(ctx context.Context, eventType string, r
 
 // fetchRepoHooks downloads all scripts from .deploy/{stage} to the local repo dir
 func (r *Reconciler) fetchRepoHooks(ctx context.Context, owner, repo, stage, localDir string) error {
+	client := r.githubClient()
 	path := fmt.Sprintf(".deploy/%s", stage)
-	_, dirContent, _, err := r.client.Repositories.GetContents(ctx, owner, repo, path, nil)
+	_, dirContent, _, err := client.Repositories.GetContents(ctx, owner, repo, path, nil)
 	if err != nil {
 		if strings.Contains(err.Error(), "404") {
 			return nil
@@ -764,7 +975,7 @@ func (r *Reconciler) fetchRepoHooks(ctx context.Context, owner, repo, stage, loc
 			continue
 		}
 
-		fileContent, _, _, err := r.client.Repositories.GetContents(ctx, owner, repo, fileMeta.GetPath(), nil)
+		fileContent, _, _, err := client.Repositories.GetContents(ctx, owner, repo, fileMeta.GetPath(), nil)
 		if err != nil {
 			r.logger.Error("Failed to fetch hook content", "file", fileMeta.GetName(), "error", err)
 			continue
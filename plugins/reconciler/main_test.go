@@ -0,0 +1,22 @@
+package reconciler
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mywio/git-ops/pkg/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_RedactsToken(t *testing.T) {
+	cfg, err := loadConfig(map[string]map[string]any{"core": {"token": "ghp_secret", "topic": "homelab"}})
+	require.NoError(t, err)
+	r := &Reconciler{cfg: cfg}
+
+	data, err := json.Marshal(core.RedactConfigView(r.Config()))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "ghp_secret")
+	assert.Contains(t, string(data), `"Token":"REDACTED"`)
+	assert.Contains(t, string(data), `"Topic":"homelab"`)
+}
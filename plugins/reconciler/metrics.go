@@ -0,0 +1,76 @@
+package reconciler
+
+import (
+	"time"
+
+	"github.com/mywio/git-ops/pkg/metrics"
+)
+
+// reconcilerMetrics are the metrics the reconciler records on the core
+// registry.
+type reconcilerMetrics struct {
+	runs         *metrics.CounterVec
+	runDuration  *metrics.HistogramVec
+	stacks       *metrics.GaugeVec
+	lastSuccess  *metrics.GaugeVec
+	searchErrors *metrics.CounterVec
+	deploys      *metrics.CounterVec
+	deployTime   *metrics.HistogramVec
+	hookFailures *metrics.CounterVec
+}
+
+// newReconcilerMetrics registers the metrics on reg; with a nil registry
+// they are recorded but not exposed.
+func newReconcilerMetrics(reg *metrics.Registry) *reconcilerMetrics {
+	return &reconcilerMetrics{
+		runs: reg.Counter("gitops_reconcile_runs_total",
+			"Full reconciliations, by result (ok or failed).", "result"),
+		runDuration: reg.Histogram("gitops_reconcile_duration_seconds",
+			"Time taken by full reconciliations.", []float64{1, 5, 10, 30, 60, 120, 300, 600}),
+		stacks: reg.Gauge("gitops_reconcile_stacks",
+			"Stacks in the last full reconciliation, by outcome.", "outcome"),
+		lastSuccess: reg.Gauge("gitops_reconcile_last_success_timestamp_seconds",
+			"Unix time of the last full reconciliation without failures."),
+		searchErrors: reg.Counter("gitops_github_search_errors_total",
+			"Failed GitHub repository searches."),
+		deploys: reg.Counter("gitops_deploys_total",
+			"Stack deployments, by stack (owner/repo) and result (success or failed).", "stack", "result"),
+		deployTime: reg.Histogram("gitops_deploy_duration_seconds",
+			"Time taken by stack deployments, by stack.", []float64{1, 5, 10, 30, 60, 120, 300, 600}, "stack"),
+		hookFailures: reg.Counter("gitops_hook_failures_total",
+			"Failed deploy hooks, by stack and hook (global_pre, repo_pre, repo_post, global_post).", "stack", "hook"),
+	}
+}
+
+// observeRun records a full reconciliation.
+func (m *reconcilerMetrics) observeRun(summary *ReconcileSummary, elapsed time.Duration) {
+	result := "ok"
+	if !summary.OK() {
+		result = "failed"
+	} else {
+		m.lastSuccess.With().Set(float64(time.Now().Unix()))
+	}
+	m.runs.With(result).Inc()
+	m.runDuration.With().Observe(elapsed.Seconds())
+	for outcome, stacks := range map[string]int{
+		"deployed":  len(summary.Deployed),
+		"unchanged": len(summary.Unchanged),
+		"failed":    len(summary.Failed),
+		"removed":   len(summary.Removed),
+		"diverged":  len(summary.Diverged),
+		"skipped":   len(summary.Skipped),
+	} {
+		m.stacks.With(outcome).Set(float64(stacks))
+	}
+}
+
+// observeDeploy records a finished deployment; eventType is deploy_success
+// or deploy_failed.
+func (m *reconcilerMetrics) observeDeploy(stack, eventType string, start time.Time) {
+	result := "success"
+	if eventType == "deploy_failed" {
+		result = "failed"
+	}
+	m.deploys.With(stack, result).Inc()
+	m.deployTime.With(stack).Observe(time.Since(start).Seconds())
+}
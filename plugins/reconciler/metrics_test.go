@@ -0,0 +1,33 @@
+package reconciler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mywio/git-ops/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcilerMetrics(t *testing.T) {
+	reg := metrics.NewRegistry(nil)
+	m := newReconcilerMetrics(reg)
+
+	summary := newSummary(false)
+	summary.record("me/a", outcomeDeployed, nil)
+	summary.record("me/b", outcomeFailed, nil)
+	m.observeRun(summary, 2*time.Second)
+	m.observeDeploy("me/a", "deploy_success", time.Now())
+	m.observeDeploy("me/b", "deploy_failed", time.Now())
+
+	var b strings.Builder
+	require.NoError(t, metrics.WriteText(&b, reg))
+	out := b.String()
+	assert.Contains(t, out, `gitops_reconcile_runs_total{result="failed"} 1`)
+	assert.Contains(t, out, `gitops_reconcile_stacks{outcome="deployed"} 1`)
+	assert.Contains(t, out, `gitops_reconcile_duration_seconds_sum 2`)
+	assert.Contains(t, out, `gitops_deploys_total{stack="me/a",result="success"} 1`)
+	assert.Contains(t, out, `gitops_deploys_total{stack="me/b",result="failed"} 1`)
+	assert.NotContains(t, out, "gitops_reconcile_last_success_timestamp_seconds", "only set after a run without failures")
+}
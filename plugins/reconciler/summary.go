@@ -0,0 +1,110 @@
+package reconciler
+
+import (
+	"sort"
+	"time"
+)
+
+// outcome is the result of reconciling one stack.
+type outcome int
+
+const (
+	outcomeDeployed  outcome = iota // compose file changed (or forced) and deployed
+	outcomeUnchanged                // compose file identical to the local copy
+	outcomeSkipped                  // nothing to deploy, e.g. no docker-compose.yml
+	outcomeFailed
+)
+
+// StackFailure is a stack that could not be deployed or removed.
+type StackFailure struct {
+	Stack string `json:"stack"`
+	Error string `json:"error"`
+}
+
+// ReconcileSummary reports what a reconciliation did. It is returned by the
+// `reconcile` and `reconcile_stack` (with wait) actions and logged after
+// every run. Stacks are named owner/repo. In dry-run mode Deployed and
+// Removed list what would have changed.
+type ReconcileSummary struct {
+	DryRun    bool           `json:"dry_run,omitempty"`
+	Deployed  []string       `json:"deployed"`
+	Unchanged []string       `json:"unchanged"`
+	Failed    []StackFailure `json:"failed"`
+	Removed   []string       `json:"removed"`
+	// Diverged stacks exist locally but are neither desired nor marked for
+	// removal on GitHub; they are left running.
+	Diverged []string `json:"diverged"`
+	Skipped  []string `json:"skipped,omitempty"`
+	// Errors are failures not tied to one stack, such as a failed search.
+	Errors   []string `json:"errors,omitempty"`
+	Duration string   `json:"duration"`
+}
+
+func newSummary(dryRun bool) *ReconcileSummary {
+	return &ReconcileSummary{
+		DryRun:    dryRun,
+		Deployed:  []string{},
+		Unchanged: []string{},
+		Failed:    []StackFailure{},
+		Removed:   []string{},
+		Diverged:  []string{},
+	}
+}
+
+// OK reports whether the run finished without failures.
+func (s *ReconcileSummary) OK() bool {
+	return len(s.Failed) == 0 && len(s.Errors) == 0
+}
+
+func (s *ReconcileSummary) record(stack string, o outcome, err error) {
+	switch o {
+	case outcomeDeployed:
+		s.Deployed = append(s.Deployed, stack)
+	case outcomeUnchanged:
+		s.Unchanged = append(s.Unchanged, stack)
+	case outcomeSkipped:
+		s.Skipped = append(s.Skipped, stack)
+	case outcomeFailed:
+		msg := "failed"
+		if err != nil {
+			msg = err.Error()
+		}
+		s.Failed = append(s.Failed, StackFailure{Stack: stack, Error: msg})
+	}
+}
+
+func (s *ReconcileSummary) removed(stack string) {
+	s.Removed = append(s.Removed, stack)
+}
+
+func (s *ReconcileSummary) diverged(stack string) {
+	s.Diverged = append(s.Diverged, stack)
+}
+
+func (s *ReconcileSummary) addError(err error) {
+	s.Errors = append(s.Errors, err.Error())
+}
+
+// finish sorts every list so summaries are stable and records the duration.
+func (s *ReconcileSummary) finish(start time.Time) {
+	for _, list := range [][]string{s.Deployed, s.Unchanged, s.Removed, s.Diverged, s.Skipped} {
+		sort.Strings(list)
+	}
+	sort.Slice(s.Failed, func(i, j int) bool { return s.Failed[i].Stack < s.Failed[j].Stack })
+	s.Duration = time.Since(start).Round(time.Millisecond).String()
+}
+
+// logArgs returns the summary counts as slog key-value pairs.
+func (s *ReconcileSummary) logArgs() []any {
+	return []any{
+		"deployed", len(s.Deployed),
+		"unchanged", len(s.Unchanged),
+		"failed", len(s.Failed),
+		"removed", len(s.Removed),
+		"diverged", len(s.Diverged),
+		"skipped", len(s.Skipped),
+		"errors", len(s.Errors),
+		"duration", s.Duration,
+		"dry_run", s.DryRun,
+	}
+}
@@ -0,0 +1,50 @@
+package reconciler
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/mywio/git-ops/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessLocalState_Summary(t *testing.T) {
+	dir := t.TempDir()
+	for _, stack := range []string{"me/keep", "me/old", "me/gone"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, stack), 0o755))
+	}
+	r := &Reconciler{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg:    config.Config{TargetDir: dir, DryRun: true},
+	}
+
+	summary := newSummary(true)
+	desired := map[string]*github.Repository{"me/keep": {}}
+	r.processLocalState(desired, map[string]bool{"me/gone": true}, summary)
+	summary.finish(time.Now())
+
+	assert.Equal(t, []string{"me/gone"}, summary.Removed)
+	assert.Equal(t, []string{"me/old"}, summary.Diverged)
+	assert.DirExists(t, filepath.Join(dir, "me/gone"), "dry run must not remove stacks")
+	assert.True(t, summary.OK())
+}
+
+func TestReconcileSummary_OK(t *testing.T) {
+	summary := newSummary(false)
+	summary.record("me/b", outcomeDeployed, nil)
+	summary.record("me/a", outcomeDeployed, nil)
+	summary.record("me/c", outcomeUnchanged, nil)
+	summary.finish(time.Now())
+	assert.True(t, summary.OK())
+	assert.Equal(t, []string{"me/a", "me/b"}, summary.Deployed)
+
+	summary.record("me/d", outcomeFailed, assert.AnError)
+	assert.False(t, summary.OK())
+	assert.Equal(t, []StackFailure{{Stack: "me/d", Error: assert.AnError.Error()}}, summary.Failed)
+}
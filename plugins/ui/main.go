@@ -1,22 +1,40 @@
-package main
+package ui
 
 import (
 	"context"
 	"log/slog"
-	"net/http"
 
 	"github.com/mywio/git-ops/pkg/core"
 )
 
 type UIPlugin struct {
-	mux    *http.ServeMux
+	router *core.PluginRouter
 	logger *slog.Logger
 }
 
-var Plugin = &UIPlugin{}
+// Manifest of the ui plugin, which serves the web dashboard.
+var Manifest = core.PluginManifest{
+	Name:         "ui",
+	Version:      core.Version,
+	APIVersion:   core.APIVersion,
+	Capabilities: []core.Capability{core.CapabilityUI, core.CapabilityAPI},
+}
+
+func init() {
+	core.RegisterBuiltin(Manifest.Name, New)
+}
+
+func New() core.Plugin {
+	return &UIPlugin{}
+}
 
 func (p *UIPlugin) Name() string {
-	return "ui"
+	return Manifest.Name
+}
+
+// Manifest implements core.ManifestProvider.
+func (p *UIPlugin) Manifest() core.PluginManifest {
+	return Manifest
 }
 
 func (p *UIPlugin) Description() string {
@@ -26,7 +44,7 @@ func (p *UIPlugin) Description() string {
 func (p *UIPlugin) Init(ctx context.Context, logger *slog.Logger, registry core.PluginRegistry) error {
 	p.logger = logger
 	if registry != nil {
-		p.mux = registry.GetMuxServer()
+		p.router = registry.Router(p.Name())
 	}
 	return nil
 }
@@ -40,8 +58,14 @@ func (p *UIPlugin) Stop(ctx context.Context) error {
 	return nil
 }
 
+// ConfigSections reports that the UI reads no config section, so config
+// reloads never restart it.
+func (p *UIPlugin) ConfigSections() []string {
+	return nil
+}
+
 func (p *UIPlugin) Capabilities() []core.Capability {
-	return []core.Capability{core.CapabilityUI, core.CapabilityAPI}
+	return Manifest.Capabilities
 }
 
 func (p *UIPlugin) Status() core.ServiceStatus {
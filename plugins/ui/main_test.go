@@ -1,4 +1,4 @@
-package main
+package ui
 
 import (
 	"context"
@@ -11,10 +11,10 @@ import (
 )
 
 func TestUIPlugin(t *testing.T) {
-	// Verify Plugin variable implements interface
-	var _ core.Plugin = Plugin
+	// Verify the constructor returns a core.Plugin
+	var plugin core.Plugin = New()
 
-	assert.Equal(t, "ui", Plugin.Name())
+	assert.Equal(t, "ui", plugin.Name())
 
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	ctx := context.Background()
@@ -22,19 +22,19 @@ func TestUIPlugin(t *testing.T) {
 	// Use ModuleManager as a dummy registry
 	mgr := core.NewModuleManager(logger)
 
-	err := Plugin.Init(ctx, logger, mgr)
+	err := plugin.Init(ctx, logger, mgr)
 	assert.NoError(t, err)
 
-	err = Plugin.Start(ctx)
+	err = plugin.Start(ctx)
 	assert.NoError(t, err)
 
-	caps := Plugin.Capabilities()
+	caps := plugin.Capabilities()
 	assert.Contains(t, caps, core.CapabilityUI)
 	assert.Contains(t, caps, core.CapabilityAPI)
 
-	status := Plugin.Status()
+	status := plugin.Status()
 	assert.Equal(t, core.StatusHealthy, status)
 
-	err = Plugin.Stop(ctx)
+	err = plugin.Stop(ctx)
 	assert.NoError(t, err)
 }
@@ -0,0 +1,26 @@
+// Command webhook_trigger builds the webhook_trigger plugin as a standalone artifact: a `.so`
+// (go build -buildmode=plugin) or an executable process plugin.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mywio/git-ops/pkg/core"
+	webhooktrigger "github.com/mywio/git-ops/plugins/webhook_trigger"
+)
+
+// Plugin is the symbol core looks up when this package is loaded as a `.so`.
+var Plugin core.Plugin = webhooktrigger.New()
+
+// Manifest is checked by core before the `Plugin` symbol is used.
+var Manifest = webhooktrigger.Manifest
+
+// main runs the plugin out-of-process when it is launched by git-ops as an
+// executable from plugins_dir.
+func main() {
+	if err := core.ServeProcessPlugin(Plugin); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -1,25 +1,27 @@
 // plugins/webhook_trigger/webhook_trigger.go
 // Plugin for exposing an HTTP endpoint to trigger reconciliation (e.g., from GitHub Actions/webhooks)
 
-package main
+package webhooktrigger
 
 import (
 	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"os"
 	"strings"
+	"sync"
 
 	"github.com/mywio/git-ops/pkg/core"
 )
 
 type WebhookTriggerPlugin struct {
-	port   string
-	token  string
-	logger *slog.Logger
-	mux    *http.ServeMux
-	server *http.Server
+	mu       sync.RWMutex // guards port and token
+	port     string
+	token    string
+	logger   *slog.Logger
+	registry core.PluginRegistry
+	router   *core.PluginRouter
+	server   *http.Server
 }
 
 type webhookTriggerConfig struct {
@@ -27,23 +29,68 @@ type webhookTriggerConfig struct {
 	Token string `yaml:"token"`
 }
 
+// ConfigSchema implements core.ConfigSchemaProvider.
+func (p *WebhookTriggerPlugin) ConfigSchema() []core.ConfigSchema {
+	return []core.ConfigSchema{{
+		Section: Manifest.Name,
+		Fields: []core.ConfigField{
+			{Name: "port", Type: core.ConfigString, Default: "8082", Description: "Port of the trigger endpoint"},
+			{Name: "token", Type: core.ConfigString, Secret: true, Description: "Bearer token required by the endpoint"},
+		},
+	}}
+}
+
 func (p *WebhookTriggerPlugin) Name() string {
-	return "webhook_trigger"
+	return Manifest.Name
+}
+
+// Manifest implements core.ManifestProvider.
+func (p *WebhookTriggerPlugin) Manifest() core.PluginManifest {
+	return Manifest
 }
 
 func (p *WebhookTriggerPlugin) Init(ctx context.Context, logger *slog.Logger, registry core.PluginRegistry) error {
 	p.logger = logger
+	p.registry = registry
+
+	var cfg map[string]map[string]any
+	if registry != nil {
+		cfg = registry.GetConfig()
+	}
+	p.applyConfig(ctx, cfg)
 
 	if registry != nil {
-		cfg := registry.GetConfig()
-		if section, ok := cfg["webhook_trigger"]; ok {
-			var wcfg webhookTriggerConfig
-			if err := core.DecodeConfigSection(section, &wcfg); err != nil {
-				p.logger.WarnContext(ctx, "Invalid webhook_trigger config", "error", err)
-			}
-			p.port = wcfg.Port
-			p.token = wcfg.Token
+		registry.RegisterEventType(core.EventTypeDesc{
+			Name:        "webhook_received",
+			Description: "Raw webhook received (before processing)",
+		})
+		p.router = registry.Router(p.Name())
+		p.router.HandleFunc("/reconcile", p.handleReconcile)
+		p.router.ServeLegacy("")
+	}
+
+	return nil
+}
+
+// Reconfigure applies a changed webhook_trigger section; the /reconcile
+// route stays registered and checks the new token.
+func (p *WebhookTriggerPlugin) Reconfigure(ctx context.Context, cfg map[string]map[string]any) error {
+	p.applyConfig(ctx, cfg)
+	return nil
+}
+
+func (p *WebhookTriggerPlugin) applyConfig(ctx context.Context, cfg map[string]map[string]any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.port, p.token = "", ""
+	if section, ok := cfg["webhook_trigger"]; ok {
+		var wcfg webhookTriggerConfig
+		if err := core.DecodeConfigSection(section, &wcfg); err != nil {
+			p.logger.WarnContext(ctx, "Invalid webhook_trigger config", "error", err)
 		}
+		p.port = wcfg.Port
+		p.token = wcfg.Token
 	}
 	if p.port == "" {
 		p.port = "8082"
@@ -54,19 +101,6 @@ func (p *WebhookTriggerPlugin) Init(ctx context.Context, logger *slog.Logger, re
 	} else {
 		p.logger.InfoContext(ctx, "Webhook Trigger Plugin Initialized", "port", p.port, "secured", true)
 	}
-
-	if registry != nil {
-		registry.RegisterEventType(core.EventTypeDesc{
-			Name:        "webhook_received",
-			Description: "Raw webhook received (before processing)",
-		})
-		p.mux = registry.GetMuxServer()
-	} else {
-		p.mux = http.NewServeMux()
-	}
-	p.mux.HandleFunc("/reconcile", p.handleReconcile)
-
-	return nil
 }
 
 func (p *WebhookTriggerPlugin) Start(_ context.Context) error {
@@ -90,10 +124,12 @@ func (p *WebhookTriggerPlugin) Description() string {
 }
 
 func (p *WebhookTriggerPlugin) Capabilities() []core.Capability {
-	return []core.Capability{core.CapabilityTrigger}
+	return Manifest.Capabilities
 }
 
 func (p *WebhookTriggerPlugin) Status() core.ServiceStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	if p.port == "" {
 		return core.StatusDegraded
 	}
@@ -114,10 +150,13 @@ func (p *WebhookTriggerPlugin) handleReconcile(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Optional token auth
-	if p.token != "" {
+	// Optional token auth, unless core already authenticated the request
+	p.mu.RLock()
+	token := p.token
+	p.mu.RUnlock()
+	if _, authenticated := core.AuthenticatedToken(r.Context()); token != "" && !authenticated {
 		auth := r.Header.Get("Authorization")
-		if !strings.HasPrefix(auth, "Bearer ") || strings.TrimPrefix(auth, "Bearer ") != p.token {
+		if !strings.HasPrefix(auth, "Bearer ") || strings.TrimPrefix(auth, "Bearer ") != token {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
@@ -127,14 +166,14 @@ func (p *WebhookTriggerPlugin) handleReconcile(w http.ResponseWriter, r *http.Re
 		"client_ip", r.RemoteAddr,
 		"user_agent", r.UserAgent())
 
-	core.Publish(r.Context(), core.InternalEvent{
+	p.registry.Publish(r.Context(), core.InternalEvent{
 		Type:    "reconcile_now",
 		Source:  "webhook_trigger",
 		Details: map[string]interface{}{"client_ip": r.RemoteAddr},
 	})
 
 	// Publish an event (useful for logging/auditing)
-	core.Publish(r.Context(), core.InternalEvent{
+	p.registry.Publish(r.Context(), core.InternalEvent{
 		Type:   "webhook_received",
 		Source: "webhook_trigger",
 		Details: map[string]interface{}{
@@ -158,8 +197,28 @@ func (p *WebhookTriggerPlugin) handleReconcile(w http.ResponseWriter, r *http.Re
 	}
 }
 
-// Exported symbol that core looks up
-var Plugin core.Plugin = &WebhookTriggerPlugin{}
+// Manifest of the webhook_trigger plugin, which starts reconciles from
+// incoming webhooks.
+var Manifest = core.PluginManifest{
+	Name:         "webhook_trigger",
+	Version:      core.Version,
+	APIVersion:   core.APIVersion,
+	Capabilities: []core.Capability{core.CapabilityTrigger},
+}
+
+func init() {
+	core.RegisterBuiltin(Manifest.Name, New)
+}
+
+func New() core.Plugin {
+	return &WebhookTriggerPlugin{}
+}
+
+// RouteScopes implements core.RouteScopeProvider: triggering a
+// reconciliation needs the trigger scope.
+func (p *WebhookTriggerPlugin) RouteScopes() map[string]core.Scope {
+	return map[string]core.Scope{"/reconcile": core.ScopeTrigger}
+}
 
 type webhookTriggerConfigView struct {
 	Port     string      `json:"port"`
@@ -169,6 +228,8 @@ type webhookTriggerConfigView struct {
 }
 
 func (p *WebhookTriggerPlugin) Config() any {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	return webhookTriggerConfigView{
 		Port:    p.port,
 		Token:   core.NewSecret(p.token),
@@ -176,25 +237,3 @@ func (p *WebhookTriggerPlugin) Config() any {
 		Enabled: p.port != "",
 	}
 }
-
-// Main for standalone testing
-func main() {
-	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	p := &WebhookTriggerPlugin{}
-	ctx := context.Background()
-
-	if err := p.Init(ctx, logger, nil); err != nil {
-		logger.Error("Init failed", "error", err)
-		return
-	}
-
-	if err := p.Start(ctx); err != nil {
-		logger.Error("Start failed", "error", err)
-		return
-	}
-
-	logger.Info("Webhook trigger running (press Ctrl+C to stop)")
-	<-ctx.Done()
-
-	p.Stop(ctx)
-}
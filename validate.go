@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+
+	"github.com/mywio/git-ops/pkg/config"
+	"github.com/mywio/git-ops/pkg/core"
+	"gopkg.in/yaml.v3"
+)
+
+// runConfigValidate implements `git-ops config validate [-config path]`. It
+// validates the config file and its conf.d fragments (merged over the
+// environment, as at startup) against the schemas of core and the enabled
+// built-in plugins, without starting anything, and prints every problem. It
+// returns the exit code.
+func runConfigValidate(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	fs.SetOutput(out)
+	path := fs.String("config", defaultConfigPath(), "config file to validate")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		*path = fs.Arg(0)
+	}
+
+	files, err := config.ConfigFiles(*path)
+	if err == nil && len(files) == 0 {
+		_, err = os.Stat(*path)
+	}
+	if err != nil {
+		fmt.Fprintf(out, "error: %v\n", err)
+		return 1
+	}
+
+	var errs, warnings []string
+	sections := map[string]bool{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			return 1
+		}
+		var raw map[string]any
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			fmt.Fprintf(out, "error: %s: %v\n", file, err)
+			return 1
+		}
+		for section, value := range raw {
+			sections[section] = true
+			if _, ok := value.(map[string]any); !ok && value != nil {
+				msg := fmt.Sprintf("%s: section must be a map", section)
+				if len(files) > 1 {
+					msg = file + ": " + msg
+				}
+				errs = append(errs, msg)
+			}
+		}
+	}
+
+	// Unresolvable references are problems like any other: report them all
+	// and validate the rest, with those values left as written.
+	fileLayers, problems, err := config.CheckConfigLayers(*path)
+	if err != nil {
+		fmt.Fprintf(out, "error: %s: %v\n", *path, err)
+		return 1
+	}
+	for _, p := range problems {
+		errs = append(errs, p.Error())
+	}
+	cfg, _ := mergeConfig(fileLayers)
+	mgr := core.NewModuleManager(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	mgr.SetConfig(cfg)
+	if err := mgr.LoadBuiltins(); err != nil {
+		errs = append(errs, "core.builtin_plugins: "+err.Error())
+	}
+	for _, p := range mgr.ValidateConfig() {
+		errs = append(errs, p.String())
+	}
+
+	checked := map[string]bool{}
+	for _, schema := range mgr.ConfigSchemas() {
+		checked[schema.Section] = true
+	}
+	for section := range sections {
+		if !checked[section] {
+			warnings = append(warnings, fmt.Sprintf("%s: not checked, no enabled built-in plugin describes this section", section))
+		}
+	}
+
+	sort.Strings(errs)
+	sort.Strings(warnings)
+	for _, w := range warnings {
+		fmt.Fprintf(out, "warning: %s\n", w)
+	}
+	for _, e := range errs {
+		fmt.Fprintf(out, "error: %s\n", e)
+	}
+	if len(errs) > 0 {
+		fmt.Fprintf(out, "%s: %d problem(s)\n", *path, len(errs))
+		return 1
+	}
+	fmt.Fprintf(out, "%s: OK\n", *path)
+	return 0
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.yaml")
+	bad := filepath.Join(dir, "bad.yaml")
+	assert.NoError(t, os.WriteFile(good, []byte("core:\n  builtin_plugins: [pushover]\npushover:\n  token: abc\n  user: me\n"), 0o644))
+	assert.NoError(t, os.WriteFile(bad, []byte("core:\n  builtin_plugins: [pushover]\npushover:\n  subscibe: [notify_*]\nextra: 1\n"), 0o644))
+
+	var out bytes.Buffer
+	assert.Equal(t, 0, runConfigValidate([]string{good}, &out))
+	assert.Contains(t, out.String(), "good.yaml: OK")
+
+	out.Reset()
+	assert.Equal(t, 1, runConfigValidate([]string{"-config", bad}, &out))
+	assert.Contains(t, out.String(), `error: pushover.subscibe: unknown key (did you mean "subscribe"?)`)
+	assert.Contains(t, out.String(), "error: extra: section must be a map")
+
+	out.Reset()
+	assert.Equal(t, 1, runConfigValidate([]string{filepath.Join(dir, "missing.yaml")}, &out))
+}
+
+func TestRunConfigValidate_Example(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "ghp_example")
+	var out bytes.Buffer
+	assert.Equal(t, 0, runConfigValidate([]string{filepath.Join("examples", "config.yaml")}, &out), out.String())
+	assert.Contains(t, out.String(), "config.yaml: OK")
+}
+
+func TestRunConfigValidate_UnresolvedReferences(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("core:\n  builtin_plugins: [pushover]\npushover:\n  token: ${GITOPS_TEST_UNSET_A}\n  user: ${GITOPS_TEST_UNSET_B}\n  subscibe: [notify_*]\n"), 0o644))
+
+	var out bytes.Buffer
+	assert.Equal(t, 1, runConfigValidate([]string{path}, &out))
+	assert.Contains(t, out.String(), "error: pushover.token: environment variable GITOPS_TEST_UNSET_A is not set")
+	assert.Contains(t, out.String(), "error: pushover.user: environment variable GITOPS_TEST_UNSET_B is not set")
+	assert.Contains(t, out.String(), `error: pushover.subscibe: unknown key (did you mean "subscribe"?)`)
+	assert.Contains(t, out.String(), "config.yaml: 3 problem(s)")
+}